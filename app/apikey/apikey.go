@@ -0,0 +1,134 @@
+// Package apikey 负责 API Key 的签发、吊销与校验。
+// Key 明文格式为 "ggk_" + 43 个 base64url 字符，数据库只保存其 SHA-256 摘要。
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+// Prefix 是所有 API Key 明文的固定前缀，用于与其他类型的 Bearer 令牌区分
+const Prefix = "ggk_"
+
+// displayPrefixLen 是保存到数据库、用于辨认 Key 的明文前缀长度
+const displayPrefixLen = 12
+
+// namePattern 限制 Key 名称，名称会出现在审计记录的调用方字段中
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$`)
+
+// validScopes 是可签发的权限范围
+var validScopes = map[string]bool{models.ScopeAdmin: true, models.ScopeRun: true, models.ScopeCommand: true}
+
+var (
+	// ErrInvalid 表示 Key 不存在、已吊销或已过期
+	ErrInvalid = errors.New("API Key 无效、已吊销或已过期")
+	// ErrNotFound 表示指定名称的 Key 不存在
+	ErrNotFound = errors.New("API Key 不存在")
+)
+
+// IsAPIKey 返回令牌是否为 API Key 格式（以 Prefix 开头）
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
+
+// Hash 返回 Key 明文的 SHA-256 十六进制摘要
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// Principal 返回 Key 在审计记录中的调用方标识
+func Principal(k *models.APIKey) string {
+	return "apikey:" + k.Name
+}
+
+// ParseScopes 解析并校验逗号分隔的权限范围
+func ParseScopes(raw string) ([]string, error) {
+	var scopes []string
+	for _, s := range strings.Split(raw, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !validScopes[s] {
+			return nil, fmt.Errorf("未知的权限范围 %q (可选 %s、%s、%s)", s, models.ScopeAdmin, models.ScopeRun, models.ScopeCommand)
+		}
+		scopes = append(scopes, s)
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("至少需要一个权限范围")
+	}
+	return scopes, nil
+}
+
+// Create 签发新的 API Key，返回保存的记录与明文（明文不会再次出现，应立即交给使用方）
+func Create(db *gorm.DB, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	if !namePattern.MatchString(name) {
+		return nil, "", errors.New("名称只能包含字母、数字、.、_ 和 -，长度 1-63")
+	}
+	if _, err := ParseScopes(strings.Join(scopes, ",")); err != nil {
+		return nil, "", err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+	raw := Prefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	key := &models.APIKey{
+		Name:      name,
+		Prefix:    raw[:displayPrefixLen],
+		KeyHash:   Hash(raw),
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: expiresAt,
+	}
+	if err := db.Create(key).Error; err != nil {
+		return nil, "", fmt.Errorf("保存 API Key 失败，名称可能已存在: %w", err)
+	}
+	return key, raw, nil
+}
+
+// Revoke 吊销指定名称的 Key，已吊销的 Key 保持原吊销时间
+func Revoke(db *gorm.DB, name string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := db.Where("name = ?", name).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		if err := db.Model(&key).Update("revoked_at", now).Error; err != nil {
+			return nil, err
+		}
+		key.RevokedAt = &now
+	}
+	return &key, nil
+}
+
+// Authenticate 校验 Key 明文，返回可用的 Key 记录；不存在、已吊销或已过期时返回 ErrInvalid
+func Authenticate(db *gorm.DB, raw string) (*models.APIKey, error) {
+	if !IsAPIKey(raw) {
+		return nil, ErrInvalid
+	}
+	var key models.APIKey
+	if err := db.Where("key_hash = ?", Hash(raw)).Limit(1).Find(&key).Error; err != nil {
+		return nil, err
+	}
+	if key.ID == 0 || !key.Active(time.Now()) {
+		return nil, ErrInvalid
+	}
+	return &key, nil
+}
@@ -0,0 +1,246 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// hmacKey 是计算审计哈希链使用的 HMAC 密钥，为空时不启用哈希链
+	hmacKey []byte
+	// checkpointFile 是签名检查点文件路径，VerifyChain 会同时校验其中的检查点
+	checkpointFile string
+	// chainMu 串行化本进程内的链尾追加
+	chainMu sync.Mutex
+)
+
+const (
+	// chainLockName 与 chainPgLockKey 分别是 MySQL 与 PostgreSQL 的哈希链咨询锁标识
+	chainLockName  = "go-gin-gorm-api.audit_chain"
+	chainPgLockKey = int64(0x676f67696e617564) // "goginaud"
+	// chainLockTimeout 是等待其他实例释放哈希链锁的最长时间
+	chainLockTimeout = 30 * time.Second
+)
+
+// ConfigureChain 设置哈希链的 HMAC 密钥与检查点文件，应在写入任何审计记录之前调用
+func ConfigureChain(key, checkpointPath string) {
+	hmacKey = []byte(key)
+	checkpointFile = checkpointPath
+}
+
+// ChainEnabled 返回是否已配置 HMAC 密钥并启用哈希链
+func ChainEnabled() bool {
+	return len(hmacKey) > 0
+}
+
+// chainPayload 是参与哈希计算的审计内容，字段顺序即序列化顺序。
+// 【注意】后续新增字段必须使用 omitempty，否则历史记录的哈希将无法通过校验。
+type chainPayload struct {
+	CreatedAt  int64  `json:"created_at"` // Unix 毫秒，避免时区与数据库精度差异
+	Path       string `json:"path"`
+	Method     string `json:"method"`
+	ClientIP   string `json:"client_ip"`
+	Principal  string `json:"principal"`
+	SQL        string `json:"sql"`
+	Args       string `json:"args"`
+	DurationMs int64  `json:"duration_ms"`
+	Rows       int    `json:"rows"`
+	Truncated  bool   `json:"truncated"`
+	Error      string `json:"error"`
+	Outcome    string `json:"outcome"`
+	HTTPStatus int    `json:"http_status"`
+	BizCode    int    `json:"biz_code"`
+	PrevHash   string `json:"prev_hash"`
+	RequestID  string `json:"request_id,omitempty"`
+}
+
+// computeHash 计算审计记录的链式哈希: HMAC-SHA256(key, payload(记录内容 + 上一条记录的哈希))
+func computeHash(a *models.Audit) string {
+	payload, _ := json.Marshal(chainPayload{
+		CreatedAt:  a.CreatedAt.UnixMilli(),
+		Path:       a.Path,
+		Method:     a.Method,
+		ClientIP:   a.ClientIP,
+		Principal:  a.Principal,
+		SQL:        a.SQL,
+		Args:       a.Args,
+		DurationMs: a.DurationMs,
+		Rows:       a.Rows,
+		Truncated:  a.Truncated,
+		Error:      a.Error,
+		Outcome:    a.Outcome,
+		HTTPStatus: a.HTTPStatus,
+		BizCode:    a.BizCode,
+		PrevHash:   a.PrevHash,
+		RequestID:  a.RequestID,
+	})
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// insertChained 在一个事务中为批次内的记录计算哈希链并写入数据库。
+// 读取链尾与写入都在哈希链锁内进行 (见 withChainLock)，多个实例并发写入时依然串行追加，不会产生分叉。
+func insertChained(db *gorm.DB, batch []*models.Audit) error {
+	if !ChainEnabled() {
+		return db.CreateInBatches(batch, len(batch)).Error
+	}
+
+	return withChainLock(db, func(conn *gorm.DB) error {
+		return conn.Transaction(func(tx *gorm.DB) error {
+			// 锁内读取链尾；MySQL 的加锁读总是读取最新提交的数据，不受可重复读快照影响
+			var last models.Audit
+			err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+				Select("id", "hash").Order("id DESC").Limit(1).Find(&last).Error
+			if err != nil {
+				return err
+			}
+
+			prev := last.Hash
+			for _, a := range batch {
+				// 提前确定 CreatedAt 并截断到毫秒，保证与数据库中读回的值一致
+				if a.CreatedAt.IsZero() {
+					a.CreatedAt = time.Now()
+				}
+				a.CreatedAt = a.CreatedAt.Truncate(time.Millisecond)
+				a.UpdatedAt = a.CreatedAt
+				a.PrevHash = prev
+				a.Hash = computeHash(a)
+				prev = a.Hash
+			}
+			return tx.CreateInBatches(batch, len(batch)).Error
+		})
+	})
+}
+
+// withChainLock 持有哈希链锁执行 fn，锁在 fn 的事务提交之后才释放。
+// 仅对链尾行加锁无法串行化追加: 空表时没有可锁的行，PostgreSQL 中等待行锁的事务醒来后读到的仍是旧链尾，
+// SQLite 方言会忽略 FOR UPDATE。因此 MySQL 与 PostgreSQL 在同一连接上使用会话级咨询锁 (与迁移锁相同的方式)；
+// SQLite 由进程内互斥锁串行化，多个进程共享同一数据库文件时，并发写入会因数据库锁失败而不会分叉。
+func withChainLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	chainMu.Lock()
+	defer chainMu.Unlock()
+
+	return db.Connection(func(conn *gorm.DB) error {
+		// 使用新会话，避免各条语句共享同一个 Statement
+		conn = conn.Session(&gorm.Session{})
+		switch conn.Dialector.Name() {
+		case "mysql":
+			var got *int64
+			secs := int(chainLockTimeout / time.Second)
+			if err := conn.Raw("SELECT GET_LOCK(?, ?)", chainLockName, secs).Scan(&got).Error; err != nil {
+				return fmt.Errorf("获取审计哈希链锁失败: %w", err)
+			}
+			if got == nil || *got != 1 {
+				return errors.New("等待审计哈希链锁超时")
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", chainLockName)
+		case "postgres":
+			ctx, cancel := context.WithTimeout(context.Background(), chainLockTimeout)
+			err := conn.WithContext(ctx).Exec("SELECT pg_advisory_lock(?)", chainPgLockKey).Error
+			cancel()
+			if err != nil {
+				return fmt.Errorf("获取审计哈希链锁失败: %w", err)
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", chainPgLockKey)
+		}
+		return fn(conn)
+	})
+}
+
+// VerifyReport 是哈希链校验结果
+type VerifyReport struct {
+	Valid         bool   `json:"valid"`
+	Checked       int64  `json:"checked"`                  // 已校验的记录数
+	Unchained     int64  `json:"unchained"`                // 启用哈希链之前写入的（无哈希）记录数
+	FirstID       uint   `json:"first_id,omitempty"`       // 校验起点记录 ID
+	LastID        uint   `json:"last_id,omitempty"`        // 最后一条通过校验的记录 ID
+	LastHash      string `json:"last_hash,omitempty"`      // 最后一条通过校验的记录哈希
+	BrokenID      uint   `json:"broken_id,omitempty"`      // 第一条断链记录 ID
+	BrokenReason  string `json:"broken_reason,omitempty"`  // 断链原因
+	CheckpointsOK int    `json:"checkpoints_ok,omitempty"` // 通过校验的检查点数量
+}
+
+// ErrChainDisabled 表示未配置 HMAC 密钥，无法校验哈希链
+var ErrChainDisabled = errors.New("审计哈希链未启用：未配置 AUDIT_HMAC_KEY")
+
+// Verify 从 fromID 开始按 ID 顺序遍历审计记录，逐条重新计算哈希并校验链接关系，报告第一处断链。
+// 起点记录的 PrevHash 被视为可信锚点（更早的记录可能已被保留策略清理），可结合检查点文件确认起点可信。
+func Verify(db *gorm.DB, fromID uint) (*VerifyReport, error) {
+	if !ChainEnabled() {
+		return nil, ErrChainDisabled
+	}
+
+	report := &VerifyReport{Valid: true}
+	var (
+		prev    string
+		started bool
+		batch   []models.Audit
+	)
+	stop := errors.New("stop")
+
+	err := db.Unscoped().Where("id >= ?", fromID).FindInBatches(&batch, retentionBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			a := &batch[i]
+			if !started {
+				if a.Hash == "" {
+					// 启用哈希链之前写入的历史记录
+					report.Unchained++
+					continue
+				}
+				started = true
+				prev = a.PrevHash
+				report.FirstID = a.ID
+			}
+
+			report.Checked++
+			switch {
+			case a.Hash == "":
+				report.BrokenReason = "记录缺少哈希"
+			case a.PrevHash != prev:
+				report.BrokenReason = fmt.Sprintf("prev_hash 与上一条记录哈希不一致 (期望 %s, 实际 %s)", prev, a.PrevHash)
+			case !hmac.Equal([]byte(computeHash(a)), []byte(a.Hash)):
+				report.BrokenReason = "记录内容与哈希不匹配，可能已被篡改"
+			}
+			if report.BrokenReason != "" {
+				report.Valid = false
+				report.BrokenID = a.ID
+				return stop
+			}
+
+			prev = a.Hash
+			report.LastID = a.ID
+			report.LastHash = a.Hash
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, stop) {
+		return nil, err
+	}
+	return report, nil
+}
+
+// VerifyChain 校验哈希链，并在配置了检查点文件时同时校验检查点
+func VerifyChain(db *gorm.DB, fromID uint) (*VerifyReport, error) {
+	report, err := Verify(db, fromID)
+	if err != nil {
+		return nil, err
+	}
+	if checkpointFile != "" {
+		if err := VerifyCheckpoints(db, checkpointFile, report); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
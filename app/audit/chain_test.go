@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 在临时目录中创建只包含 audits 表的 SQLite 数据库，并启用哈希链
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "audit.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Audit{}); err != nil {
+		t.Fatalf("创建 audits 表失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	ConfigureChain("test-key", "")
+	t.Cleanup(func() { ConfigureChain("", "") })
+	return db
+}
+
+// insertAudits 按批写入 n 条审计记录
+func insertAudits(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		a := &models.Audit{Path: fmt.Sprintf("/svc/%d", i), Method: "GET", SQL: "SELECT 1", Outcome: models.AuditOutcomeSuccess}
+		if err := insertChained(db, []*models.Audit{a}); err != nil {
+			t.Fatalf("写入审计记录失败: %v", err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		tamper     string // 写入 5 条记录后执行的 SQL
+		fromID     uint
+		wantValid  bool
+		wantBroken uint
+	}{
+		{"完整的链", "", 0, true, 0},
+		{"修改记录内容", "UPDATE audits SET sql = 'DELETE FROM users' WHERE id = 3", 0, false, 3},
+		{"删除中间记录", "DELETE FROM audits WHERE id = 3", 0, false, 4},
+		{"清空哈希", "UPDATE audits SET hash = '' WHERE id = 4", 0, false, 4},
+		{"伪造 prev_hash", "UPDATE audits SET prev_hash = 'x' WHERE id = 2", 0, false, 2},
+		{"从篡改之后开始校验", "UPDATE audits SET sql = 'x' WHERE id = 1", 2, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			insertAudits(t, db, 5)
+			if tt.tamper != "" {
+				if err := db.Exec(tt.tamper).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			report, err := Verify(db, tt.fromID)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if report.Valid != tt.wantValid || report.BrokenID != tt.wantBroken {
+				t.Errorf("Verify() = valid %v, broken %d (%s); want %v, %d",
+					report.Valid, report.BrokenID, report.BrokenReason, tt.wantValid, tt.wantBroken)
+			}
+		})
+	}
+}
+
+func TestVerifySkipsUnchainedRecords(t *testing.T) {
+	db := openTestDB(t)
+	ConfigureChain("", "")
+	insertAudits(t, db, 2)
+	ConfigureChain("test-key", "")
+	insertAudits(t, db, 3)
+
+	report, err := Verify(db, 0)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !report.Valid || report.Unchained != 2 || report.Checked != 3 || report.FirstID != 3 || report.LastID != 5 {
+		t.Errorf("Verify() = %+v; want valid, 2 unchained, 3 checked from 3 to 5", report)
+	}
+}
+
+func TestVerifyDisabled(t *testing.T) {
+	db := openTestDB(t)
+	ConfigureChain("", "")
+	if _, err := Verify(db, 0); !errors.Is(err, ErrChainDisabled) {
+		t.Errorf("Verify() error = %v, want ErrChainDisabled", err)
+	}
+}
+
+// TestInsertChainedConcurrent 并发追加 (包括空表上的第一批) 不能产生分叉
+func TestInsertChainedConcurrent(t *testing.T) {
+	db := openTestDB(t)
+	const workers, batches = 8, 5
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*batches)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < batches; i++ {
+				batch := []*models.Audit{
+					{Path: fmt.Sprintf("/w%d/%d", w, i), Method: "GET", Outcome: models.AuditOutcomeSuccess},
+					{Path: fmt.Sprintf("/w%d/%d", w, i), Method: "POST", Outcome: models.AuditOutcomeSuccess},
+				}
+				if err := insertChained(db, batch); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("写入审计记录失败: %v", err)
+	}
+
+	report, err := Verify(db, 0)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !report.Valid || report.Checked != workers*batches*2 {
+		t.Errorf("Verify() = valid %v, checked %d (%s); want valid, %d", report.Valid, report.Checked, report.BrokenReason, workers*batches*2)
+	}
+}
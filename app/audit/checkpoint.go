@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+// Checkpoint 是对哈希链某一时刻链尾的签名快照。
+// 检查点导出到数据库之外的文件，用于发现对链尾的截断或整体重写。
+type Checkpoint struct {
+	AuditID   uint      `json:"audit_id"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	Signature string    `json:"signature"`
+}
+
+// sign 计算检查点签名，使用独立的前缀与审计记录哈希区分
+func (cp *Checkpoint) sign() string {
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write([]byte("audit-checkpoint\n" + strconv.FormatUint(uint64(cp.AuditID), 10) + "\n" + cp.Hash + "\n" +
+		strconv.FormatInt(cp.CreatedAt.UnixMilli(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WriteCheckpoint 为当前链尾生成签名检查点并追加到 path 文件 (NDJSON)。
+// 链中还没有任何带哈希的记录时返回 nil, nil。
+func WriteCheckpoint(db *gorm.DB, path string) (*Checkpoint, error) {
+	if !ChainEnabled() {
+		return nil, ErrChainDisabled
+	}
+
+	var last models.Audit
+	if err := db.Unscoped().Select("id", "hash").Where("hash <> ''").Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return nil, err
+	}
+	if last.ID == 0 {
+		return nil, nil
+	}
+
+	cp := &Checkpoint{AuditID: last.ID, Hash: last.Hash, CreatedAt: time.Now().UTC().Truncate(time.Millisecond)}
+	cp.Signature = cp.sign()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("打开检查点文件失败: %w", err)
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(cp); err != nil {
+		return nil, fmt.Errorf("写入检查点文件失败: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return nil, fmt.Errorf("写入检查点文件失败: %w", err)
+	}
+	return cp, nil
+}
+
+// StartCheckpoints 启动后台任务，按 CheckpointInterval 定期导出签名检查点。
+// 未配置 CheckpointFile 或未启用哈希链时不启动。
+func StartCheckpoints(ctx context.Context, db *gorm.DB, cfg Config) {
+	if cfg.CheckpointFile == "" || !ChainEnabled() {
+		return
+	}
+	if cfg.CheckpointInterval <= 0 {
+		cfg.CheckpointInterval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.CheckpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := WriteCheckpoint(db, cfg.CheckpointFile); err != nil {
+				slog.Error("审计检查点导出失败", "error", err)
+			}
+		}
+	}()
+}
+
+// VerifyCheckpoints 校验检查点文件中每个检查点的签名，并确认其引用的记录仍存在且哈希未变。
+// 早于哈希链起点 (report.FirstID) 的检查点所引用的记录可能已被保留策略清理，视为跳过。
+// 结果写入 report：发现问题时将 report.Valid 置为 false 并记录原因。
+func VerifyCheckpoints(db *gorm.DB, path string, report *VerifyReport) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("打开检查点文件失败: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var cp Checkpoint
+		reason := ""
+		if err := json.Unmarshal(scanner.Bytes(), &cp); err != nil {
+			reason = fmt.Sprintf("检查点文件第 %d 行格式错误: %v", line, err)
+		} else if !hmac.Equal([]byte(cp.sign()), []byte(cp.Signature)) {
+			reason = fmt.Sprintf("检查点文件第 %d 行签名无效", line)
+		} else if report.FirstID != 0 && cp.AuditID < report.FirstID {
+			continue
+		} else {
+			var a models.Audit
+			if err := db.Unscoped().Select("id", "hash").Where("id = ?", cp.AuditID).Limit(1).Find(&a).Error; err != nil {
+				return err
+			}
+			switch {
+			case a.ID == 0:
+				reason = fmt.Sprintf("检查点引用的审计记录 %d 已不存在，链尾可能被截断", cp.AuditID)
+			case a.Hash != cp.Hash:
+				reason = fmt.Sprintf("审计记录 %d 的哈希与检查点不一致", cp.AuditID)
+			}
+		}
+
+		if reason != "" {
+			report.Valid = false
+			if report.BrokenReason == "" {
+				report.BrokenID = cp.AuditID
+				report.BrokenReason = reason
+			}
+			return nil
+		}
+		report.CheckpointsOK++
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+// retentionBatchSize 是保留策略每批归档/删除的记录数，避免长事务锁表
+const retentionBatchSize = 1000
+
+// StartRetention 启动后台保留策略任务，按 RetentionInterval 定期清理过期审计记录。
+// RetentionDays 为 0 时不启动。ctx 取消时任务退出。
+func StartRetention(ctx context.Context, db *gorm.DB, cfg Config) {
+	if cfg.RetentionDays <= 0 {
+		return
+	}
+	if cfg.RetentionInterval <= 0 {
+		cfg.RetentionInterval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.RetentionInterval)
+		defer ticker.Stop()
+		for {
+			cutoff := time.Now().AddDate(0, 0, -cfg.RetentionDays)
+			if n, file, err := Purge(ctx, db, cutoff, cfg.ArchiveDir); err != nil {
+				slog.Error("审计保留策略执行失败", "error", err)
+			} else if n > 0 {
+				slog.Info("审计保留策略已清理过期记录", "purged", n, "cutoff", cutoff.Format(time.RFC3339), "archive_file", file)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Purge 物理删除 created_at 早于 cutoff 的审计记录，返回删除条数与归档文件路径。
+// archiveDir 非空时，记录在删除前以 NDJSON 格式写入 gzip 压缩的归档文件，归档失败则不删除。
+func Purge(ctx context.Context, db *gorm.DB, cutoff time.Time, archiveDir string) (int64, string, error) {
+	var (
+		archive *gzip.Writer
+		enc     *json.Encoder
+		file    *os.File
+		path    string
+	)
+	if archiveDir != "" {
+		if err := os.MkdirAll(archiveDir, 0o750); err != nil {
+			return 0, "", fmt.Errorf("创建归档目录失败: %w", err)
+		}
+		path = filepath.Join(archiveDir, fmt.Sprintf("audits-before-%s-%s.ndjson.gz",
+			cutoff.UTC().Format("20060102T150405Z"), time.Now().UTC().Format("20060102T150405Z")))
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+		if err != nil {
+			return 0, "", fmt.Errorf("创建归档文件失败: %w", err)
+		}
+		file = f
+		archive = gzip.NewWriter(f)
+		enc = json.NewEncoder(archive)
+	}
+
+	var total int64
+	var purgeErr error
+	for {
+		if err := ctx.Err(); err != nil {
+			purgeErr = err
+			break
+		}
+
+		// Unscoped: 同时处理软删除的记录，并执行物理删除以真正回收空间
+		var batch []models.Audit
+		if err := db.WithContext(ctx).Unscoped().Where("created_at < ?", cutoff).
+			Order("id ASC").Limit(retentionBatchSize).Find(&batch).Error; err != nil {
+			purgeErr = err
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		if enc != nil {
+			for i := range batch {
+				if err := enc.Encode(&batch[i]); err != nil {
+					purgeErr = fmt.Errorf("写入归档文件失败: %w", err)
+					break
+				}
+			}
+			if purgeErr != nil {
+				break
+			}
+			// 先落盘再删除，保证归档文件中包含所有被删除的记录
+			if err := archive.Flush(); err != nil {
+				purgeErr = fmt.Errorf("写入归档文件失败: %w", err)
+				break
+			}
+			if err := file.Sync(); err != nil {
+				purgeErr = fmt.Errorf("写入归档文件失败: %w", err)
+				break
+			}
+		}
+
+		ids := make([]uint, len(batch))
+		for i := range batch {
+			ids[i] = batch[i].ID
+		}
+		res := db.WithContext(ctx).Unscoped().Where("id IN ?", ids).Delete(&models.Audit{})
+		if res.Error != nil {
+			purgeErr = res.Error
+			break
+		}
+		total += res.RowsAffected
+
+		if len(batch) < retentionBatchSize {
+			break
+		}
+	}
+
+	if archive != nil {
+		if err := archive.Close(); err != nil && purgeErr == nil {
+			purgeErr = fmt.Errorf("关闭归档文件失败: %w", err)
+		}
+		if err := file.Close(); err != nil && purgeErr == nil {
+			purgeErr = fmt.Errorf("关闭归档文件失败: %w", err)
+		}
+		// 没有任何过期记录时不保留空归档文件
+		if total == 0 && purgeErr == nil {
+			os.Remove(path)
+			path = ""
+		}
+	}
+
+	return total, path, purgeErr
+}
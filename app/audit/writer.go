@@ -0,0 +1,229 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+// 队列满时的处理策略
+const (
+	// PolicyBlock 在队列满时阻塞调用方（最多 EnqueueTimeout），超时后丢弃并计数
+	PolicyBlock = "block"
+	// PolicyDrop 在队列满时立即丢弃并计数，不增加请求延迟
+	PolicyDrop = "drop"
+)
+
+// Config 定义后台审计写入器与保留策略的配置
+type Config struct {
+	QueueSize      int           `yaml:"queue_size"`        // 缓冲队列容量
+	BatchSize      int           `yaml:"batch_size"`        // 每批写入的最大记录数
+	FlushInterval  time.Duration `yaml:"flush_interval"`    // 批次未满时的最长等待时间
+	FullPolicy     string        `yaml:"queue_full_policy"` // 队列满时的处理策略: block | drop
+	EnqueueTimeout time.Duration `yaml:"enqueue_timeout"`   // block 策略下的最长等待时间
+
+	RetentionDays     int           `yaml:"retention_days"`     // 审计记录保留天数，0 表示不清理
+	RetentionInterval time.Duration `yaml:"retention_interval"` // 保留策略任务的执行间隔
+	ArchiveDir        string        `yaml:"archive_dir"`        // 归档目录，非空时先将过期记录导出为 gzip 压缩的 NDJSON 文件再删除
+
+	CheckpointFile     string        `yaml:"checkpoint_file"`     // 签名检查点导出文件，为空时不导出
+	CheckpointInterval time.Duration `yaml:"checkpoint_interval"` // 检查点导出间隔
+}
+
+// Writer 是异步批量审计写入器。
+// 请求路径只负责将记录放入缓冲队列，由后台 goroutine 按批次写入数据库。
+type Writer struct {
+	db  *gorm.DB
+	cfg Config
+
+	queue   chan *models.Audit
+	dropped atomic.Int64
+	written atomic.Int64
+	failed  atomic.Int64
+
+	// consecutiveFailures 记录连续写入失败的批次数，成功写入后清零
+	consecutiveFailures atomic.Int64
+	lastError           atomic.Value // string
+
+	closeOnce sync.Once
+	closing   chan struct{}
+	done      chan struct{}
+}
+
+// DefaultWriter 是应用使用的全局审计写入器，由 main 在启动时初始化。
+// 未初始化时 Record 会退化为同步写入（例如命令行工具场景）。
+var DefaultWriter *Writer
+
+// NewWriter 创建审计写入器并启动后台写入 goroutine
+func NewWriter(db *gorm.DB, cfg Config) *Writer {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.FullPolicy != PolicyDrop {
+		cfg.FullPolicy = PolicyBlock
+	}
+	if cfg.EnqueueTimeout <= 0 {
+		cfg.EnqueueTimeout = 50 * time.Millisecond
+	}
+
+	w := &Writer{
+		db:      db,
+		cfg:     cfg,
+		queue:   make(chan *models.Audit, cfg.QueueSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Record 将审计记录交给全局写入器；写入器未初始化时直接同步写入数据库
+func Record(db *gorm.DB, a *models.Audit) {
+	if DefaultWriter != nil {
+		DefaultWriter.Enqueue(a)
+		return
+	}
+	if err := insertChained(db, []*models.Audit{a}); err != nil {
+		slog.Error("审计记录写入失败", "error", err, "path", a.Path, "request_id", a.RequestID)
+	}
+}
+
+// Enqueue 将审计记录放入队列。队列已满时按 FullPolicy 阻塞或丢弃，返回记录是否被接收。
+func (w *Writer) Enqueue(a *models.Audit) bool {
+	select {
+	case <-w.closing:
+		w.dropped.Add(1)
+		return false
+	default:
+	}
+
+	select {
+	case w.queue <- a:
+		return true
+	default:
+	}
+
+	if w.cfg.FullPolicy == PolicyBlock {
+		timer := time.NewTimer(w.cfg.EnqueueTimeout)
+		defer timer.Stop()
+		select {
+		case w.queue <- a:
+			return true
+		case <-timer.C:
+		case <-w.closing:
+		}
+	}
+
+	if n := w.dropped.Add(1); n == 1 || n%1000 == 0 {
+		slog.Warn("审计队列已满，记录被丢弃", "dropped_total", n, "queue_size", w.cfg.QueueSize)
+	}
+	return false
+}
+
+// QueueDepth 返回当前队列中待写入的记录数
+func (w *Writer) QueueDepth() int { return len(w.queue) }
+
+// QueueCapacity 返回队列容量
+func (w *Writer) QueueCapacity() int { return cap(w.queue) }
+
+// Dropped 返回因队列满或已关闭而被丢弃的记录数
+func (w *Writer) Dropped() int64 { return w.dropped.Load() }
+
+// Written 返回已成功写入数据库的记录数
+func (w *Writer) Written() int64 { return w.written.Load() }
+
+// Failed 返回写入数据库失败的记录数
+func (w *Writer) Failed() int64 { return w.failed.Load() }
+
+// Health 检查写入器是否健康：未关闭、队列未接近满 (90%)、最近一批写入成功
+func (w *Writer) Health() error {
+	select {
+	case <-w.closing:
+		return errors.New("审计写入器已关闭")
+	default:
+	}
+	if depth, capacity := w.QueueDepth(), w.QueueCapacity(); depth*10 >= capacity*9 {
+		return fmt.Errorf("审计队列接近满 (%d/%d)", depth, capacity)
+	}
+	if n := w.consecutiveFailures.Load(); n > 0 {
+		msg, _ := w.lastError.Load().(string)
+		return fmt.Errorf("审计记录连续 %d 批写入失败: %s", n, msg)
+	}
+	return nil
+}
+
+// run 是后台写入循环：批次满或到达 FlushInterval 时写入，关闭时排空队列后退出
+func (w *Writer) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*models.Audit, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case a := <-w.queue:
+			batch = append(batch, a)
+			if len(batch) >= w.cfg.BatchSize {
+				batch = w.flush(batch)
+			}
+		case <-ticker.C:
+			batch = w.flush(batch)
+		case <-w.closing:
+			for {
+				select {
+				case a := <-w.queue:
+					batch = append(batch, a)
+					if len(batch) >= w.cfg.BatchSize {
+						batch = w.flush(batch)
+					}
+				default:
+					w.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush 批量写入并返回清空后的批次切片
+func (w *Writer) flush(batch []*models.Audit) []*models.Audit {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := insertChained(w.db, batch); err != nil {
+		w.failed.Add(int64(len(batch)))
+		w.consecutiveFailures.Add(1)
+		w.lastError.Store(err.Error())
+		slog.Error("审计记录批量写入失败", "error", err, "batch_size", len(batch))
+	} else {
+		w.written.Add(int64(len(batch)))
+		w.consecutiveFailures.Store(0)
+	}
+	return batch[:0]
+}
+
+// Close 停止接收新记录并将队列中剩余的记录写入数据库。
+// ctx 到期时不再等待，返回 ctx.Err()。
+func (w *Writer) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() { close(w.closing) })
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go-gin-gorm-api/app/models"
+)
+
+// idleWriter 返回未启动后台写入 goroutine 的写入器，队列只进不出，便于构造队列已满的场景
+func idleWriter(queueSize int, policy string, timeout time.Duration) *Writer {
+	return &Writer{
+		cfg:     Config{QueueSize: queueSize, FullPolicy: policy, EnqueueTimeout: timeout},
+		queue:   make(chan *models.Audit, queueSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func TestWriterEnqueueFullQueue(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	tests := []struct {
+		name        string
+		policy      string
+		freeAfter   time.Duration // 大于 0 时在该时间后从队列取出一条记录
+		wantOK      bool
+		wantDropped int64
+		minWait     time.Duration
+		maxWait     time.Duration
+	}{
+		{"drop 策略立即丢弃", PolicyDrop, 0, false, 1, 0, timeout / 2},
+		{"block 策略等待超时后丢弃", PolicyBlock, 0, false, 1, timeout, time.Second},
+		{"block 策略等到空位后写入", PolicyBlock, timeout / 5, true, 0, timeout / 5, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := idleWriter(2, tt.policy, timeout)
+			for i := 0; i < 2; i++ {
+				if !w.Enqueue(&models.Audit{}) {
+					t.Fatalf("队列未满时 Enqueue() = false")
+				}
+			}
+			if tt.freeAfter > 0 {
+				time.AfterFunc(tt.freeAfter, func() { <-w.queue })
+			}
+			start := time.Now()
+			ok := w.Enqueue(&models.Audit{})
+			elapsed := time.Since(start)
+			if ok != tt.wantOK || w.Dropped() != tt.wantDropped {
+				t.Errorf("Enqueue() = %v, dropped %d; want %v, %d", ok, w.Dropped(), tt.wantOK, tt.wantDropped)
+			}
+			if elapsed < tt.minWait || elapsed > tt.maxWait {
+				t.Errorf("Enqueue() 耗时 %v, want between %v and %v", elapsed, tt.minWait, tt.maxWait)
+			}
+		})
+	}
+}
+
+func TestWriterEnqueueAfterClose(t *testing.T) {
+	w := NewWriter(openTestDB(t), Config{})
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if w.Enqueue(&models.Audit{}) || w.Dropped() != 1 {
+		t.Errorf("关闭后 Enqueue() 应丢弃记录, dropped = %d", w.Dropped())
+	}
+	if err := w.Health(); err == nil {
+		t.Error("关闭后 Health() = nil, want error")
+	}
+}
+
+// TestWriterCloseDrains 关闭时写入队列中剩余的全部记录，包括未满一批的部分
+func TestWriterCloseDrains(t *testing.T) {
+	db := openTestDB(t)
+	w := NewWriter(db, Config{QueueSize: 100, BatchSize: 7, FlushInterval: time.Hour})
+	const n = 30
+	for i := 0; i < n; i++ {
+		if !w.Enqueue(&models.Audit{Path: fmt.Sprintf("/svc/%d", i), Method: "GET", Outcome: models.AuditOutcomeSuccess}) {
+			t.Fatalf("Enqueue(%d) = false", i)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var count int64
+	if err := db.Model(&models.Audit{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != n || w.Written() != n || w.Dropped() != 0 || w.Failed() != 0 {
+		t.Errorf("rows = %d, written = %d, dropped = %d, failed = %d; want %d written", count, w.Written(), w.Dropped(), w.Failed(), n)
+	}
+	// 记录按入队顺序写入并链接
+	var first models.Audit
+	if err := db.Order("id").First(&first).Error; err != nil || first.Path != "/svc/0" {
+		t.Errorf("first audit = %q, %v; want /svc/0", first.Path, err)
+	}
+	if report, err := Verify(db, 0); err != nil || !report.Valid || report.Checked != n {
+		t.Errorf("Verify() = %+v, %v; want valid chain of %d", report, err, n)
+	}
+}
+
+func TestWriterHealthAfterFailedBatch(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.Migrator().DropTable(&models.Audit{}); err != nil {
+		t.Fatal(err)
+	}
+	w := NewWriter(db, Config{BatchSize: 1, FlushInterval: time.Hour})
+	w.Enqueue(&models.Audit{Path: "/svc", Method: "GET"})
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if w.Failed() != 1 || w.consecutiveFailures.Load() != 1 {
+		t.Errorf("failed = %d, consecutive = %d; want 1, 1", w.Failed(), w.consecutiveFailures.Load())
+	}
+	if msg, _ := w.lastError.Load().(string); !strings.Contains(msg, "audits") {
+		t.Errorf("lastError = %q, want the insert error", msg)
+	}
+}
+
+func TestWriterHealthQueueNearlyFull(t *testing.T) {
+	w := idleWriter(10, PolicyDrop, time.Millisecond)
+	for i := 0; i < 8; i++ {
+		w.Enqueue(&models.Audit{})
+	}
+	if err := w.Health(); err != nil {
+		t.Errorf("队列 8/10 时 Health() = %v, want nil", err)
+	}
+	w.Enqueue(&models.Audit{})
+	if err := w.Health(); err == nil {
+		t.Error("队列 9/10 时 Health() = nil, want error")
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/logging"
+	"go-gin-gorm-api/app/migrate"
+)
+
+// command 是一个命令行子命令。run 返回进程退出码: 0 成功，1 执行失败，2 用法错误
+type command struct {
+	summary string
+	run     func(cfg *config.Config, args []string) int
+}
+
+// commands 是除 serve 外的全部子命令
+var commands = map[string]command{
+	"migrate": {"执行、回滚或查看数据库迁移 (up | down [N] | status)", runMigrate},
+	"service": {"管理动态服务 (list | show | register | disable | export | import)", runService},
+	"audit":   {"查看与校验审计记录 (tail | verify)", runAudit},
+	"apikey":  {"签发与吊销 API Key (create | revoke)", runAPIKey},
+	"user":    {"导入与导出用户 (import | export)", runUser},
+	"sdk":     {"按已发布的动态服务生成 Go / TypeScript 客户端 (generate)", runSDK},
+}
+
+// usage 输出顶层用法说明
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "用法: app [serve | <command> <subcommand> [flags]]")
+	fmt.Fprintln(w, "\n未指定子命令时等同于 serve（启动 HTTP 服务）。可用子命令:")
+	fmt.Fprintf(w, "  %-8s %s\n", "serve", "启动 HTTP 服务")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "\n配置与 serve 相同: CONFIG_FILE / config.yaml 与环境变量。使用 app <command> -h 查看子命令帮助。")
+}
+
+// runCommand 执行 serve 以外的子命令并返回进程退出码。
+// 命令行输出写入 stdout，日志写入 stderr，便于脚本解析输出。
+func runCommand(cfg *config.Config, args []string) int {
+	if err := logging.InitWithWriter(os.Stderr, cfg.Logging.Format, cfg.Logging.Level); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	name := args[0]
+	// verify-audit 是 audit verify 的旧名称，保留以兼容已有脚本
+	if name == "verify-audit" {
+		return runAudit(cfg, append([]string{"verify"}, args[1:]...))
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(os.Stdout)
+		return 0
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知的子命令 %q\n\n", name)
+		usage(os.Stderr)
+		return 2
+	}
+	return cmd.run(cfg, args[1:])
+}
+
+// subcommand 从 args 中取出二级子命令，缺失或为 -h 时输出 help 并返回 false
+func subcommand(args []string, help string) (string, []string, bool) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprintln(os.Stderr, help)
+		return "", nil, false
+	}
+	return args[0], args[1:], true
+}
+
+// newFlagSet 创建出错时不退出进程的 FlagSet，用法说明写入 stderr
+func newFlagSet(name, usageLine string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: app "+name+" "+usageLine)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags 解析参数，返回非 flag 参数；失败时返回 false（调用方应以退出码 2 结束）。
+// 与标准库不同，flag 可以出现在位置参数之后，例如 app service disable q --enable
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, bool) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, false
+		}
+		remaining := fs.Args()
+		// "--" 之后的参数全部视为位置参数
+		if consumed := len(args) - len(remaining); consumed > 0 && args[consumed-1] == "--" {
+			return append(rest, remaining...), true
+		}
+		if len(remaining) == 0 {
+			return rest, true
+		}
+		rest = append(rest, remaining[0])
+		args = remaining[1:]
+	}
+}
+
+// connectCLI 为命令行子命令连接主库并设置 config.DB。命令行不会执行迁移，
+// 存在未执行的迁移时报错，避免在旧表结构上读写数据。
+func connectCLI(cfg *config.Config) bool {
+	db, err := config.OpenDatabase(cfg)
+	if err != nil {
+		slog.Error("连接数据库失败", "error", err)
+		return false
+	}
+	if !cfg.Database.AutoMigrate {
+		pending, err := migrate.Pending(context.Background(), db)
+		if err != nil {
+			slog.Error("读取迁移状态失败", "error", err)
+			return false
+		}
+		if len(pending) > 0 {
+			slog.Error("数据库存在未执行的迁移，请先执行 app migrate up", "pending", len(pending))
+			return false
+		}
+	}
+	config.UseDatabase(db)
+	return true
+}
+
+// closeCLI 关闭命令行子命令使用的数据库连接
+func closeCLI() {
+	if config.DB == nil {
+		return
+	}
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// writeJSON 以缩进格式输出 v
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeJSONLine 以单行 JSON 输出 v（JSON Lines 格式）
+func writeJSONLine(v interface{}) error {
+	return json.NewEncoder(os.Stdout).Encode(v)
+}
+
+// openOutput 返回输出文件，path 为空或 "-" 时使用 stdout
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// readInput 读取输入文件，path 为 "-" 时读取 stdin
+func readInput(path string) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("缺少输入文件 (-f)")
+	}
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// oneLine 将多行文本压缩为一行，用于表格输出
+func oneLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if max > 0 && len([]rune(s)) > max {
+		return string([]rune(s)[:max-1]) + "…"
+	}
+	return s
+}
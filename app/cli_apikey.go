@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"go-gin-gorm-api/app/apikey"
+	"go-gin-gorm-api/app/config"
+)
+
+const apikeyHelp = `用法: app apikey <subcommand> [flags]
+  create --name N [--scopes admin,run] [--expires 720h]
+                    签发 API Key，明文只输出一次（stdout 第一行），请立即妥善保存
+  revoke <name>     吊销 API Key`
+
+// runAPIKey 执行 apikey 子命令
+func runAPIKey(cfg *config.Config, args []string) int {
+	sub, args, ok := subcommand(args, apikeyHelp)
+	if !ok {
+		return 2
+	}
+	var run func([]string) int
+	switch sub {
+	case "create":
+		run = apikeyCreate
+	case "revoke":
+		run = apikeyRevoke
+	default:
+		fmt.Fprintf(os.Stderr, "未知的 apikey 子命令 %q\n\n%s\n", sub, apikeyHelp)
+		return 2
+	}
+	if !connectCLI(cfg) {
+		return 1
+	}
+	defer closeCLI()
+	return run(args)
+}
+
+func apikeyCreate(args []string) int {
+	fs := newFlagSet("apikey create", "--name N [--scopes admin,run] [--expires 720h]")
+	name := fs.String("name", "", "Key 名称，审计记录中的调用方为 apikey:<name>")
+	scopes := fs.String("scopes", "run", "逗号分隔的权限范围: admin (管理接口)、run (执行动态服务)")
+	expires := fs.Duration("expires", 0, "有效期，例如 720h；0 表示不过期")
+	if _, ok := parseFlags(fs, args); !ok {
+		return 2
+	}
+	list, err := apikey.ParseScopes(*scopes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	var expiresAt *time.Time
+	if *expires < 0 {
+		fmt.Fprintln(os.Stderr, "--expires 不能为负数")
+		return 2
+	}
+	if *expires > 0 {
+		t := time.Now().Add(*expires)
+		expiresAt = &t
+	}
+
+	key, raw, err := apikey.Create(config.DB, strings.TrimSpace(*name), list, expiresAt)
+	if err != nil {
+		slog.Error("签发 API Key 失败", "error", err)
+		return 1
+	}
+	fmt.Println(raw)
+	writeJSON(os.Stdout, key)
+	return 0
+}
+
+func apikeyRevoke(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "用法: app apikey revoke <name>")
+		return 2
+	}
+	key, err := apikey.Revoke(config.DB, args[0])
+	if err != nil {
+		if errors.Is(err, apikey.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "API Key %s 不存在\n", args[0])
+		} else {
+			slog.Error("吊销 API Key 失败", "error", err)
+		}
+		return 1
+	}
+	fmt.Printf("API Key %s 已于 %s 吊销\n", key.Name, key.RevokedAt.Format(time.RFC3339))
+	return 0
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/migrate"
+)
+
+// migrateUsage 是 migrate 子命令的用法说明
+const migrateUsage = `用法: app migrate [up | down [N] | status]
+  up        执行全部未执行的迁移（默认）
+  down [N]  回滚最近执行的 N 个迁移（默认 1）
+  status    列出全部迁移及其执行状态`
+
+// runMigrate 执行 migrate 子命令并返回进程退出码
+func runMigrate(cfg *config.Config, args []string) int {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	steps := 1
+	switch action {
+	case "up", "status":
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+	case "down":
+		if len(args) > 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, "回滚数量必须是正整数")
+				return 2
+			}
+			steps = n
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db, err := config.OpenDatabase(cfg)
+	if err != nil {
+		slog.Error("连接数据库失败", "error", err)
+		return 1
+	}
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		applied, err := migrate.Up(ctx, db)
+		for _, m := range applied {
+			fmt.Printf("已执行 %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			slog.Error("数据库迁移失败", "error", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("没有待执行的迁移")
+		}
+	case "down":
+		reverted, err := migrate.Down(ctx, db, steps)
+		for _, m := range reverted {
+			fmt.Printf("已回滚 %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			slog.Error("回滚迁移失败", "error", err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+	case "status":
+		list, err := migrate.StatusOf(ctx, db)
+		if err != nil {
+			slog.Error("读取迁移状态失败", "error", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED_AT")
+		for _, s := range list {
+			status, at := "pending", ""
+			if s.Applied {
+				status, at = "applied", s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, status, at)
+		}
+		w.Flush()
+	}
+	return 0
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/sdkgen"
+	"gorm.io/gorm"
+)
+
+const sdkHelp = `用法: app sdk <subcommand> [flags]
+  generate --lang go|typescript [-o file] [--package client] [--watch] [--interval 5s]
+      按当前已发布的动态服务生成客户端（每个服务一个类型化函数）。
+      --watch 持续检查服务变更，生成结果变化时重写 -o 指定的文件`
+
+// sdkDatasourceWait 是生成客户端前等待附加数据源连接的最长时间，超时的数据源上的服务结果列按未知处理
+const sdkDatasourceWait = 10 * time.Second
+
+// runSDK 执行 sdk 子命令
+func runSDK(cfg *config.Config, args []string) int {
+	sub, args, ok := subcommand(args, sdkHelp)
+	if !ok {
+		return 2
+	}
+	if sub != "generate" {
+		fmt.Fprintf(os.Stderr, "未知的 sdk 子命令 %q\n\n%s\n", sub, sdkHelp)
+		return 2
+	}
+
+	fs := newFlagSet("sdk generate", "--lang go|typescript [-o file] [--package client] [--watch] [--interval 5s]")
+	lang := fs.String("lang", "", "客户端语言: go | typescript")
+	out := fs.String("o", "-", "输出文件，- 表示 stdout")
+	pkg := fs.String("package", sdkgen.DefaultPackage, "Go 客户端的包名")
+	watch := fs.Bool("watch", false, "持续检查服务变更并重新生成（需指定 -o）")
+	interval := fs.Duration("interval", 5*time.Second, "--watch 检查服务变更的间隔")
+	if _, ok := parseFlags(fs, args); !ok {
+		return 2
+	}
+	normalized := sdkgen.NormalizeLang(*lang)
+	if normalized == "" {
+		fmt.Fprintf(os.Stderr, "--lang 必须为 go 或 typescript\n")
+		return 2
+	}
+	if *watch && (*out == "" || *out == "-") {
+		fmt.Fprintf(os.Stderr, "--watch 需要通过 -o 指定输出文件\n")
+		return 2
+	}
+	if *interval <= 0 {
+		fmt.Fprintf(os.Stderr, "--interval 必须大于 0\n")
+		return 2
+	}
+
+	if !connectCLI(cfg) {
+		return 1
+	}
+	defer closeCLI()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	openCLIDatasources(ctx, cfg)
+	defer config.CloseDatasources()
+
+	opts := sdkgen.Options{Package: *pkg}
+	generate := func() bool {
+		src, err := sdkgen.Generate(ctx, config.DB, normalized, opts)
+		if err != nil {
+			slog.Error("生成客户端失败", "error", err)
+			return false
+		}
+		changed, err := writeIfChanged(*out, src)
+		if err != nil {
+			slog.Error("写入客户端失败", "file", *out, "error", err)
+			return false
+		}
+		if changed && *out != "-" {
+			slog.Info("客户端已生成", "lang", normalized, "file", *out)
+		}
+		return true
+	}
+	if !generate() {
+		return 1
+	}
+	if !*watch {
+		return 0
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sig:
+			return 0
+		case <-ticker.C:
+		}
+		// 生成失败（例如数据库暂时不可用）时保留上一次的文件，下一次检查时重试
+		generate()
+	}
+}
+
+// openCLIDatasources 连接配置中的附加数据源，最多等待 sdkDatasourceWait
+func openCLIDatasources(ctx context.Context, cfg *config.Config) {
+	if len(cfg.Datasources) == 0 {
+		return
+	}
+	var opened atomic.Int32
+	done := make(chan struct{})
+	config.OpenDatasources(ctx, cfg, func(name string, db *gorm.DB) {
+		if int(opened.Add(1)) == len(cfg.Datasources) {
+			close(done)
+		}
+	})
+	select {
+	case <-done:
+	case <-time.After(sdkDatasourceWait):
+		slog.Warn("部分数据源未能连接，其服务的结果行类型按未知处理", "wait", sdkDatasourceWait.String())
+	}
+}
+
+// writeIfChanged 将 data 写入 path（- 表示 stdout），文件内容未变化时不重写，返回是否写入。
+// 先写入同目录的临时文件再重命名，监听文件变化的构建工具不会读到写了一半的内容
+func writeIfChanged(path string, data []byte) (bool, error) {
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(data)
+		return true, err
+	}
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+		return false, nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/handlers"
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+const serviceHelp = `用法: app service <subcommand> [flags]
+  list [--json]                 列出全部动态服务
+  show <name|id>                以 JSON 输出服务定义
+  register -f service.json      注册服务（JSON 字段与 POST /api/v1/dynamic/register 相同，- 表示 stdin）
+  register --name N --method M --path P --sql S [--param-keys JSON --param-types JSON ...]
+                                [--kind command --max-affected-rows N] 注册写服务
+                                [--kind pipeline --steps JSON --isolation-level L] 注册多步骤流水线服务
+  status <name|id> <status>     设置发布状态: draft、active、disabled (503) 或 deprecated
+  disable <name|id> [--enable]  停用（或重新启用）服务
+  export [-o file] [--format yaml|json] [name ...]
+                                导出全部或指定服务的定义包 (默认 YAML)
+  import -f file [--on-conflict skip|overwrite|fail] [--dry-run] [--json]
+                                导入定义包: 先校验全部服务，在单个事务中应用，--dry-run 只输出差异`
+
+// runService 执行 service 子命令
+func runService(cfg *config.Config, args []string) int {
+	sub, args, ok := subcommand(args, serviceHelp)
+	if !ok {
+		return 2
+	}
+	handlersBySub := map[string]func([]string) int{
+		"list":     serviceList,
+		"show":     serviceShow,
+		"register": serviceRegister,
+		"disable":  serviceDisable,
+		"status":   serviceStatus,
+		"export":   serviceExport,
+		"import":   serviceImport,
+	}
+	run, ok := handlersBySub[sub]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知的 service 子命令 %q\n\n%s\n", sub, serviceHelp)
+		return 2
+	}
+	if !connectCLI(cfg) {
+		return 1
+	}
+	defer closeCLI()
+	return run(args)
+}
+
+// findService 按名称或 ID 查找服务
+func findService(ref string) (*models.APIService, error) {
+	var service models.APIService
+	query := config.DB.Where("name = ?", ref)
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		query = config.DB.Where("name = ? OR id = ?", ref, id)
+	}
+	if err := query.First(&service).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("动态服务 %s 不存在", ref)
+		}
+		return nil, err
+	}
+	return &service, nil
+}
+
+func serviceList(args []string) int {
+	fs := newFlagSet("service list", "[--json]")
+	asJSON := fs.Bool("json", false, "以 JSON 数组输出")
+	if _, ok := parseFlags(fs, args); !ok {
+		return 2
+	}
+
+	var services []models.APIService
+	if err := config.DB.Order("id").Find(&services).Error; err != nil {
+		slog.Error("查询动态服务失败", "error", err)
+		return 1
+	}
+	if *asJSON {
+		writeJSON(os.Stdout, services)
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tMETHOD\tPATH\tDATASOURCE\tSTATUS\tMANAGED_BY\tSQL")
+	for _, s := range services {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.ID, s.Name, s.Method, s.Path, datasourceLabel(s.Datasource), s.Status, orDash(s.ManagedBy), oneLine(s.SQL, 60))
+	}
+	w.Flush()
+	return 0
+}
+
+// datasourceLabel 返回服务数据源的显示名称（空表示主库）
+func datasourceLabel(name string) string {
+	if name == "" {
+		return config.DefaultDatasource
+	}
+	return name
+}
+
+func serviceShow(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "用法: app service show <name|id>")
+		return 2
+	}
+	service, err := findService(args[0])
+	if err != nil {
+		slog.Error("查询动态服务失败", "error", err)
+		return 1
+	}
+	writeJSON(os.Stdout, service)
+	return 0
+}
+
+func serviceRegister(args []string) int {
+	fs := newFlagSet("service register", "-f service.json | --name N --method M --path P --sql S [flags]")
+	file := fs.String("f", "", "服务定义 JSON 文件，- 表示 stdin")
+	var s models.APIService
+	fs.StringVar(&s.Name, "name", "", "服务名称")
+	fs.StringVar(&s.Method, "method", "GET", "HTTP 方法 (GET|POST|PUT|DELETE)")
+	fs.StringVar(&s.Path, "path", "", "执行路径 (/api/v1/dynamic/run 之后的部分)")
+	fs.StringVar(&s.SQL, "sql", "", "只读 SQL 语句；--kind command 时为单条 INSERT/UPDATE/DELETE")
+	fs.StringVar(&s.Kind, "kind", models.ServiceKindQuery, "服务类型: query、command (写服务，需启用 dynamic.commands_enabled) 或 pipeline")
+	fs.StringVar(&s.Steps, "steps", "", `pipeline 服务的步骤 JSON 数组，例如 '[{"name":"a","sql":"SELECT ...","params":["id"]}]'`)
+	fs.StringVar(&s.IsolationLevel, "isolation-level", "", "command 与 pipeline 服务的事务隔离级别: read_uncommitted、read_committed、repeatable_read 或 serializable")
+	fs.IntVar(&s.MaxAffectedRows, "max-affected-rows", 0, "写服务单次执行允许影响的最大行数，0 表示使用 dynamic.command_max_affected_rows")
+	fs.StringVar(&s.ParamKeys, "param-keys", "", `参数名 JSON 数组，例如 '["id"]'`)
+	fs.StringVar(&s.ParamTypes, "param-types", "", `参数类型 JSON 数组，例如 '["int"]'`)
+	fs.StringVar(&s.Datasource, "datasource", "", "数据源名称，默认主库")
+	fs.StringVar(&s.AllowedOrigins, "allowed-origins", "", "额外允许跨域调用的来源 JSON 数组")
+	fs.BoolVar(&s.RequiresFreshData, "requires-fresh-data", false, "始终在主库执行，不使用只读副本")
+	fs.StringVar(&s.Status, "status", models.ServiceStatusActive, "发布状态: draft、active、disabled 或 deprecated")
+	activates := fs.String("activates-at", "", "生效时间 (RFC 3339)，之前调用返回 404")
+	expires := fs.String("expires-at", "", "到期时间 (RFC 3339)，之后调用返回 410")
+	if _, ok := parseFlags(fs, args); !ok {
+		return 2
+	}
+
+	if *file != "" {
+		data, err := readInput(*file)
+		if err != nil {
+			slog.Error("读取服务定义失败", "error", err)
+			return 1
+		}
+		s = models.APIService{}
+		if err := json.Unmarshal(data, &s); err != nil {
+			slog.Error("服务定义不是有效的 JSON", "error", err)
+			return 1
+		}
+	} else {
+		for _, t := range []struct {
+			flag, value string
+			dst         **time.Time
+		}{{"--activates-at", *activates, &s.ActivatesAt}, {"--expires-at", *expires, &s.ExpiresAt}} {
+			if t.value == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, t.value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s 不是有效的 RFC 3339 时间: %v\n", t.flag, err)
+				return 2
+			}
+			*t.dst = &parsed
+		}
+	}
+
+	if err := handlers.ValidateService(&s); err != nil {
+		fmt.Fprintln(os.Stderr, "服务定义无效:", err)
+		return 1
+	}
+	if err := handlers.CheckNotManaged(config.DB, s.Name, s.Path); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	s.ManagedBy = ""
+	s.TrackDeprecation(nil, time.Now())
+	if err := config.DB.Create(&s).Error; err != nil {
+		slog.Error("服务注册失败，可能是路径或名称已存在", "error", err)
+		return 1
+	}
+	writeJSON(os.Stdout, s)
+	return 0
+}
+
+func serviceDisable(args []string) int {
+	fs := newFlagSet("service disable", "<name|id> [--enable]")
+	enable := fs.Bool("enable", false, "重新启用服务 (等同于 service status <name|id> active)")
+	rest, ok := parseFlags(fs, args)
+	if !ok {
+		return 2
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		return 2
+	}
+	status := models.ServiceStatusDisabled
+	if *enable {
+		status = models.ServiceStatusActive
+	}
+	return setServiceStatus(rest[0], status)
+}
+
+func serviceStatus(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "用法: app service status <name|id> <draft|active|disabled|deprecated>")
+		return 2
+	}
+	switch args[1] {
+	case models.ServiceStatusDraft, models.ServiceStatusActive, models.ServiceStatusDisabled, models.ServiceStatusDeprecated:
+	default:
+		fmt.Fprintf(os.Stderr, "未知的服务状态 %q (可选 draft、active、disabled、deprecated)\n", args[1])
+		return 2
+	}
+	return setServiceStatus(args[0], args[1])
+}
+
+// setServiceStatus 更新服务的发布状态，由文件管理的服务只能通过修改文件变更
+func setServiceStatus(ref, status string) int {
+	service, err := findService(ref)
+	if err != nil {
+		slog.Error("查询动态服务失败", "error", err)
+		return 1
+	}
+	if service.ManagedBy != "" {
+		fmt.Fprintf(os.Stderr, "动态服务 %s 由文件 %s 管理，请修改服务定义目录中的 status 字段\n", service.Name, service.ManagedBy)
+		return 1
+	}
+	updated := *service
+	updated.Status = status
+	updated.TrackDeprecation(service, time.Now())
+	if err := config.DB.Model(service).Updates(map[string]interface{}{"status": status, "deprecated_at": updated.DeprecatedAt}).Error; err != nil {
+		slog.Error("更新服务状态失败", "error", err)
+		return 1
+	}
+	fmt.Printf("动态服务 %s 的状态已更新为 %s\n", service.Name, status)
+	return 0
+}
+
+func serviceExport(args []string) int {
+	fs := newFlagSet("service export", "[-o file] [--format yaml|json] [name ...]")
+	out := fs.String("o", "-", "输出文件，- 表示 stdout")
+	format := fs.String("format", "", "输出格式 yaml 或 json，默认按输出文件扩展名，否则为 yaml")
+	names, ok := parseFlags(fs, args)
+	if !ok {
+		return 2
+	}
+	if *format == "" {
+		*format = "yaml"
+		if strings.HasSuffix(*out, ".json") {
+			*format = "json"
+		}
+	}
+
+	bundle, err := handlers.ExportBundle(config.DB, names)
+	if err != nil {
+		slog.Error("导出动态服务失败", "error", err)
+		return 1
+	}
+	w, err := openOutput(*out)
+	if err != nil {
+		slog.Error("创建输出文件失败", "error", err)
+		return 1
+	}
+	defer w.Close()
+	if err := handlers.EncodeBundle(w, bundle, *format); err != nil {
+		slog.Error("写入导出文件失败", "error", err)
+		return 1
+	}
+	return 0
+}
+
+func serviceImport(args []string) int {
+	fs := newFlagSet("service import", "-f file [--on-conflict skip|overwrite|fail] [--dry-run] [--json]")
+	file := fs.String("f", "", "YAML 或 JSON 定义包文件，- 表示 stdin")
+	strategy := fs.String("on-conflict", handlers.ConflictFail, "与已有同名服务定义不同时的处理: skip、overwrite 或 fail")
+	dryRun := fs.Bool("dry-run", false, "只输出导入计划与字段差异，不修改数据库")
+	asJSON := fs.Bool("json", false, "以 JSON 输出导入计划")
+	if _, ok := parseFlags(fs, args); !ok {
+		return 2
+	}
+	if !handlers.ValidConflictStrategy(*strategy) {
+		fmt.Fprintln(os.Stderr, "--on-conflict 仅支持 skip、overwrite 或 fail")
+		return 2
+	}
+	data, err := readInput(*file)
+	if err != nil {
+		slog.Error("读取导入文件失败", "error", err)
+		return 1
+	}
+	bundle, err := handlers.ParseBundle(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	plan, err := handlers.ImportBundle(config.DB, bundle, *strategy, *dryRun)
+	var invalid *handlers.BundleValidationError
+	if errors.As(err, &invalid) {
+		fmt.Fprintln(os.Stderr, "定义包校验失败，未做任何修改:")
+		for _, e := range invalid.Errors {
+			fmt.Fprintln(os.Stderr, "  "+e)
+		}
+		return 1
+	}
+	if plan != nil {
+		if *asJSON {
+			writeJSON(os.Stdout, plan)
+		} else {
+			printBundlePlan(plan)
+		}
+	}
+	if err != nil {
+		slog.Error("导入动态服务失败，已全部回滚", "error", err)
+		return 1
+	}
+	return 0
+}
+
+// printBundlePlan 以类似 diff 的文本格式输出导入计划
+func printBundlePlan(plan *handlers.BundlePlan) {
+	marks := map[string]string{
+		handlers.BundleActionCreate:    "+",
+		handlers.BundleActionUpdate:    "~",
+		handlers.BundleActionUnchanged: "=",
+		handlers.BundleActionSkip:      "-",
+		handlers.BundleActionConflict:  "!",
+	}
+	for _, c := range plan.Changes {
+		line := fmt.Sprintf("%s %-9s %s", marks[c.Action], c.Action, c.Name)
+		if c.Reason != "" {
+			line += " (" + c.Reason + ")"
+		}
+		fmt.Println(line)
+		for _, d := range c.Diff {
+			fmt.Printf("      %s: %s -> %s\n", d.Field, oneLine(d.Old, 60), oneLine(d.New, 60))
+		}
+	}
+	summary := fmt.Sprintf("新增 %d，更新 %d，未变 %d，跳过 %d，冲突 %d",
+		plan.Created, plan.Updated, plan.Unchanged, plan.Skipped, plan.Conflicts)
+	switch {
+	case plan.Applied:
+		fmt.Println("已导入: " + summary)
+	case plan.DryRun && plan.Conflicts == 0:
+		fmt.Println("dry-run，未修改数据库: " + summary)
+	default:
+		fmt.Println("未做任何修改: " + summary)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+const userHelp = `用法: app user <subcommand> [flags]
+  export [-o file]   以 JSON 数组导出全部用户
+  import -f file     从 JSON 数组导入用户（单个事务，任一失败则全部回滚）`
+
+// runUser 执行 user 子命令
+func runUser(cfg *config.Config, args []string) int {
+	sub, args, ok := subcommand(args, userHelp)
+	if !ok {
+		return 2
+	}
+	var run func([]string) int
+	switch sub {
+	case "export":
+		run = userExport
+	case "import":
+		run = userImport
+	default:
+		fmt.Fprintf(os.Stderr, "未知的 user 子命令 %q\n\n%s\n", sub, userHelp)
+		return 2
+	}
+	if !connectCLI(cfg) {
+		return 1
+	}
+	defer closeCLI()
+	return run(args)
+}
+
+func userExport(args []string) int {
+	fs := newFlagSet("user export", "[-o file]")
+	out := fs.String("o", "-", "输出文件，- 表示 stdout")
+	if _, ok := parseFlags(fs, args); !ok {
+		return 2
+	}
+
+	var users []models.User
+	if err := config.DB.Order("id").Find(&users).Error; err != nil {
+		slog.Error("查询用户失败", "error", err)
+		return 1
+	}
+	w, err := openOutput(*out)
+	if err != nil {
+		slog.Error("创建输出文件失败", "error", err)
+		return 1
+	}
+	defer w.Close()
+	if err := writeJSON(w, users); err != nil {
+		slog.Error("写入导出文件失败", "error", err)
+		return 1
+	}
+	return 0
+}
+
+func userImport(args []string) int {
+	fs := newFlagSet("user import", "-f file")
+	file := fs.String("f", "", "JSON 数组文件（字段与 POST /api/v1/users 相同），- 表示 stdin")
+	if _, ok := parseFlags(fs, args); !ok {
+		return 2
+	}
+	data, err := readInput(*file)
+	if err != nil {
+		slog.Error("读取导入文件失败", "error", err)
+		return 1
+	}
+	var users []models.User
+	if err := json.Unmarshal(data, &users); err != nil {
+		slog.Error("导入文件不是有效的 JSON 数组", "error", err)
+		return 1
+	}
+
+	for i := range users {
+		u := &users[i]
+		u.ID = 0 // 由目标数据库分配 ID
+		u.Username = strings.TrimSpace(u.Username)
+		u.Email = strings.TrimSpace(u.Email)
+		if u.Username == "" || u.Email == "" {
+			fmt.Fprintf(os.Stderr, "第 %d 个用户缺少 username 或 email\n", i+1)
+			return 1
+		}
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range users {
+			if err := tx.Create(&users[i]).Error; err != nil {
+				return fmt.Errorf("导入用户 %s 失败: %w", users[i].Username, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("导入用户失败，已全部回滚", "error", err)
+		return 1
+	}
+	fmt.Printf("已导入 %d 个用户\n", len(users))
+	return 0
+}
@@ -0,0 +1,565 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"go-gin-gorm-api/app/audit"
+	"go-gin-gorm-api/app/logging"
+	"go-gin-gorm-api/app/tracing"
+)
+
+// Config 是应用的完整配置。
+// 加载顺序: 内置默认值 → 配置文件 (YAML) → 环境变量覆盖，最后统一校验。
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	CORS     CORSConfig     `yaml:"cors"`
+	Dynamic  DynamicConfig  `yaml:"dynamic"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	Audit    AuditConfig    `yaml:"audit"`
+	Tracing  tracing.Config `yaml:"tracing"`
+
+	// Datasources 是动态服务可使用的附加命名数据源（键为数据源名称），
+	// 未配置的连接池与超时参数沿用 database 配置段。主库的名称固定为 "default"。
+	Datasources map[string]DatabaseConfig `yaml:"datasources"`
+}
+
+// ServerConfig HTTP 服务器配置
+type ServerConfig struct {
+	Port             int           `yaml:"port"`
+	Mode             string        `yaml:"mode"` // gin 运行模式: debug | release | test
+	ReadTimeout      time.Duration `yaml:"read_timeout"`
+	WriteTimeout     time.Duration `yaml:"write_timeout"`
+	IdleTimeout      time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout  time.Duration `yaml:"shutdown_timeout"`
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout"` // /readyz 每项检查的超时时间
+}
+
+// DatabaseConfig 数据库连接、连接池与重试配置
+type DatabaseConfig struct {
+	// Driver 是数据库驱动: mysql (默认) | postgres | sqlite。使用 sqlite 时 Name 为数据库文件路径
+	// (":memory:" 表示内存数据库)，Host 与 Port 不需要配置
+	Driver   string `yaml:"driver"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+
+	WaitOnStartup bool        `yaml:"wait_on_startup"`
+	Retry         RetryConfig `yaml:"retry"`
+
+	// MigrateOnStartup 为 true 时启动时执行未执行的版本化迁移；为 false 时仅检查，
+	// 存在未执行的迁移则等待（按重试策略）直到通过 `app migrate up` 完成迁移。
+	// AutoMigrate 为 true 时改用 GORM AutoMigrate 按当前模型同步表结构，仅用于本地开发。
+	MigrateOnStartup bool `yaml:"migrate_on_startup"`
+	AutoMigrate      bool `yaml:"auto_migrate"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	TLS          string        `yaml:"tls"` // false | true | skip-verify | preferred
+	TLSCAFile    string        `yaml:"tls_ca_file"`
+	Timezone     string        `yaml:"timezone"`
+	DialTimeout  time.Duration `yaml:"dial_timeout"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+
+	// Replicas 是主库的只读副本，未配置的用户名、密码、库名与连接参数沿用主库；仅 database 配置段支持
+	Replicas              []DatabaseConfig `yaml:"replicas"`
+	ReplicaHealthInterval time.Duration    `yaml:"replica_health_interval"` // 副本健康检查间隔
+}
+
+// RetryConfig 数据库连接重试 (指数退避) 配置
+type RetryConfig struct {
+	InitialInterval time.Duration `yaml:"initial_interval"`
+	MaxInterval     time.Duration `yaml:"max_interval"`
+	MaxWait         time.Duration `yaml:"max_wait"` // 0 表示一直重试
+}
+
+// DynamicConfig 动态服务执行限制（可热加载，ServicesDir 除外）
+type DynamicConfig struct {
+	MaxRows      int           `yaml:"max_rows"`
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+
+	// ServicesDir 是声明式服务定义目录 (GitOps 模式)，为空表示不启用。目录中的 YAML/JSON
+	// 定义包在启动时与文件变更后同步到数据库，由文件管理的服务不能通过注册接口修改。修改需重启
+	ServicesDir string `yaml:"services_dir"`
+	// ServicesSyncInterval 是检查目录文件变更的间隔
+	ServicesSyncInterval time.Duration `yaml:"services_sync_interval"`
+
+	// CommandsEnabled 为 true 时允许注册与执行 kind=command 的写服务 (INSERT/UPDATE/DELETE)，默认关闭
+	CommandsEnabled bool `yaml:"commands_enabled"`
+	// CommandMaxAffectedRows 是写服务未设置 max_affected_rows 时单次执行允许影响的最大行数，超过时回滚
+	CommandMaxAffectedRows int `yaml:"command_max_affected_rows"`
+}
+
+// AuthConfig 认证配置
+type AuthConfig struct {
+	AdminToken string `yaml:"admin_token"` // 管理接口令牌，为空时管理接口关闭
+}
+
+// LoggingConfig 日志配置，其中 Level 可热加载
+type LoggingConfig struct {
+	Level              string        `yaml:"level"`  // debug | info | warn | error
+	Format             string        `yaml:"format"` // json | text
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+	SQLParams          bool          `yaml:"sql_params"`
+}
+
+// AuditConfig 审计写入器、保留策略与哈希链配置
+type AuditConfig struct {
+	audit.Config `yaml:",inline"`
+	HMACKey      string `yaml:"hmac_key"`
+}
+
+// Default 返回内置默认配置
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:             8080,
+			ReadTimeout:      15 * time.Second,
+			WriteTimeout:     60 * time.Second,
+			IdleTimeout:      120 * time.Second,
+			ShutdownTimeout:  30 * time.Second,
+			ReadinessTimeout: 2 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:           DriverMySQL,
+			MigrateOnStartup: true,
+			Retry: RetryConfig{
+				InitialInterval: 500 * time.Millisecond,
+				MaxInterval:     10 * time.Second,
+				MaxWait:         60 * time.Second,
+			},
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			DialTimeout:     5 * time.Second,
+			ReadTimeout:     30 * time.Second,
+			WriteTimeout:    30 * time.Second,
+
+			ReplicaHealthInterval: 5 * time.Second,
+		},
+		CORS: defaultCORS(),
+		Dynamic: DynamicConfig{
+			MaxRows:              1000,
+			QueryTimeout:         5 * time.Second,
+			ServicesSyncInterval: 5 * time.Second,
+
+			CommandMaxAffectedRows: 100,
+		},
+		Logging: LoggingConfig{
+			Level:              "info",
+			Format:             "json",
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		Audit: AuditConfig{Config: audit.Config{
+			QueueSize:          10000,
+			BatchSize:          100,
+			FlushInterval:      time.Second,
+			FullPolicy:         audit.PolicyBlock,
+			EnqueueTimeout:     50 * time.Millisecond,
+			RetentionInterval:  time.Hour,
+			CheckpointInterval: time.Hour,
+		}},
+		Tracing: tracing.Config{
+			Exporter:    tracing.ExporterNone,
+			SampleRatio: 1,
+		},
+	}
+}
+
+// current 保存当前生效的配置，热加载时整体替换
+var current atomic.Pointer[Config]
+
+// Current 返回当前生效的配置。尚未加载时返回默认配置。
+// 返回值应视为只读：修改配置请构造新副本并调用 SetCurrent。
+func Current() *Config {
+	if c := current.Load(); c != nil {
+		return c
+	}
+	return Default()
+}
+
+// SetCurrent 替换当前生效的配置
+func SetCurrent(c *Config) {
+	current.Store(c)
+}
+
+// Load 加载配置: 默认值 → path 指定的 YAML 文件 (为空时跳过) → 环境变量，并校验。
+// 配置文件中出现未知字段或环境变量格式错误均视为错误。
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取配置文件失败: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	cfg.applyDriverDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// envOverrides 收集环境变量覆盖过程中的格式错误
+type envOverrides struct{ errs []error }
+
+// str 读取字符串环境变量。空值视为未设置，避免 .env 中留空的示例项覆盖配置文件
+func (e *envOverrides) str(key string, dst *string) {
+	if v := os.Getenv(key); v != "" {
+		*dst = v
+	}
+}
+
+func (e *envOverrides) int(key string, dst *int) {
+	if v := os.Getenv(key); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("环境变量 %s=%q 不是有效的整数", key, v))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envOverrides) float(key string, dst *float64) {
+	if v := os.Getenv(key); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("环境变量 %s=%q 不是有效的数字", key, v))
+			return
+		}
+		*dst = f
+	}
+}
+
+func (e *envOverrides) bool(key string, dst *bool) {
+	if v := os.Getenv(key); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("环境变量 %s=%q 不是有效的布尔值", key, v))
+			return
+		}
+		*dst = b
+	}
+}
+
+// duration 读取以 unit 为单位的整数环境变量（与历史环境变量保持兼容，例如 *_SECONDS、*_MS）
+func (e *envOverrides) duration(key string, unit time.Duration, dst *time.Duration) {
+	if v := os.Getenv(key); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("环境变量 %s=%q 不是有效的整数", key, v))
+			return
+		}
+		*dst = time.Duration(n) * unit
+	}
+}
+
+// list 读取逗号分隔的列表环境变量
+func (e *envOverrides) list(key string, dst *[]string) {
+	if v := os.Getenv(key); v != "" {
+		var items []string
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		*dst = items
+	}
+}
+
+// applyEnv 用环境变量覆盖配置文件中的值，环境变量名与历史版本保持一致
+func (c *Config) applyEnv() error {
+	e := &envOverrides{}
+
+	e.int("PORT", &c.Server.Port)
+	e.str("GIN_MODE", &c.Server.Mode)
+	e.duration("SERVER_READ_TIMEOUT_SECONDS", time.Second, &c.Server.ReadTimeout)
+	e.duration("SERVER_WRITE_TIMEOUT_SECONDS", time.Second, &c.Server.WriteTimeout)
+	e.duration("SERVER_IDLE_TIMEOUT_SECONDS", time.Second, &c.Server.IdleTimeout)
+	e.duration("SHUTDOWN_TIMEOUT_SECONDS", time.Second, &c.Server.ShutdownTimeout)
+	e.duration("READINESS_TIMEOUT_MS", time.Millisecond, &c.Server.ReadinessTimeout)
+
+	// 主库连接参数: MYSQL_* 为历史名称，DB_* 为与驱动无关的通用名称（同时设置时 DB_* 优先）
+	e.str("DB_DRIVER", &c.Database.Driver)
+	e.str("MYSQL_HOST", &c.Database.Host)
+	e.str("MYSQL_PORT", &c.Database.Port)
+	e.str("MYSQL_USER", &c.Database.User)
+	e.str("MYSQL_PASSWORD", &c.Database.Password)
+	e.str("MYSQL_DATABASE", &c.Database.Name)
+	e.str("DB_HOST", &c.Database.Host)
+	e.str("DB_PORT", &c.Database.Port)
+	e.str("DB_USER", &c.Database.User)
+	e.str("DB_PASSWORD", &c.Database.Password)
+	e.str("DB_NAME", &c.Database.Name)
+	e.bool("DB_WAIT_ON_STARTUP", &c.Database.WaitOnStartup)
+	e.bool("DB_MIGRATE_ON_STARTUP", &c.Database.MigrateOnStartup)
+	e.bool("DB_AUTO_MIGRATE", &c.Database.AutoMigrate)
+	// DB_REPLICA_HOSTS: 逗号分隔的 host[:port] 列表，覆盖配置文件中的副本
+	var replicaHosts []string
+	e.list("DB_REPLICA_HOSTS", &replicaHosts)
+	if replicaHosts != nil {
+		c.Database.Replicas = nil
+		for _, hp := range replicaHosts {
+			host, port, err := net.SplitHostPort(hp)
+			if err != nil {
+				host, port = hp, ""
+			}
+			c.Database.Replicas = append(c.Database.Replicas, DatabaseConfig{Host: host, Port: port})
+		}
+	}
+	e.duration("DB_REPLICA_HEALTH_INTERVAL_SECONDS", time.Second, &c.Database.ReplicaHealthInterval)
+	e.duration("DB_RETRY_INITIAL_INTERVAL_MS", time.Millisecond, &c.Database.Retry.InitialInterval)
+	e.duration("DB_RETRY_MAX_INTERVAL_SECONDS", time.Second, &c.Database.Retry.MaxInterval)
+	e.duration("DB_RETRY_MAX_WAIT_SECONDS", time.Second, &c.Database.Retry.MaxWait)
+	e.int("DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns)
+	e.int("DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns)
+	e.duration("DB_CONN_MAX_LIFETIME_SECONDS", time.Second, &c.Database.ConnMaxLifetime)
+	e.duration("DB_CONN_MAX_IDLE_TIME_SECONDS", time.Second, &c.Database.ConnMaxIdleTime)
+	e.str("DB_TLS", &c.Database.TLS)
+	e.str("DB_TLS_CA_FILE", &c.Database.TLSCAFile)
+	e.str("DB_TIMEZONE", &c.Database.Timezone)
+	e.duration("DB_DIAL_TIMEOUT_SECONDS", time.Second, &c.Database.DialTimeout)
+	e.duration("DB_READ_TIMEOUT_SECONDS", time.Second, &c.Database.ReadTimeout)
+	e.duration("DB_WRITE_TIMEOUT_SECONDS", time.Second, &c.Database.WriteTimeout)
+
+	e.list("CORS_ALLOW_ORIGINS", &c.CORS.AllowOrigins)
+	e.bool("CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials)
+	if os.Getenv("CORS_MANAGEMENT_ALLOW_ORIGINS") != "" {
+		c.CORS.Management = c.CORS.Management.orDefault(c.CORS.CORSPolicy)
+		e.list("CORS_MANAGEMENT_ALLOW_ORIGINS", &c.CORS.Management.AllowOrigins)
+	}
+	if os.Getenv("CORS_RUN_ALLOW_ORIGINS") != "" {
+		c.CORS.Run = c.CORS.Run.orDefault(c.CORS.CORSPolicy)
+		e.list("CORS_RUN_ALLOW_ORIGINS", &c.CORS.Run.AllowOrigins)
+	}
+
+	e.int("DYNAMIC_MAX_ROWS", &c.Dynamic.MaxRows)
+	e.duration("DYNAMIC_QUERY_TIMEOUT_SECONDS", time.Second, &c.Dynamic.QueryTimeout)
+	e.str("DYNAMIC_SERVICES_DIR", &c.Dynamic.ServicesDir)
+	e.duration("DYNAMIC_SERVICES_SYNC_INTERVAL_SECONDS", time.Second, &c.Dynamic.ServicesSyncInterval)
+	e.bool("DYNAMIC_COMMANDS_ENABLED", &c.Dynamic.CommandsEnabled)
+	e.int("DYNAMIC_COMMAND_MAX_AFFECTED_ROWS", &c.Dynamic.CommandMaxAffectedRows)
+
+	e.str("ADMIN_API_TOKEN", &c.Auth.AdminToken)
+
+	e.str("LOG_LEVEL", &c.Logging.Level)
+	e.str("LOG_FORMAT", &c.Logging.Format)
+	e.duration("LOG_SLOW_QUERY_MS", time.Millisecond, &c.Logging.SlowQueryThreshold)
+	e.bool("LOG_SQL_PARAMS", &c.Logging.SQLParams)
+
+	e.int("AUDIT_QUEUE_SIZE", &c.Audit.QueueSize)
+	e.int("AUDIT_BATCH_SIZE", &c.Audit.BatchSize)
+	e.duration("AUDIT_FLUSH_INTERVAL_MS", time.Millisecond, &c.Audit.FlushInterval)
+	e.str("AUDIT_QUEUE_FULL_POLICY", &c.Audit.FullPolicy)
+	e.duration("AUDIT_ENQUEUE_TIMEOUT_MS", time.Millisecond, &c.Audit.EnqueueTimeout)
+	e.int("AUDIT_RETENTION_DAYS", &c.Audit.RetentionDays)
+	e.duration("AUDIT_RETENTION_INTERVAL_MINUTES", time.Minute, &c.Audit.RetentionInterval)
+	e.str("AUDIT_ARCHIVE_DIR", &c.Audit.ArchiveDir)
+	e.str("AUDIT_CHECKPOINT_FILE", &c.Audit.CheckpointFile)
+	e.duration("AUDIT_CHECKPOINT_INTERVAL_MINUTES", time.Minute, &c.Audit.CheckpointInterval)
+	e.str("AUDIT_HMAC_KEY", &c.Audit.HMACKey)
+
+	// 只读副本与附加数据源沿用主库的连接参数（需在主库环境变量覆盖之后处理）
+	for i := range c.Database.Replicas {
+		c.Database.Replicas[i] = c.Database.Replicas[i].inheritReplica(c.Database)
+	}
+
+	// 附加数据源: DATASOURCE_<NAME>_DRIVER / _HOST / _PORT / _USER / _PASSWORD / _DATABASE，
+	// NAME 为数据源名称的大写形式（- 替换为 _），便于通过环境变量注入凭据
+	for name, ds := range c.Datasources {
+		prefix := "DATASOURCE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		e.str(prefix+"DRIVER", &ds.Driver)
+		e.str(prefix+"HOST", &ds.Host)
+		e.str(prefix+"PORT", &ds.Port)
+		e.str(prefix+"USER", &ds.User)
+		e.str(prefix+"PASSWORD", &ds.Password)
+		e.str(prefix+"DATABASE", &ds.Name)
+		c.Datasources[name] = ds.inherit(c.Database)
+	}
+
+	e.str("TRACING_EXPORTER", &c.Tracing.Exporter)
+	e.str("TRACING_FILE", &c.Tracing.FilePath)
+	e.str("OTEL_SERVICE_NAME", &c.Tracing.ServiceName)
+	e.float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	return errors.Join(e.errs...)
+}
+
+// applyDriverDefaults 为主库、只读副本与附加数据源规范化驱动名称并填充默认端口
+func (c *Config) applyDriverDefaults() {
+	c.Database.applyDriverDefaults()
+	for i := range c.Database.Replicas {
+		c.Database.Replicas[i].applyDriverDefaults()
+	}
+	for name, ds := range c.Datasources {
+		ds.applyDriverDefaults()
+		c.Datasources[name] = ds
+	}
+}
+
+// Validate 校验配置，返回包含所有问题的错误（每行一项，带字段路径）
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		fail("server.port", "必须在 1-65535 之间，当前为 %d", c.Server.Port)
+	}
+	switch c.Server.Mode {
+	case "", "debug", "release", "test":
+	default:
+		fail("server.mode", "只能是 debug、release 或 test，当前为 %q", c.Server.Mode)
+	}
+	if c.Server.WriteTimeout > 0 && c.Server.WriteTimeout <= c.Dynamic.QueryTimeout {
+		fail("server.write_timeout", "必须大于 dynamic.query_timeout (%s)，否则超时前响应已无法写出", c.Dynamic.QueryTimeout)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("server.shutdown_timeout", "必须大于 0")
+	}
+	if c.Server.ReadinessTimeout <= 0 {
+		fail("server.readiness_timeout", "必须大于 0")
+	}
+
+	errs = append(errs, c.Database.validate("database")...)
+	if c.Database.Timezone != "" && c.Database.Timezone != "Local" {
+		if _, err := time.LoadLocation(c.Database.Timezone); err != nil {
+			fail("database.timezone", "无效的时区 %q", c.Database.Timezone)
+		}
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		fail("database.max_idle_conns", "不能大于 max_open_conns (%d)", c.Database.MaxOpenConns)
+	}
+	if c.Database.Driver == DriverSQLite && len(c.Database.Replicas) > 0 {
+		fail("database.replicas", "sqlite 不支持只读副本")
+	}
+	for i, r := range c.Database.Replicas {
+		errs = append(errs, r.validate(fmt.Sprintf("database.replicas[%d]", i))...)
+	}
+	if len(c.Database.Replicas) > 0 && c.Database.ReplicaHealthInterval <= 0 {
+		fail("database.replica_health_interval", "必须大于 0")
+	}
+	if c.Database.Retry.InitialInterval <= 0 {
+		fail("database.retry.initial_interval", "必须大于 0")
+	}
+
+	for name, ds := range c.Datasources {
+		errs = append(errs, ds.validateDatasource(name)...)
+	}
+
+	errs = append(errs, c.CORS.validate()...)
+
+	if c.Dynamic.MaxRows <= 0 {
+		fail("dynamic.max_rows", "必须大于 0")
+	}
+	if c.Dynamic.QueryTimeout <= 0 {
+		fail("dynamic.query_timeout", "必须大于 0")
+	}
+	if c.Dynamic.CommandMaxAffectedRows <= 0 {
+		fail("dynamic.command_max_affected_rows", "必须大于 0")
+	}
+	if c.Dynamic.ServicesDir != "" {
+		if fi, err := os.Stat(c.Dynamic.ServicesDir); err != nil || !fi.IsDir() {
+			fail("dynamic.services_dir", "%s 不是可访问的目录", c.Dynamic.ServicesDir)
+		}
+		if c.Dynamic.ServicesSyncInterval <= 0 {
+			fail("dynamic.services_sync_interval", "必须大于 0")
+		}
+	}
+
+	if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+		fail("logging.level", "%v", err)
+	}
+	switch c.Logging.Format {
+	case "", "json", "text":
+	default:
+		fail("logging.format", "只能是 json 或 text，当前为 %q", c.Logging.Format)
+	}
+
+	if c.Audit.QueueSize <= 0 {
+		fail("audit.queue_size", "必须大于 0")
+	}
+	if c.Audit.BatchSize <= 0 {
+		fail("audit.batch_size", "必须大于 0")
+	}
+	switch c.Audit.FullPolicy {
+	case audit.PolicyBlock, audit.PolicyDrop:
+	default:
+		fail("audit.queue_full_policy", "只能是 %s 或 %s，当前为 %q", audit.PolicyBlock, audit.PolicyDrop, c.Audit.FullPolicy)
+	}
+	if c.Audit.RetentionDays < 0 {
+		fail("audit.retention_days", "不能为负数")
+	}
+
+	switch c.Tracing.Exporter {
+	case "", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	case tracing.ExporterFile:
+		if c.Tracing.FilePath == "" {
+			fail("tracing.file_path", "使用 file 导出器时不能为空 (TRACING_FILE)")
+		}
+	default:
+		fail("tracing.exporter", "只能是 none、otlp、stdout 或 file，当前为 %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "必须在 0-1 之间")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("配置校验失败:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// 实现 DBConfig 接口
+func (c *Config) GetDBDriver() string                      { return c.Database.Driver }
+func (c *Config) GetDBUser() string                        { return c.Database.User }
+func (c *Config) GetDBPass() string                        { return c.Database.Password }
+func (c *Config) GetDBHost() string                        { return c.Database.Host }
+func (c *Config) GetDBPort() string                        { return c.Database.Port }
+func (c *Config) GetDBName() string                        { return c.Database.Name }
+func (c *Config) GetSlowQueryThreshold() time.Duration     { return c.Logging.SlowQueryThreshold }
+func (c *Config) GetLogSQLParams() bool                    { return c.Logging.SQLParams }
+func (c *Config) GetDBMaxOpenConns() int                   { return c.Database.MaxOpenConns }
+func (c *Config) GetDBMaxIdleConns() int                   { return c.Database.MaxIdleConns }
+func (c *Config) GetDBConnMaxLifetime() time.Duration      { return c.Database.ConnMaxLifetime }
+func (c *Config) GetDBConnMaxIdleTime() time.Duration      { return c.Database.ConnMaxIdleTime }
+func (c *Config) GetDBTLS() string                         { return c.Database.TLS }
+func (c *Config) GetDBTLSCAFile() string                   { return c.Database.TLSCAFile }
+func (c *Config) GetDBTimezone() string                    { return c.Database.Timezone }
+func (c *Config) GetDBDialTimeout() time.Duration          { return c.Database.DialTimeout }
+func (c *Config) GetDBReadTimeout() time.Duration          { return c.Database.ReadTimeout }
+func (c *Config) GetDBWriteTimeout() time.Duration         { return c.Database.WriteTimeout }
+func (c *Config) GetDBRetryInitialInterval() time.Duration { return c.Database.Retry.InitialInterval }
+func (c *Config) GetDBRetryMaxInterval() time.Duration     { return c.Database.Retry.MaxInterval }
+func (c *Config) GetDBRetryMaxWait() time.Duration         { return c.Database.Retry.MaxWait }
+func (c *Config) GetDBMigrateOnStartup() bool              { return c.Database.MigrateOnStartup }
+func (c *Config) GetDBAutoMigrate() bool                   { return c.Database.AutoMigrate }
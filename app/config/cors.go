@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CORSConfig 跨域配置（可热加载）。
+// 内联的默认策略用于根路径、健康检查与用户接口；管理接口 (/api/v1/admin、动态服务注册)
+// 与动态服务执行接口 (/api/v1/dynamic/run) 可分别配置独立策略，未配置时沿用默认策略。
+type CORSConfig struct {
+	CORSPolicy `yaml:",inline"`
+	Management *CORSPolicy `yaml:"management"`
+	Run        *CORSPolicy `yaml:"run"`
+}
+
+// CORSPolicy 是一组跨域规则。
+// AllowOrigins 支持 "*"（任意来源，不能与凭据同时使用）、完整来源 (https://app.example.com)
+// 以及通配子域名 (https://*.example.com，匹配任意层级子域名但不匹配 example.com 本身)。
+// 为空时拒绝所有跨域请求，仅允许同源访问。
+type CORSPolicy struct {
+	AllowOrigins     []string      `yaml:"allow_origins"`
+	AllowMethods     []string      `yaml:"allow_methods"`
+	AllowHeaders     []string      `yaml:"allow_headers"`
+	ExposeHeaders    []string      `yaml:"expose_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+// defaultCORS 返回默认跨域配置: 公共接口允许任意来源但不携带凭据，管理接口仅允许同源访问
+func defaultCORS() CORSConfig {
+	def := CORSPolicy{
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"},
+		ExposeHeaders: []string{"Content-Length", "X-Request-ID"},
+		MaxAge:        12 * time.Hour,
+	}
+	mgmt := def
+	mgmt.AllowOrigins = []string{}
+	return CORSConfig{CORSPolicy: def, Management: &mgmt}
+}
+
+// orDefault 返回 p 的副本；p 为 nil 时返回 def 的副本
+func (p *CORSPolicy) orDefault(def CORSPolicy) *CORSPolicy {
+	if p == nil {
+		cp := def
+		return &cp
+	}
+	cp := *p
+	return &cp
+}
+
+// inherit 为未配置的方法、请求头、暴露头与缓存时间填充默认策略的值。
+// 来源与凭据不继承，必须显式配置。
+func (p CORSPolicy) inherit(def CORSPolicy) CORSPolicy {
+	if len(p.AllowMethods) == 0 {
+		p.AllowMethods = def.AllowMethods
+	}
+	if len(p.AllowHeaders) == 0 {
+		p.AllowHeaders = def.AllowHeaders
+	}
+	if len(p.ExposeHeaders) == 0 {
+		p.ExposeHeaders = def.ExposeHeaders
+	}
+	if p.MaxAge == 0 {
+		p.MaxAge = def.MaxAge
+	}
+	return p
+}
+
+// ManagementPolicy 返回管理接口生效的跨域策略
+func (c CORSConfig) ManagementPolicy() CORSPolicy {
+	if c.Management == nil {
+		return c.CORSPolicy
+	}
+	return c.Management.inherit(c.CORSPolicy)
+}
+
+// RunPolicy 返回动态服务执行接口生效的跨域策略（服务级 allowed_origins 在此基础上追加）
+func (c CORSConfig) RunPolicy() CORSPolicy {
+	if c.Run == nil {
+		return c.CORSPolicy
+	}
+	return c.Run.inherit(c.CORSPolicy)
+}
+
+// AllowsAny 返回策略是否允许任意来源
+func (p CORSPolicy) AllowsAny() bool {
+	for _, o := range p.AllowOrigins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows 返回策略是否允许 origin
+func (p CORSPolicy) Allows(origin string) bool {
+	for _, pattern := range p.AllowOrigins {
+		if OriginMatches(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// OriginMatches 判断 origin 是否匹配来源规则 pattern（"*"、完整来源或通配子域名），比较时忽略大小写
+func OriginMatches(pattern, origin string) bool {
+	pattern, origin = strings.ToLower(pattern), strings.ToLower(origin)
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+	scheme, rest, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+	// rest 为 "example.com" 或 "example.com:8443"
+	prefix := scheme + "://"
+	if !strings.HasPrefix(origin, prefix) {
+		return false
+	}
+	host := strings.TrimPrefix(origin, prefix)
+	return strings.HasSuffix(host, "."+rest) && len(host) > len(rest)+1 && !strings.ContainsAny(host, "/?#@")
+}
+
+// ValidateOrigin 校验来源规则格式: "*"、scheme://host[:port] 或 scheme://*.domain[:port]
+func ValidateOrigin(pattern string) error {
+	if pattern == "*" {
+		return nil
+	}
+	check := pattern
+	if scheme, rest, ok := strings.Cut(pattern, "://*."); ok {
+		if strings.Contains(rest, "*") || !strings.Contains(rest, ".") {
+			return fmt.Errorf("无效的通配来源 %q，应形如 https://*.example.com", pattern)
+		}
+		check = scheme + "://" + rest
+	} else if strings.Contains(pattern, "*") {
+		return fmt.Errorf("无效的来源 %q: 通配符只能用于子域名，如 https://*.example.com", pattern)
+	}
+	u, err := url.Parse(check)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("无效的来源 %q，应形如 https://example.com", pattern)
+	}
+	return nil
+}
+
+// validate 校验全部跨域策略
+func (c CORSConfig) validate() []error {
+	errs := c.CORSPolicy.validate("cors")
+	if c.Management != nil {
+		errs = append(errs, c.Management.validate("cors.management")...)
+	}
+	if c.Run != nil {
+		errs = append(errs, c.Run.validate("cors.run")...)
+	}
+	return errs
+}
+
+func (p CORSPolicy) validate(field string) []error {
+	var errs []error
+	for _, origin := range p.AllowOrigins {
+		if err := ValidateOrigin(origin); err != nil {
+			errs = append(errs, fmt.Errorf("%s.allow_origins: %v", field, err))
+		}
+	}
+	if p.AllowsAny() {
+		if len(p.AllowOrigins) > 1 {
+			errs = append(errs, fmt.Errorf("%s.allow_origins: \"*\" 不能与其他来源同时配置", field))
+		}
+		if p.AllowCredentials {
+			errs = append(errs, fmt.Errorf("%s.allow_credentials: 允许任意来源 (\"*\") 时不能携带凭据，浏览器会拒绝此类响应，请改为列出具体来源", field))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOriginMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{"任意来源", "*", "https://anything.test", true},
+		{"完整来源", "https://app.example.com", "https://app.example.com", true},
+		{"完整来源忽略大小写", "https://App.Example.com", "HTTPS://app.example.COM", true},
+		{"完整来源不匹配子域名", "https://example.com", "https://app.example.com", false},
+		{"通配一级子域名", "https://*.example.com", "https://app.example.com", true},
+		{"通配多级子域名", "https://*.example.com", "https://a.b.example.com", true},
+		{"通配不匹配裸域名", "https://*.example.com", "https://example.com", false},
+		{"通配不匹配空子域名", "https://*.example.com", "https://.example.com", false},
+		{"后缀相同的其他域名", "https://*.example.com", "https://evilexample.com", false},
+		{"以规则域名开头的其他域名", "https://*.example.com", "https://example.com.evil.com", false},
+		{"子域名以规则域名开头的其他域名", "https://*.example.com", "https://app.example.com.evil.com", false},
+		{"协议不同", "https://*.example.com", "http://app.example.com", false},
+		{"完整来源协议不同", "https://app.example.com", "http://app.example.com", false},
+		{"来源带端口", "https://*.example.com", "https://app.example.com:8443", false},
+		{"规则带端口", "https://*.example.com:8443", "https://app.example.com:8443", true},
+		{"端口不同", "https://*.example.com:8443", "https://app.example.com:9443", false},
+		{"规则带端口来源不带", "https://*.example.com:8443", "https://app.example.com", false},
+		{"完整来源端口不同", "https://app.example.com", "https://app.example.com:8443", false},
+		{"来源包含用户信息", "https://*.example.com", "https://evil.com@app.example.com", false},
+		{"来源包含路径", "https://*.example.com", "https://evil.com/.example.com", false},
+		{"空来源", "https://*.example.com", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OriginMatches(tt.pattern, tt.origin); got != tt.want {
+				t.Errorf("OriginMatches(%q, %q) = %v, want %v", tt.pattern, tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOrigin(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{"*", false},
+		{"https://example.com", false},
+		{"http://localhost:3000", false},
+		{"https://*.example.com", false},
+		{"https://*.example.com:8443", false},
+
+		{"example.com", true},
+		{"ftp://example.com", true},
+		{"https://example.com/", true},
+		{"https://example.com/app", true},
+		{"https://user@example.com", true},
+		{"https://*", true},
+		{"https://*.com", true},
+		{"https://*.*.example.com", true},
+		{"https://app.*.example.com", true},
+		{"*.example.com", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateOrigin(tt.pattern); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateOrigin(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCORSPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  CORSPolicy
+		wantErr []string
+	}{
+		{"任意来源不携带凭据", CORSPolicy{AllowOrigins: []string{"*"}}, nil},
+		{"具体来源携带凭据", CORSPolicy{AllowOrigins: []string{"https://app.example.com", "https://*.example.com"}, AllowCredentials: true}, nil},
+		{"任意来源携带凭据", CORSPolicy{AllowOrigins: []string{"*"}, AllowCredentials: true}, []string{"cors.allow_credentials"}},
+		{"任意来源与其他来源同时配置", CORSPolicy{AllowOrigins: []string{"*", "https://app.example.com"}}, []string{"cors.allow_origins"}},
+		{"无效来源", CORSPolicy{AllowOrigins: []string{"https://example.com/app"}}, []string{"cors.allow_origins"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.policy.validate("cors")
+			if len(errs) != len(tt.wantErr) {
+				t.Fatalf("validate() = %v, want %d errors", errs, len(tt.wantErr))
+			}
+			for i, want := range tt.wantErr {
+				if !strings.HasPrefix(errs[i].Error(), want) {
+					t.Errorf("validate() error = %v, want prefix %q", errs[i], want)
+				}
+			}
+		})
+	}
+
+	// 独立策略同样校验，错误带有对应的字段名
+	cfg := defaultCORS()
+	cfg.Run = &CORSPolicy{AllowOrigins: []string{"*"}, AllowCredentials: true}
+	if errs := cfg.validate(); len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "cors.run.allow_credentials") {
+		t.Errorf("CORSConfig.validate() = %v, want cors.run.allow_credentials error", errs)
+	}
+}
+
+func TestCORSPolicyAllows(t *testing.T) {
+	cfg := defaultCORS()
+	cfg.Run = &CORSPolicy{AllowOrigins: []string{"https://*.example.com"}, AllowCredentials: true}
+
+	if !cfg.CORSPolicy.Allows("https://any.test") {
+		t.Error("默认策略应允许任意来源")
+	}
+	if mgmt := cfg.ManagementPolicy(); mgmt.Allows("https://any.test") || len(mgmt.AllowMethods) == 0 {
+		t.Errorf("管理接口策略 = %+v, want 拒绝跨域且继承方法列表", mgmt)
+	}
+	run := cfg.RunPolicy()
+	if !run.Allows("https://app.example.com") || run.Allows("https://example.com") || run.Allows("https://evilexample.com") {
+		t.Errorf("执行接口策略 %v 的匹配结果不符合预期", run.AllowOrigins)
+	}
+	if !run.AllowCredentials || run.MaxAge != cfg.MaxAge {
+		t.Errorf("执行接口策略 = %+v, want 保留凭据设置并继承 max_age", run)
+	}
+}
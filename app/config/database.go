@@ -1,60 +1,348 @@
 package config
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
 	
-	"gorm.io/driver/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 
+	"go-gin-gorm-api/app/logging"
+	"go-gin-gorm-api/app/migrate"
 	"go-gin-gorm-api/app/models"
 )
 
 // DB 存储 GORM 数据库连接实例
 var DB *gorm.DB
 
+// migrated 标记数据库连接与模型迁移是否均已完成，供就绪检查使用
+var migrated atomic.Bool
+
 // 【新增】DBConfig 接口定义了数据库连接所需的配置参数。
 // 通过接口隔离，避免 config 包直接依赖 main 包的 Config 结构。
 type DBConfig interface {
+	// 【新增】数据库驱动: mysql | postgres | sqlite，为空表示 mysql；SQLite 的 GetDBName 为数据库文件路径
+	GetDBDriver() string
 	GetDBUser() string
 	GetDBPass() string
 	GetDBHost() string
 	GetDBPort() string
 	GetDBName() string
+
+	// 【新增】GORM 日志：慢查询阈值 (0 表示不记录) 与是否在 SQL 日志中输出参数值
+	GetSlowQueryThreshold() time.Duration
+	GetLogSQLParams() bool
+
+	// 【新增】连接池: 最大打开连接数、最大空闲连接数 (0 使用 database/sql 默认值)、连接最长存活与空闲时间 (0 表示不限制)
+	GetDBMaxOpenConns() int
+	GetDBMaxIdleConns() int
+	GetDBConnMaxLifetime() time.Duration
+	GetDBConnMaxIdleTime() time.Duration
+
+	// 【新增】DSN 选项: TLS 模式 (false|true|skip-verify|preferred)、自定义 CA 证书文件、
+	// 时区 (IANA 名称，如 Asia/Shanghai，默认 Local)、建连/读/写超时 (0 表示不限制)
+	GetDBTLS() string
+	GetDBTLSCAFile() string
+	GetDBTimezone() string
+	GetDBDialTimeout() time.Duration
+	GetDBReadTimeout() time.Duration
+	GetDBWriteTimeout() time.Duration
+
+	// 【新增】连接重试: 首次重试间隔、最大重试间隔 (指数退避上限) 与总等待时间上限 (0 表示一直重试)
+	GetDBRetryInitialInterval() time.Duration
+	GetDBRetryMaxInterval() time.Duration
+	GetDBRetryMaxWait() time.Duration
+
+	// 【新增】表结构迁移: 启动时是否执行版本化迁移，以及是否改用 AutoMigrate (开发模式)
+	GetDBMigrateOnStartup() bool
+	GetDBAutoMigrate() bool
 }
 
-// 【修改】InitDatabase 初始化数据库连接并自动迁移模型
+// customTLSConfigName 是使用自定义 CA 证书时向 MySQL 驱动注册的 TLS 配置名称前缀
+const customTLSConfigName = "custom"
+
+// 【修改】InitDatabase 初始化数据库连接并自动迁移模型。
+// 数据库尚未启动时按指数退避重试，超过 GetDBRetryMaxWait 仍失败则退出进程。
 func InitDatabase(cfg DBConfig) { // 接收 DBConfig 接口
-	dbUser := cfg.GetDBUser()
-	dbPass := cfg.GetDBPass()
+	if err := ConnectWithRetry(context.Background(), cfg, cfg.GetDBRetryMaxWait()); err != nil {
+		slog.Error("数据库初始化失败", "error", err)
+		os.Exit(1)
+	}
+}
+
+// ConnectWithRetry 调用 ConnectDatabase，失败时按指数退避 (含随机抖动) 重试，
+// 直到成功、ctx 被取消或累计等待超过 maxWait (maxWait <= 0 表示不限制)。
+func ConnectWithRetry(ctx context.Context, cfg DBConfig, maxWait time.Duration) error {
+	interval := cfg.GetDBRetryInitialInterval()
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	maxInterval := cfg.GetDBRetryMaxInterval()
+	if maxInterval < interval {
+		maxInterval = interval
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := ConnectDatabase(cfg)
+		if err == nil {
+			return nil
+		}
+		if !isRetryable(err) {
+			return err
+		}
+
+		// 抖动范围 [interval/2, interval]，避免多个实例同时重连
+		wait := interval/2 + time.Duration(rand.Int64N(int64(interval/2)+1))
+		if maxWait > 0 {
+			remaining := maxWait - time.Since(start)
+			if remaining <= 0 {
+				return fmt.Errorf("等待数据库超过 %s，共尝试 %d 次: %w", maxWait, attempt, err)
+			}
+			if wait > remaining {
+				wait = remaining
+			}
+		}
+		slog.Warn("数据库尚未就绪，稍后重试", "attempt", attempt, "retry_in", wait.String(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+// isRetryable 判断连接错误是否值得重试: 配置错误 (如 DSN/TLS 证书无效) 重试也不会成功
+func isRetryable(err error) bool {
+	var cfgErr *configError
+	return !errors.As(err, &cfgErr)
+}
+
+// configError 表示数据库连接配置本身有误
+type configError struct{ err error }
+
+func (e *configError) Error() string { return "数据库配置错误: " + e.err.Error() }
+func (e *configError) Unwrap() error { return e.err }
+
+// buildDSN 根据配置生成 MySQL DSN，使用驱动的 Config 生成以正确转义用户名、密码等特殊字符
+func buildDSN(cfg DBConfig) (string, error) {
+	dc := mysqldriver.NewConfig()
+	dc.User = cfg.GetDBUser()
+	dc.Passwd = cfg.GetDBPass()
+	dc.Net = "tcp"
+	dc.Addr = net.JoinHostPort(cfg.GetDBHost(), cfg.GetDBPort())
+	dc.DBName = cfg.GetDBName()
+	dc.Params = map[string]string{"charset": "utf8mb4"}
+	dc.ParseTime = true
+	dc.Timeout = cfg.GetDBDialTimeout()
+	dc.ReadTimeout = cfg.GetDBReadTimeout()
+	dc.WriteTimeout = cfg.GetDBWriteTimeout()
+
+	dc.Loc = time.Local
+	if tz := cfg.GetDBTimezone(); tz != "" && tz != "Local" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return "", fmt.Errorf("无效的数据库时区 %q: %w", tz, err)
+		}
+		dc.Loc = loc
+	}
+
+	switch mode := cfg.GetDBTLS(); {
+	case cfg.GetDBTLSCAFile() != "":
+		pem, err := os.ReadFile(cfg.GetDBTLSCAFile())
+		if err != nil {
+			return "", fmt.Errorf("读取数据库 CA 证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("数据库 CA 证书 %s 中没有有效的 PEM 证书", cfg.GetDBTLSCAFile())
+		}
+		tlsCfg := &tls.Config{RootCAs: pool, ServerName: cfg.GetDBHost(), MinVersion: tls.VersionTLS12}
+		if mode == "skip-verify" {
+			tlsCfg.InsecureSkipVerify = true
+		}
+		// 每个数据库地址注册独立的 TLS 配置，避免多个数据源使用不同 CA 时互相覆盖
+		name := customTLSConfigName + "-" + dc.Addr
+		if err := mysqldriver.RegisterTLSConfig(name, tlsCfg); err != nil {
+			return "", err
+		}
+		dc.TLSConfig = name
+	case mode == "", mode == "false":
+	case mode == "true", mode == "skip-verify", mode == "preferred":
+		dc.TLSConfig = mode
+	default:
+		return "", fmt.Errorf("无效的 DB_TLS 取值 %q (可选 false|true|skip-verify|preferred)", mode)
+	}
+
+	return dc.FormatDSN(), nil
+}
+
+// configurePool 设置 database/sql 连接池参数
+func configurePool(db *gorm.DB, cfg DBConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if n := cfg.GetDBMaxOpenConns(); n > 0 {
+		sqlDB.SetMaxOpenConns(n)
+	}
+	if n := cfg.GetDBMaxIdleConns(); n > 0 {
+		sqlDB.SetMaxIdleConns(n)
+	}
+	if d := cfg.GetDBConnMaxLifetime(); d > 0 {
+		sqlDB.SetConnMaxLifetime(d)
+	}
+	if d := cfg.GetDBConnMaxIdleTime(); d > 0 {
+		sqlDB.SetConnMaxIdleTime(d)
+	}
+	return nil
+}
+
+// openDB 根据配置选择驱动建立连接并设置连接池，不执行迁移
+func openDB(cfg DBConfig) (*gorm.DB, error) {
+	dial, err := dialector(cfg)
+	if err != nil {
+		return nil, &configError{err}
+	}
+
+	db, err := gorm.Open(dial, &gorm.Config{
+		// 结构化 SQL 日志：普通 SQL 为 Debug 级别，慢查询为 Warn，错误为 Error
+		Logger: logging.NewGormLogger(cfg.GetSlowQueryThreshold(), cfg.GetLogSQLParams()),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("连接数据库失败 (%s:%s/%s): %w", cfg.GetDBHost(), cfg.GetDBPort(), cfg.GetDBName(), err)
+	}
+
+	if err := configurePool(db, cfg); err != nil {
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		return nil, fmt.Errorf("配置数据库连接池失败: %w", err)
+	}
+	// SQLite 内存数据库随连接关闭而销毁，只能使用单个长期连接
+	if normalizeDriver(cfg.GetDBDriver()) == DriverSQLite && cfg.GetDBName() == sqliteMemory {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.SetMaxOpenConns(1)
+			sqlDB.SetMaxIdleConns(1)
+			sqlDB.SetConnMaxLifetime(0)
+			sqlDB.SetConnMaxIdleTime(0)
+		}
+	}
+	return db, nil
+}
+
+// OpenDatabase 按配置建立数据库连接但不执行迁移，供 migrate 等命令行子命令使用
+func OpenDatabase(cfg DBConfig) (*gorm.DB, error) {
+	return openDB(cfg)
+}
+
+// 【修改】ConnectDatabase 连接数据库并迁移表结构，失败时返回错误而不是退出进程，
+// 便于调用方在数据库尚未启动或其他实例正在迁移时重试。成功后才会设置全局 DB。
+func ConnectDatabase(cfg DBConfig) error {
 	dbHost := cfg.GetDBHost()
 	dbPort := cfg.GetDBPort()
 	dbName := cfg.GetDBName()
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		dbUser, dbPass, dbHost, dbPort, dbName)
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	slog.Info("数据库连接成功", "driver", normalizeDriver(cfg.GetDBDriver()), "host", dbHost, "port", dbPort, "database", dbName)
 
-	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		// 开启日志记录，方便调试和问题追踪
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+	if err := migrateSchema(context.Background(), db, cfg); err != nil {
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		return fmt.Errorf("数据库迁移失败: %w", err)
+	}
+
+	DB = db
+	migrated.Store(true)
+	return nil
+}
+
+// migrateSchema 按配置迁移表结构: 开发模式下使用 AutoMigrate，否则执行 (或仅检查) 版本化迁移
+func migrateSchema(ctx context.Context, db *gorm.DB, cfg DBConfig) error {
+	if cfg.GetDBAutoMigrate() {
+		slog.Warn("开发模式: 使用 AutoMigrate 同步表结构，不记录迁移版本，请勿在生产环境使用")
+		return db.AutoMigrate(
+			&models.User{},
+			&models.APIService{},
+			&models.Audit{},
+			&models.APIKey{},
+		)
+	}
 
+	if !cfg.GetDBMigrateOnStartup() {
+		pending, err := migrate.Pending(ctx, db)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("存在 %d 个未执行的迁移 (最新版本 %d)，请执行 app migrate up", len(pending), migrate.Latest())
+		}
+		slog.Info("数据库表结构已是最新版本", "version", migrate.Latest())
+		return nil
+	}
+
+	applied, err := migrate.Up(ctx, db)
 	if err != nil {
-		log.Fatalf("连接数据库失败: %v", err)
+		return err
 	}
+	for _, m := range applied {
+		slog.Info("已执行数据库迁移", "version", m.Version, "name", m.Name)
+	}
+	slog.Info("数据库迁移完成", "version", migrate.Latest(), "applied", len(applied))
+	return nil
+}
 
-	log.Println("数据库连接成功！")
+// UseDatabase 将已确认迁移完成的连接设为全局 DB，供命令行子命令使用（之后 Datasource("") 可用）
+func UseDatabase(db *gorm.DB) {
+	DB = db
+	migrated.Store(true)
+}
+
+// MigrationsCompleted 返回数据库连接与迁移是否已完成
+func MigrationsCompleted() bool {
+	return migrated.Load()
+}
 
-	// 自动迁移所有模型
-	err = DB.AutoMigrate(
-		&models.User{},
-		&models.APIService{},
-		&models.Audit{},
-	)
+// CloseDatabase 关闭数据库连接池，应在所有请求处理完成、审计记录刷新之后调用
+func CloseDatabase() error {
+	if !migrated.Load() {
+		return nil
+	}
+	migrated.Store(false)
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// PingDatabase 检查数据库连接是否可用，供就绪检查使用
+func PingDatabase(ctx context.Context) error {
+	if !migrated.Load() {
+		return errors.New("数据库尚未连接")
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatalf("数据库迁移失败: %v", err)
+		return err
 	}
-	log.Println("数据库迁移完成。")
+	return sqlDB.PingContext(ctx)
 }
@@ -0,0 +1,304 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultDatasource 是主库 (config.DB) 的数据源名称。APIService.Datasource 为空时同样使用主库。
+const DefaultDatasource = "default"
+
+// datasourceNamePattern 限制数据源名称，保证可以映射为环境变量名
+var datasourceNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,63}$`)
+
+// ErrUnknownDatasource 表示服务引用的数据源未在配置中定义
+var ErrUnknownDatasource = errors.New("未知的数据源")
+
+// ErrDatasourceUnavailable 表示数据源已配置但尚未连接成功
+var ErrDatasourceUnavailable = errors.New("数据源暂不可用")
+
+var (
+	dsMu sync.RWMutex
+	// datasources 保存已连接的附加数据源，键为数据源名称
+	datasources = map[string]*gorm.DB{}
+)
+
+// inherit 为附加数据源填充未配置的驱动、端口、连接池、超时与时区参数（沿用主库配置）。
+// 端口仅在驱动与主库相同时沿用，否则使用该驱动的默认端口。
+func (d DatabaseConfig) inherit(primary DatabaseConfig) DatabaseConfig {
+	if d.Driver == "" {
+		d.Driver = primary.Driver
+	}
+	if d.Port == "" && normalizeDriver(d.Driver) == normalizeDriver(primary.Driver) {
+		d.Port = primary.Port
+	}
+	if d.MaxOpenConns == 0 {
+		d.MaxOpenConns = primary.MaxOpenConns
+	}
+	if d.MaxIdleConns == 0 {
+		d.MaxIdleConns = primary.MaxIdleConns
+	}
+	if d.ConnMaxLifetime == 0 {
+		d.ConnMaxLifetime = primary.ConnMaxLifetime
+	}
+	if d.ConnMaxIdleTime == 0 {
+		d.ConnMaxIdleTime = primary.ConnMaxIdleTime
+	}
+	if d.DialTimeout == 0 {
+		d.DialTimeout = primary.DialTimeout
+	}
+	if d.ReadTimeout == 0 {
+		d.ReadTimeout = primary.ReadTimeout
+	}
+	if d.WriteTimeout == 0 {
+		d.WriteTimeout = primary.WriteTimeout
+	}
+	if d.Timezone == "" {
+		d.Timezone = primary.Timezone
+	}
+	if d.Retry == (RetryConfig{}) {
+		d.Retry = primary.Retry
+	}
+	return d
+}
+
+// validateDatasource 校验附加数据源配置
+func (d DatabaseConfig) validateDatasource(name string) []error {
+	field := "datasources." + name
+	errs := d.validate(field)
+	if name == DefaultDatasource || !datasourceNamePattern.MatchString(name) {
+		errs = append(errs, fmt.Errorf("%s: 数据源名称只能包含小写字母、数字、- 和 _，以字母开头，且不能为 %q", field, DefaultDatasource))
+	}
+	if len(d.Replicas) > 0 {
+		errs = append(errs, fmt.Errorf("%s.replicas: 只有主库 (database) 支持只读副本", field))
+	}
+	return errs
+}
+
+// validate 按驱动校验连接参数，field 为错误信息中的字段路径。
+// SQLite 只需要数据库文件路径 (name)，不校验主机、端口与 TLS。
+func (d DatabaseConfig) validate(field string) []error {
+	var errs []error
+	if !validDriver(d.Driver) {
+		return append(errs, fmt.Errorf("%s.driver: 只能是 mysql、postgres 或 sqlite，当前为 %q", field, d.Driver))
+	}
+	if d.Name == "" {
+		errs = append(errs, fmt.Errorf("%s.name: 不能为空", field))
+	}
+	if d.Driver == DriverSQLite {
+		return errs
+	}
+	if d.Host == "" {
+		errs = append(errs, fmt.Errorf("%s.host: 不能为空", field))
+	}
+	if n, err := strconv.Atoi(d.Port); err != nil || n < 1 || n > 65535 {
+		errs = append(errs, fmt.Errorf("%s.port: 不是有效的端口号: %q", field, d.Port))
+	}
+	switch d.TLS {
+	case "", "false", "true", "skip-verify", "preferred":
+	default:
+		errs = append(errs, fmt.Errorf("%s.tls: 只能是 false、true、skip-verify 或 preferred，当前为 %q", field, d.TLS))
+	}
+	return errs
+}
+
+// datasourceConfig 返回以附加数据源替换主库参数后的配置，用于复用 DSN 构建与连接池设置
+func (c *Config) datasourceConfig(name string) *Config {
+	cp := *c
+	cp.Database = c.Datasources[name]
+	return &cp
+}
+
+// DatasourceDriver 返回数据源使用的数据库驱动，供动态服务选择 SQL 方言（未知数据源返回主库驱动）
+func DatasourceDriver(name string) string {
+	cfg := Current()
+	if ds, ok := cfg.Datasources[name]; ok && name != "" {
+		return normalizeDriver(ds.Driver)
+	}
+	return normalizeDriver(cfg.Database.Driver)
+}
+
+// HasDatasource 返回数据源是否已在配置中定义（"" 与 "default" 表示主库）
+func HasDatasource(name string) bool {
+	if name == "" || name == DefaultDatasource {
+		return true
+	}
+	_, ok := Current().Datasources[name]
+	return ok
+}
+
+// DatasourceNames 返回全部数据源名称（含主库），按名称排序
+func DatasourceNames() []string {
+	names := []string{DefaultDatasource}
+	for name := range Current().Datasources {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// Datasource 返回指定名称的数据源连接。"" 与 "default" 返回主库。
+func Datasource(name string) (*gorm.DB, error) {
+	if name == "" || name == DefaultDatasource {
+		if !MigrationsCompleted() {
+			return nil, fmt.Errorf("%w: %s", ErrDatasourceUnavailable, DefaultDatasource)
+		}
+		return DB, nil
+	}
+	if !HasDatasource(name) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDatasource, name)
+	}
+	dsMu.RLock()
+	db := datasources[name]
+	dsMu.RUnlock()
+	if db == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDatasourceUnavailable, name)
+	}
+	return db, nil
+}
+
+// ReadDatasource 返回执行只读查询使用的连接: 主库配置了只读副本时按 ReadDB 路由，
+// fresh 为 true 时强制使用主库；附加数据源与 Datasource 相同。
+func ReadDatasource(name string, fresh bool) (*gorm.DB, error) {
+	db, err := Datasource(name)
+	if err != nil || (name != "" && name != DefaultDatasource) {
+		return db, err
+	}
+	return ReadDB(fresh), nil
+}
+
+// OpenDatasources 连接配置中的全部附加数据源。附加数据源不可用时不影响应用启动：
+// 连接失败的数据源在后台按指数退避重试，直到成功或 ctx 取消。每个数据源连接成功后调用 onOpen。
+func OpenDatasources(ctx context.Context, cfg *Config, onOpen func(name string, db *gorm.DB)) {
+	for name := range cfg.Datasources {
+		name := name
+		dsCfg := cfg.datasourceConfig(name)
+		go func() {
+			interval := dsCfg.Database.Retry.InitialInterval
+			if interval <= 0 {
+				interval = 500 * time.Millisecond
+			}
+			for attempt := 1; ; attempt++ {
+				db, err := openDB(dsCfg)
+				if err == nil {
+					dsMu.Lock()
+					datasources[name] = db
+					dsMu.Unlock()
+					slog.Info("数据源连接成功", "datasource", name, "host", dsCfg.Database.Host, "database", dsCfg.Database.Name)
+					if onOpen != nil {
+						onOpen(name, db)
+					}
+					return
+				}
+				var cfgErr *configError
+				if errors.As(err, &cfgErr) {
+					slog.Error("数据源配置错误", "datasource", name, "error", err)
+					return
+				}
+				slog.Warn("数据源连接失败，稍后重试", "datasource", name, "attempt", attempt, "retry_in", interval.String(), "error", err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(interval):
+				}
+				if interval *= 2; dsCfg.Database.Retry.MaxInterval > 0 && interval > dsCfg.Database.Retry.MaxInterval {
+					interval = dsCfg.Database.Retry.MaxInterval
+				}
+			}
+		}()
+	}
+}
+
+// PingDatasource 检查数据源连通性，供健康检查使用
+func PingDatasource(ctx context.Context, name string) error {
+	if name == "" || name == DefaultDatasource {
+		return PingDatabase(ctx)
+	}
+	db, err := Datasource(name)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CloseDatasources 关闭全部附加数据源连接
+func CloseDatasources() {
+	dsMu.Lock()
+	defer dsMu.Unlock()
+	for name, db := range datasources {
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				slog.Error("关闭数据源连接失败", "datasource", name, "error", err)
+			}
+		}
+		delete(datasources, name)
+	}
+}
+
+// DatasourceInfo 是数据源的公开信息，不包含用户名与密码
+type DatasourceInfo struct {
+	Name      string `json:"name"`
+	Driver    string `json:"driver"`
+	Host      string `json:"host"`
+	Port      string `json:"port"`
+	Database  string `json:"database"`
+	Connected bool   `json:"connected"`
+	Status    string `json:"status"` // ok | fail
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+
+	OpenConnections int `json:"open_connections"`
+	InUse           int `json:"in_use"`
+	Idle            int `json:"idle"`
+}
+
+// DescribeDatasources 返回全部数据源的连接状态与连接池统计，每个数据源的检查超时为 timeout
+func DescribeDatasources(ctx context.Context, timeout time.Duration) []DatasourceInfo {
+	cfg := Current()
+	names := DatasourceNames()
+	infos := make([]DatasourceInfo, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		dbCfg := cfg.Database
+		if name != DefaultDatasource {
+			dbCfg = cfg.Datasources[name]
+		}
+		infos[i] = DatasourceInfo{Name: name, Driver: normalizeDriver(dbCfg.Driver), Host: dbCfg.Host, Port: dbCfg.Port, Database: dbCfg.Name, Status: "ok"}
+
+		wg.Add(1)
+		go func(info *DatasourceInfo) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := PingDatasource(cctx, info.Name)
+			info.LatencyMs = time.Since(start).Milliseconds()
+			if err != nil {
+				info.Status = "fail"
+				info.Error = err.Error()
+			}
+			if db, err := Datasource(info.Name); err == nil {
+				info.Connected = true
+				if sqlDB, err := db.DB(); err == nil {
+					st := sqlDB.Stats()
+					info.OpenConnections, info.InUse, info.Idle = st.OpenConnections, st.InUse, st.Idle
+				}
+			}
+		}(&infos[i])
+	}
+	wg.Wait()
+	return infos
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// 支持的数据库驱动
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqliteMemory 是 SQLite 内存数据库的库名，所有连接共享同一个内存库
+const sqliteMemory = ":memory:"
+
+// normalizeDriver 返回规范化的驱动名称，空值表示 MySQL（与历史版本保持一致）
+func normalizeDriver(driver string) string {
+	switch d := strings.ToLower(strings.TrimSpace(driver)); d {
+	case "":
+		return DriverMySQL
+	case "postgresql", "pg":
+		return DriverPostgres
+	case "sqlite3":
+		return DriverSQLite
+	default:
+		return d
+	}
+}
+
+// validDriver 返回驱动名称是否受支持
+func validDriver(driver string) bool {
+	switch driver {
+	case DriverMySQL, DriverPostgres, DriverSQLite:
+		return true
+	}
+	return false
+}
+
+// defaultPort 返回驱动的默认端口，SQLite 没有端口
+func defaultPort(driver string) string {
+	switch driver {
+	case DriverPostgres:
+		return "5432"
+	case DriverSQLite:
+		return ""
+	default:
+		return "3306"
+	}
+}
+
+// applyDriverDefaults 规范化驱动名称并填充依赖驱动的默认端口
+func (d *DatabaseConfig) applyDriverDefaults() {
+	d.Driver = normalizeDriver(d.Driver)
+	if d.Port == "" {
+		d.Port = defaultPort(d.Driver)
+	}
+}
+
+// dialector 根据驱动生成 GORM Dialector 与 DSN
+func dialector(cfg DBConfig) (gorm.Dialector, error) {
+	switch driver := normalizeDriver(cfg.GetDBDriver()); driver {
+	case DriverMySQL:
+		dsn, err := buildDSN(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		dsn, err := buildPostgresDSN(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		dsn, err := buildSQLiteDSN(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("不支持的数据库驱动 %q (可选 mysql|postgres|sqlite)", driver)
+	}
+}
+
+// buildPostgresDSN 生成 PostgreSQL 连接 URL。TLS 模式映射为 sslmode:
+// false → disable，true → verify-full，skip-verify → require，preferred → prefer。
+// 驱动不支持读写超时，ReadTimeout/WriteTimeout 对 PostgreSQL 不生效。
+func buildPostgresDSN(cfg DBConfig) (string, error) {
+	u := &url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(cfg.GetDBUser(), cfg.GetDBPass()),
+		Host:   net.JoinHostPort(cfg.GetDBHost(), cfg.GetDBPort()),
+		Path:   "/" + cfg.GetDBName(),
+	}
+	q := url.Values{}
+
+	switch mode := cfg.GetDBTLS(); mode {
+	case "", "false":
+		q.Set("sslmode", "disable")
+	case "true":
+		q.Set("sslmode", "verify-full")
+	case "skip-verify":
+		q.Set("sslmode", "require")
+	case "preferred":
+		q.Set("sslmode", "prefer")
+	default:
+		return "", fmt.Errorf("无效的 DB_TLS 取值 %q (可选 false|true|skip-verify|preferred)", mode)
+	}
+	if ca := cfg.GetDBTLSCAFile(); ca != "" {
+		q.Set("sslrootcert", ca)
+	}
+
+	if tz := cfg.GetDBTimezone(); tz != "" && tz != "Local" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return "", fmt.Errorf("无效的数据库时区 %q: %w", tz, err)
+		}
+		q.Set("TimeZone", tz)
+	}
+	if d := cfg.GetDBDialTimeout(); d > 0 {
+		// connect_timeout 以秒为单位，不足 1 秒按 1 秒处理
+		secs := int(d / time.Second)
+		if secs < 1 {
+			secs = 1
+		}
+		q.Set("connect_timeout", strconv.Itoa(secs))
+	}
+
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// buildSQLiteDSN 生成 SQLite DSN，库名 (database.name) 为数据库文件路径，":memory:" 表示内存数据库。
+// 启用外键约束与忙等待，文件数据库使用 WAL 模式以允许读写并发。
+func buildSQLiteDSN(cfg DBConfig) (string, error) {
+	name := cfg.GetDBName()
+	if name == "" {
+		return "", fmt.Errorf("SQLite 数据库文件路径不能为空")
+	}
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	if name == sqliteMemory {
+		return "file::memory:?" + q.Encode(), nil
+	}
+	q.Add("_pragma", "journal_mode(WAL)")
+	return "file:" + name + "?" + q.Encode(), nil
+}
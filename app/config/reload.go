@@ -0,0 +1,107 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+// watchInterval 是轮询配置文件修改时间的间隔
+const watchInterval = 2 * time.Second
+
+// Reload 重新加载配置，仅应用可安全热更新的字段：动态服务限制 (dynamic，服务定义目录除外)、日志级别 (logging.level) 与 CORS。
+// 其他字段的变更会被忽略并输出警告，需要重启后生效。新配置校验失败时保留当前配置并返回错误。
+func Reload(path string) (*Config, error) {
+	loaded, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	old := Current()
+	next := *old
+	next.Dynamic = loaded.Dynamic
+	next.Dynamic.ServicesDir = old.Dynamic.ServicesDir
+	next.Dynamic.ServicesSyncInterval = old.Dynamic.ServicesSyncInterval
+	next.CORS = loaded.CORS
+	next.Logging.Level = loaded.Logging.Level
+
+	// 将可热更新字段对齐后比较，剩余差异即为需要重启的配置
+	cmp := *loaded
+	cmp.Dynamic, cmp.CORS, cmp.Logging.Level = old.Dynamic, old.CORS, old.Logging.Level
+	cmp.Dynamic.ServicesDir = loaded.Dynamic.ServicesDir
+	cmp.Dynamic.ServicesSyncInterval = loaded.Dynamic.ServicesSyncInterval
+	if ignored := changedSections(old, &cmp); len(ignored) > 0 {
+		slog.Warn("部分配置项不支持热加载，需重启后生效", "sections", ignored)
+	}
+
+	SetCurrent(&next)
+	return &next, nil
+}
+
+// changedSections 返回两份配置中存在差异的顶层配置段名称
+func changedSections(a, b *Config) []string {
+	var sections []string
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			sections = append(sections, t.Field(i).Tag.Get("yaml"))
+		}
+	}
+	return sections
+}
+
+// Watch 在收到 SIGHUP 或配置文件被修改时调用 Reload，并将生效的新配置传给 onReload。
+// path 为空时仅响应 SIGHUP（重新读取环境变量）。ctx 取消后停止监听。
+func Watch(ctx context.Context, path string, onReload func(*Config)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+
+		var ticker *time.Ticker
+		var tick <-chan time.Time
+		var lastMod time.Time
+		var lastSize int64
+		if path != "" {
+			if fi, err := os.Stat(path); err == nil {
+				lastMod, lastSize = fi.ModTime(), fi.Size()
+			}
+			ticker = time.NewTicker(watchInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			reason := ""
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reason = "SIGHUP"
+			case <-tick:
+				fi, err := os.Stat(path)
+				if err != nil || (fi.ModTime().Equal(lastMod) && fi.Size() == lastSize) {
+					continue
+				}
+				lastMod, lastSize = fi.ModTime(), fi.Size()
+				reason = "file_changed"
+			}
+
+			cfg, err := Reload(path)
+			if err != nil {
+				slog.Error("配置热加载失败，继续使用当前配置", "reason", reason, "error", err)
+				continue
+			}
+			slog.Info("配置已重新加载", "reason", reason)
+			if onReload != nil {
+				onReload(cfg)
+			}
+		}
+	}()
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// replica 是主库的一个只读副本及其健康状态
+type replica struct {
+	name    string // replica-<序号>，用于日志与指标
+	addr    string
+	cfg     *Config
+	db      atomic.Pointer[gorm.DB] // 连接成功前为 nil
+	healthy atomic.Bool
+	lastErr atomic.Value // string
+}
+
+var (
+	replicasMu sync.RWMutex
+	replicas   []*replica
+	// replicaNext 是轮询选择副本的计数器
+	replicaNext atomic.Uint64
+)
+
+// inheritReplica 为只读副本填充未配置的凭据、库名与连接参数（沿用主库配置），副本的驱动始终与主库相同
+func (d DatabaseConfig) inheritReplica(primary DatabaseConfig) DatabaseConfig {
+	d.Driver = primary.Driver
+	if d.User == "" {
+		d.User = primary.User
+	}
+	if d.Password == "" {
+		d.Password = primary.Password
+	}
+	if d.Name == "" {
+		d.Name = primary.Name
+	}
+	if d.TLS == "" {
+		d.TLS = primary.TLS
+		d.TLSCAFile = primary.TLSCAFile
+	}
+	return d.inherit(primary)
+}
+
+// StartReplicas 连接配置中的全部只读副本，并按 ReplicaHealthInterval 定期检查健康状态。
+// 连接失败或健康检查失败的副本不参与读请求路由，恢复后自动重新加入。
+// 每个副本首次连接成功后调用 onOpen。
+func StartReplicas(ctx context.Context, cfg *Config, onOpen func(name string, db *gorm.DB)) {
+	if len(cfg.Database.Replicas) == 0 {
+		return
+	}
+
+	list := make([]*replica, len(cfg.Database.Replicas))
+	for i, rc := range cfg.Database.Replicas {
+		cp := *cfg
+		cp.Database = rc
+		list[i] = &replica{
+			name: fmt.Sprintf("replica-%d", i),
+			addr: net.JoinHostPort(rc.Host, rc.Port),
+			cfg:  &cp,
+		}
+		list[i].lastErr.Store("尚未连接")
+	}
+	replicasMu.Lock()
+	replicas = list
+	replicasMu.Unlock()
+
+	interval := cfg.Database.ReplicaHealthInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for _, r := range list {
+		go r.run(ctx, interval, onOpen)
+	}
+}
+
+// run 负责副本的连接与周期性健康检查
+func (r *replica) run(ctx context.Context, interval time.Duration, onOpen func(string, *gorm.DB)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.check(ctx, interval, onOpen)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check 在副本尚未连接时尝试连接，已连接时执行 Ping 并更新健康状态
+func (r *replica) check(ctx context.Context, timeout time.Duration, onOpen func(string, *gorm.DB)) {
+	db := r.db.Load()
+	var err error
+	if db == nil {
+		db, err = openDB(r.cfg)
+		if err == nil {
+			r.db.Store(db)
+			slog.Info("只读副本连接成功", "replica", r.name, "addr", r.addr)
+			if onOpen != nil {
+				onOpen(r.name, db)
+			}
+		}
+	} else {
+		var sqlDB interface{ PingContext(context.Context) error }
+		if sqlDB, err = db.DB(); err == nil {
+			pctx, cancel := context.WithTimeout(ctx, timeout)
+			err = sqlDB.PingContext(pctx)
+			cancel()
+		}
+	}
+
+	if err != nil {
+		r.lastErr.Store(err.Error())
+		if r.healthy.Swap(false) {
+			slog.Warn("只读副本不可用，读请求回退到其他副本或主库", "replica", r.name, "addr", r.addr, "error", err)
+		}
+		return
+	}
+	r.lastErr.Store("")
+	if !r.healthy.Swap(true) {
+		slog.Info("只读副本已恢复", "replica", r.name, "addr", r.addr)
+	}
+}
+
+// ReadDB 返回用于只读查询的连接: 按轮询顺序选择一个健康的副本；
+// 未配置副本、全部副本不可用或 fresh 为 true（要求读取最新数据）时返回主库。
+func ReadDB(fresh bool) *gorm.DB {
+	if !fresh {
+		replicasMu.RLock()
+		list := replicas
+		replicasMu.RUnlock()
+		if n := len(list); n > 0 {
+			start := replicaNext.Add(1)
+			for i := 0; i < n; i++ {
+				r := list[(start+uint64(i))%uint64(n)]
+				if r.healthy.Load() {
+					if db := r.db.Load(); db != nil {
+						return db
+					}
+				}
+			}
+		}
+	}
+	return DB
+}
+
+// ReplicaStatus 是只读副本的公开状态，不包含凭据
+type ReplicaStatus struct {
+	Name    string `json:"name"`
+	Addr    string `json:"addr"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Replicas 返回全部只读副本的健康状态
+func Replicas() []ReplicaStatus {
+	replicasMu.RLock()
+	defer replicasMu.RUnlock()
+	out := make([]ReplicaStatus, len(replicas))
+	for i, r := range replicas {
+		msg, _ := r.lastErr.Load().(string)
+		out[i] = ReplicaStatus{Name: r.name, Addr: r.addr, Healthy: r.healthy.Load(), Error: msg}
+	}
+	return out
+}
+
+// CloseReplicas 关闭全部只读副本连接
+func CloseReplicas() {
+	replicasMu.Lock()
+	defer replicasMu.Unlock()
+	for _, r := range replicas {
+		r.healthy.Store(false)
+		if db := r.db.Load(); db != nil {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		}
+	}
+	replicas = nil
+}
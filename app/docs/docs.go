@@ -0,0 +1,61 @@
+// Package docs 提供 OpenAPI 文档的离线浏览页面 (/docs/)。
+//
+// 页面资源通过 go:embed 打包进二进制，不依赖 CDN，可在内网或离线环境使用。默认使用内置的轻量文档页面
+// (ui/index.html)；执行 scripts/fetch-swagger-ui.sh 将 swagger-ui-dist 复制到 ui/swagger-ui/ 后重新构建，
+// /docs/ 会改为使用完整的 Swagger UI。两者都从 /api/v1/openapi.json 加载文档。
+package docs
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed ui
+var assets embed.FS
+
+// swaggerUIBundle 是 Swagger UI 脚本在 ui 目录中的位置，存在时 /docs/ 使用完整的 Swagger UI
+const swaggerUIBundle = "swagger-ui/swagger-ui-bundle.js"
+
+// HasSwaggerUI 报告二进制是否打包了完整的 Swagger UI
+func HasSwaggerUI() bool {
+	_, err := fs.Stat(ui(), swaggerUIBundle)
+	return err == nil
+}
+
+// ui 返回页面资源目录
+func ui() fs.FS {
+	sub, err := fs.Sub(assets, "ui")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// Handler 返回 /docs/*file 的处理函数: /docs/ 返回文档页面，其余路径返回打包的静态资源
+func Handler() gin.HandlerFunc {
+	files := ui()
+	index := "index.html"
+	if HasSwaggerUI() {
+		index = "swagger.html"
+	}
+	fileServer := http.StripPrefix("/docs", http.FileServer(http.FS(files)))
+
+	return func(c *gin.Context) {
+		file := strings.TrimPrefix(c.Param("file"), "/")
+		if file == "" || file == "index.html" {
+			// http.FileServer 会把 index.html 重定向到目录，直接返回页面内容
+			page, err := fs.ReadFile(files, index)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+			return
+		}
+		fileServer.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,177 @@
+// 内置的轻量 OpenAPI 文档页面: 按标签列出全部操作、参数、请求体与响应结构，并支持直接发起调用。
+// 不依赖任何外部资源，供未打包 Swagger UI 时使用。
+(function () {
+  "use strict";
+
+  var specURL = "../api/v1/openapi.json";
+  var methods = ["get", "post", "put", "delete"];
+  var spec = null;
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (k) {
+      if (k === "text") node.textContent = attrs[k];
+      else node.setAttribute(k, attrs[k]);
+    });
+    (children || []).forEach(function (c) { if (c) node.appendChild(c); });
+    return node;
+  }
+
+  // resolve 展开 #/components/... 引用
+  function resolve(obj) {
+    if (!obj || !obj.$ref) return obj;
+    return obj.$ref.replace(/^#\//, "").split("/").reduce(function (o, k) { return o && o[k]; }, spec);
+  }
+
+  // schemaText 将 Schema 转为便于阅读的类型描述
+  function schemaText(schema, depth) {
+    schema = resolve(schema) || {};
+    depth = depth || 0;
+    var pad = new Array(depth + 1).join("  ");
+    if (schema.allOf) {
+      var merged = { type: "object", properties: {} };
+      schema.allOf.forEach(function (s) {
+        s = resolve(s);
+        Object.keys((s && s.properties) || {}).forEach(function (k) { merged.properties[k] = s.properties[k]; });
+      });
+      return schemaText(merged, depth);
+    }
+    if (schema.oneOf) {
+      return schema.oneOf.map(function (s) { return schemaText(s, depth); }).join("\n" + pad + "| ");
+    }
+    if (schema.type === "array") return "[\n" + pad + "  " + schemaText(schema.items, depth + 1) + "\n" + pad + "]";
+    if (schema.properties) {
+      var lines = Object.keys(schema.properties).map(function (k) {
+        return pad + "  " + k + ": " + schemaText(schema.properties[k], depth + 1);
+      });
+      return "{\n" + lines.join(",\n") + "\n" + pad + "}";
+    }
+    var t = schema.type || "any";
+    if (schema.format) t += " (" + schema.format + ")";
+    if (schema.nullable) t += " | null";
+    return t;
+  }
+
+  function renderOperation(path, method, op) {
+    var body = el("div", { class: "body" });
+    if (op.description) body.appendChild(el("p", { class: "desc", text: op.description }));
+
+    var item = spec.paths[path];
+    var params = (item.parameters || []).concat(op.parameters || []).map(resolve);
+    var inputs = {};
+    if (params.length) {
+      var rows = params.map(function (p) {
+        var input = el("input", { placeholder: (p.schema && p.schema.type) || "" });
+        inputs[p.name] = { input: input, param: p };
+        return el("tr", {}, [el("td", { text: p.name + (p.required ? " *" : "") }), el("td", { text: p.in }),
+          el("td", { text: schemaText(p.schema) }), el("td", {}, [input])]);
+      });
+      body.appendChild(el("h4", { text: "参数" }));
+      body.appendChild(el("table", {}, [el("tr", {}, [el("th", { text: "名称" }), el("th", { text: "位置" }),
+        el("th", { text: "类型" }), el("th", { text: "值" })])].concat(rows)));
+    }
+
+    var reqBody = null;
+    if (op.requestBody) {
+      var schema = op.requestBody.content["application/json"].schema;
+      body.appendChild(el("h4", { text: "请求体 (application/json)" }));
+      body.appendChild(el("pre", { text: schemaText(schema) }));
+      reqBody = el("textarea", { rows: "5", cols: "60" });
+      reqBody.value = JSON.stringify(example(schema), null, 2);
+      body.appendChild(reqBody);
+    }
+
+    Object.keys(op.responses || {}).forEach(function (code) {
+      var resp = resolve(op.responses[code]);
+      if (!resp.content || code !== Object.keys(op.responses)[0]) return;
+      body.appendChild(el("h4", { text: "响应 " + code + ": " + resp.description }));
+      body.appendChild(el("pre", { text: schemaText(resp.content["application/json"].schema) }));
+    });
+
+    var output = el("pre", { class: "muted", text: "" });
+    var button = el("button", { text: "调用" });
+    button.addEventListener("click", function () {
+      var url = path, query = [];
+      Object.keys(inputs).forEach(function (name) {
+        var v = inputs[name].input.value, p = inputs[name].param;
+        if (p.in === "path") url = url.replace("{" + name + "}", encodeURIComponent(v));
+        else if (p.in === "query" && v !== "") query.push(encodeURIComponent(name) + "=" + encodeURIComponent(v));
+      });
+      if (query.length) url += "?" + query.join("&");
+      var headers = { "Accept": "application/json" };
+      var token = document.getElementById("token").value;
+      if (token) headers["Authorization"] = "Bearer " + token;
+      var init = { method: method.toUpperCase(), headers: headers };
+      if (reqBody) {
+        headers["Content-Type"] = "application/json";
+        init.body = reqBody.value;
+      }
+      output.textContent = "请求中...";
+      fetch(url, init).then(function (res) {
+        return res.text().then(function (text) {
+          try { text = JSON.stringify(JSON.parse(text), null, 2); } catch (e) { /* 保留原始文本 */ }
+          output.textContent = res.status + " " + res.statusText + "\n\n" + text;
+        });
+      }).catch(function (err) { output.textContent = String(err); });
+    });
+    body.appendChild(button);
+    body.appendChild(output);
+
+    var details = el("details", { class: "op" + (op.deprecated ? " deprecated" : ""), "data-search": (path + " " + (op.summary || "")).toLowerCase() }, [
+      el("summary", {}, [el("span", { class: "method " + method, text: method.toUpperCase() }),
+        el("span", { class: "path", text: path }), el("span", { class: "summary", text: op.summary || "" })]),
+      body
+    ]);
+    return details;
+  }
+
+  // example 根据 Schema 生成示例请求体
+  function example(schema) {
+    schema = resolve(schema) || {};
+    if (schema.properties) {
+      var obj = {};
+      Object.keys(schema.properties).forEach(function (k) { obj[k] = example(schema.properties[k]); });
+      return obj;
+    }
+    switch (schema.type) {
+      case "integer": case "number": return 0;
+      case "boolean": return false;
+      case "array": return [];
+      default: return "";
+    }
+  }
+
+  function render() {
+    document.getElementById("title").textContent = spec.info.title;
+    document.getElementById("description").textContent = spec.info.description || "";
+    var main = document.getElementById("operations");
+    main.innerHTML = "";
+    (spec.tags || []).forEach(function (tag) {
+      var section = el("section", {}, [el("h2", { text: tag.name + (tag.description ? " - " + tag.description : "") })]);
+      Object.keys(spec.paths).sort().forEach(function (path) {
+        methods.forEach(function (m) {
+          var op = spec.paths[path][m];
+          if (op && (op.tags || []).indexOf(tag.name) >= 0) section.appendChild(renderOperation(path, m, op));
+        });
+      });
+      main.appendChild(section);
+    });
+  }
+
+  document.getElementById("filter").addEventListener("input", function (e) {
+    var q = e.target.value.toLowerCase();
+    Array.prototype.forEach.call(document.querySelectorAll("details.op"), function (d) {
+      d.style.display = d.getAttribute("data-search").indexOf(q) >= 0 ? "" : "none";
+    });
+  });
+
+  fetch(specURL).then(function (res) {
+    if (!res.ok) throw new Error(res.status + " " + res.statusText);
+    return res.json();
+  }).then(function (s) {
+    spec = s;
+    render();
+  }).catch(function (err) {
+    document.getElementById("operations").textContent = "加载 OpenAPI 文档失败: " + err;
+  });
+})();
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/audit"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/models"
+	"go-gin-gorm-api/app/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAuditPageSize = 20
+	maxAuditPageSize     = 200
+	// auditExportBatchSize 是导出时每批从数据库读取的行数，避免一次性加载全部审计记录
+	auditExportBatchSize = 500
+	// defaultAuditStatsWindow 是未指定 from 时统计接口默认覆盖的时间范围
+	defaultAuditStatsWindow = 24 * time.Hour
+	// maxAuditStatsWindow 是统计接口允许的最大时间范围。百分位需要在内存中计算，
+	// 限制范围以控制单次统计读取的行数
+	maxAuditStatsWindow = 7 * 24 * time.Hour
+)
+
+// auditCSVHeader 定义 CSV 导出的列顺序
+var auditCSVHeader = []string{
+	"id", "created_at", "path", "method", "client_ip", "principal", "outcome",
+	"http_status", "biz_code", "duration_ms", "rows", "truncated", "sql", "args", "error",
+}
+
+// parseAuditTime 解析时间参数，支持 RFC3339 和 Unix 秒级时间戳
+func parseAuditTime(value string) (time.Time, error) {
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(ts, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// buildAuditQuery 根据查询参数构建审计记录的过滤条件。
+// 支持的参数: path, method, client_ip, principal, outcome, from, to (RFC3339 或 Unix 秒), min_duration_ms。
+func buildAuditQuery(c *gin.Context) (*gorm.DB, error) {
+	query := config.DB.Model(&models.Audit{})
+
+	if v := c.Query("path"); v != "" {
+		query = query.Where("path = ?", v)
+	}
+	if v := c.Query("method"); v != "" {
+		query = query.Where("method = ?", strings.ToUpper(v))
+	}
+	if v := c.Query("client_ip"); v != "" {
+		query = query.Where("client_ip = ?", v)
+	}
+	if v := c.Query("principal"); v != "" {
+		query = query.Where("principal = ?", v)
+	}
+	if v := c.Query("outcome"); v != "" {
+		query = query.Where("outcome IN ?", strings.Split(v, ","))
+	}
+	if v := c.Query("from"); v != "" {
+		from, err := parseAuditTime(v)
+		if err != nil {
+			return nil, fmt.Errorf("from 参数格式错误: %w", err)
+		}
+		query = query.Where("created_at >= ?", from)
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := parseAuditTime(v)
+		if err != nil {
+			return nil, fmt.Errorf("to 参数格式错误: %w", err)
+		}
+		query = query.Where("created_at < ?", to)
+	}
+	if v := c.Query("min_duration_ms"); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("min_duration_ms 参数格式错误: %w", err)
+		}
+		query = query.Where("duration_ms >= ?", ms)
+	}
+
+	// 使用新会话，使返回的查询可以安全地被 Count / Find 等多次复用
+	return query.Session(&gorm.Session{}), nil
+}
+
+// ListAudits 分页检索审计记录，按时间倒序返回。
+// GET /api/v1/admin/audits?path=&method=&client_ip=&principal=&outcome=&from=&to=&min_duration_ms=&page=&page_size=
+func ListAudits(c *gin.Context) {
+	query, err := buildAuditQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "请求参数错误", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultAuditPageSize)))
+	if pageSize < 1 {
+		pageSize = defaultAuditPageSize
+	}
+	if pageSize > maxAuditPageSize {
+		pageSize = maxAuditPageSize
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "查询审计记录失败", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+
+	var audits []models.Audit
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&audits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "查询审计记录失败", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: "查询成功", Data: gin.H{
+		"items":     audits,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}})
+}
+
+// ExportAudits 以流式方式导出满足过滤条件的审计记录。
+// GET /api/v1/admin/audits/export?format=csv|ndjson （过滤参数同 ListAudits）
+func ExportAudits(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", "ndjson"))
+	if format != "csv" && format != "ndjson" {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "format 仅支持 csv 或 ndjson"})
+		return
+	}
+
+	query, err := buildAuditQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "请求参数错误", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+
+	filename := fmt.Sprintf("audits-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var writeRow func(a *models.Audit) error
+	var flush func() error
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(c.Writer)
+		if err := w.Write(auditCSVHeader); err != nil {
+			return
+		}
+		writeRow = func(a *models.Audit) error {
+			return w.Write([]string{
+				strconv.FormatUint(uint64(a.ID), 10), a.CreatedAt.Format(time.RFC3339), a.Path, a.Method,
+				a.ClientIP, a.Principal, a.Outcome, strconv.Itoa(a.HTTPStatus), strconv.Itoa(a.BizCode),
+				strconv.FormatInt(a.DurationMs, 10), strconv.Itoa(a.Rows), strconv.FormatBool(a.Truncated),
+				a.SQL, a.Args, a.Error,
+			})
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(c.Writer)
+		writeRow = func(a *models.Audit) error { return enc.Encode(a) }
+		flush = func() error { return nil }
+	}
+	c.Status(http.StatusOK)
+
+	var batch []models.Audit
+	err = query.FindInBatches(&batch, auditExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := writeRow(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}).Error
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "导出审计记录失败", "format", format, "error", err)
+		_ = c.Error(err)
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.Header("Content-Type", "")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "导出审计记录失败", Data: gin.H{"detail": err.Error()}})
+			return
+		}
+		// 响应头已发送，只能中断输出；客户端收到的文件不完整
+		c.Abort()
+	}
+}
+
+// auditStatsKey 标识统计桶：一个服务（Method + Path）在某一小时内的执行情况
+type auditStatsKey struct {
+	Method string
+	Path   string
+	Hour   time.Time
+}
+
+// AuditStats 是单个服务在一个小时内的聚合统计
+type AuditStats struct {
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Hour       time.Time `json:"hour"`
+	Executions int       `json:"executions"`
+	Errors     int       `json:"errors"`
+	ErrorRate  float64   `json:"error_rate"`
+	P50Ms      int64     `json:"p50_ms"`
+	P95Ms      int64     `json:"p95_ms"`
+}
+
+// percentile 使用最近秩 (nearest-rank) 方法计算已排序切片的百分位数
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p*float64(len(sorted))+0.999999) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// GetAuditStats 按服务和小时聚合审计记录：执行次数、错误率 (outcome 非 success)、p50/p95 耗时。
+// GET /api/v1/admin/audits/stats?from=&to=&path=&method=... 未指定 from 时默认统计 to (默认为当前时间)
+// 之前的 24 小时，时间范围最长 7 天。
+func GetAuditStats(c *gin.Context) {
+	query, err := buildAuditQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "请求参数错误", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+	// from 与 to 已由 buildAuditQuery 校验
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		to, _ = parseAuditTime(v)
+	}
+	from := to.Add(-defaultAuditStatsWindow)
+	if v := c.Query("from"); v != "" {
+		from, _ = parseAuditTime(v)
+	} else {
+		query = query.Where("created_at >= ?", from)
+	}
+	if to.Sub(from) > maxAuditStatsWindow {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "请求参数错误", Data: gin.H{
+			"detail": fmt.Sprintf("统计时间范围不能超过 %.0f 小时", maxAuditStatsWindow.Hours()),
+		}})
+		return
+	}
+
+	// MySQL 不支持百分位聚合函数，因此只取必要列逐行读取并在内存中分桶计算
+	rows, err := query.Select("method, path, created_at, duration_ms, outcome").Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "统计审计记录失败", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+	defer rows.Close()
+
+	buckets := make(map[auditStatsKey]*AuditStats)
+	durations := make(map[auditStatsKey][]int64)
+	for rows.Next() {
+		var (
+			method, path, outcome string
+			createdAt             time.Time
+			durationMs            int64
+		)
+		if err := rows.Scan(&method, &path, &createdAt, &durationMs, &outcome); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "统计审计记录失败", Data: gin.H{"detail": err.Error()}})
+			return
+		}
+
+		key := auditStatsKey{Method: method, Path: path, Hour: createdAt.UTC().Truncate(time.Hour)}
+		st, ok := buckets[key]
+		if !ok {
+			st = &AuditStats{Method: method, Path: path, Hour: key.Hour}
+			buckets[key] = st
+		}
+		st.Executions++
+		if outcome != models.AuditOutcomeSuccess {
+			st.Errors++
+		}
+		durations[key] = append(durations[key], durationMs)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "统计审计记录失败", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+
+	stats := make([]*AuditStats, 0, len(buckets))
+	for key, st := range buckets {
+		d := durations[key]
+		sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+		st.P50Ms = percentile(d, 0.50)
+		st.P95Ms = percentile(d, 0.95)
+		st.ErrorRate = float64(st.Errors) / float64(st.Executions)
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if !stats[i].Hour.Equal(stats[j].Hour) {
+			return stats[i].Hour.Before(stats[j].Hour)
+		}
+		if stats[i].Path != stats[j].Path {
+			return stats[i].Path < stats[j].Path
+		}
+		return stats[i].Method < stats[j].Method
+	})
+
+	c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: "查询成功", Data: stats})
+}
+
+// VerifyAudits 校验审计哈希链与签名检查点，报告第一处断链。
+// GET /api/v1/admin/audits/verify?from_id=
+func VerifyAudits(c *gin.Context) {
+	fromID, err := strconv.ParseUint(c.DefaultQuery("from_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "from_id 参数格式错误"})
+		return
+	}
+
+	report, err := audit.VerifyChain(config.DB, uint(fromID))
+	if err != nil {
+		if errors.Is(err, audit.ErrChainDisabled) {
+			c.JSON(http.StatusConflict, utils.APIResponse{Code: 409, Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "审计哈希链校验失败", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+
+	if !report.Valid {
+		c.JSON(http.StatusOK, utils.APIResponse{Code: 1, Message: "审计哈希链校验未通过", Data: report})
+		return
+	}
+	c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: "审计哈希链校验通过", Data: report})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/models"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []int64
+		p      float64
+		want   int64
+	}{
+		{"空切片", nil, 0.5, 0},
+		{"单个值", []int64{7}, 0.95, 7},
+		{"p50 取下中位数", []int64{1, 2, 3, 4}, 0.50, 2},
+		{"p50 奇数个", []int64{1, 2, 3, 4, 5}, 0.50, 3},
+		{"p95 最近秩向上取整", []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 0.95, 10},
+		{"p95 恰好整除", append(make([]int64, 19), 100), 0.95, 0},
+		{"p0 取最小值", []int64{3, 5}, 0, 3},
+		{"p100 取最大值", []int64{3, 5}, 1, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%v, %v) = %d, want %d", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAuditStats(t *testing.T) {
+	db := setupTestDB(t)
+	base := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	insert := func(at time.Time, path string, durationMs int64, outcome string) {
+		t.Helper()
+		a := &models.Audit{Path: path, Method: "GET", DurationMs: durationMs, Outcome: outcome}
+		a.CreatedAt = at
+		if err := db.Create(a).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// base 这一小时的 /a: 10 次，耗时 10..100，其中 2 次失败
+	for i := int64(1); i <= 10; i++ {
+		outcome := models.AuditOutcomeSuccess
+		if i > 8 {
+			outcome = models.AuditOutcomeSQLError
+		}
+		insert(base.Add(time.Duration(i)*time.Minute), "/a", i*10, outcome)
+	}
+	insert(base.Add(time.Hour+time.Minute), "/a", 5, models.AuditOutcomeSuccess)
+	insert(base.Add(30*time.Minute), "/b", 40, models.AuditOutcomeTimeout)
+	// 默认 24 小时窗口之外
+	insert(base.Add(-3*24*time.Hour), "/a", 1000, models.AuditOutcomeSuccess)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/stats", GetAuditStats)
+	unix := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantStatus int
+		want       []AuditStats
+	}{
+		{"默认最近 24 小时", nil, http.StatusOK, []AuditStats{
+			{Method: "GET", Path: "/a", Hour: base, Executions: 10, Errors: 2, ErrorRate: 0.2, P50Ms: 50, P95Ms: 100},
+			{Method: "GET", Path: "/b", Hour: base, Executions: 1, Errors: 1, ErrorRate: 1, P50Ms: 40, P95Ms: 40},
+			{Method: "GET", Path: "/a", Hour: base.Add(time.Hour), Executions: 1, P50Ms: 5, P95Ms: 5},
+		}},
+		{"按路径过滤", url.Values{"path": {"/b"}}, http.StatusOK, []AuditStats{
+			{Method: "GET", Path: "/b", Hour: base, Executions: 1, Errors: 1, ErrorRate: 1, P50Ms: 40, P95Ms: 40},
+		}},
+		{"只指定 to 时统计其之前的 24 小时", url.Values{"to": {unix(base.Add(-3*24*time.Hour + time.Hour))}}, http.StatusOK, []AuditStats{
+			{Method: "GET", Path: "/a", Hour: base.Add(-3 * 24 * time.Hour), Executions: 1, P50Ms: 1000, P95Ms: 1000},
+		}},
+		{"恰好 7 天", url.Values{"from": {unix(base.Add(-7 * 24 * time.Hour))}, "to": {unix(base)}}, http.StatusOK, []AuditStats{
+			{Method: "GET", Path: "/a", Hour: base.Add(-3 * 24 * time.Hour), Executions: 1, P50Ms: 1000, P95Ms: 1000},
+		}},
+		{"超过 7 天", url.Values{"from": {unix(base.Add(-7*24*time.Hour - time.Second))}, "to": {unix(base)}},
+			http.StatusBadRequest, nil},
+		{"from 超过 7 天且未指定 to", url.Values{"from": {unix(base.Add(-8 * 24 * time.Hour))}}, http.StatusBadRequest, nil},
+		{"时间格式错误", url.Values{"from": {"yesterday"}}, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := doRequest(t, r, http.MethodGet, "/stats?"+tt.query.Encode(), "")
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", status, resp.Message, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []AuditStats
+			if err := json.Unmarshal(resp.Data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("stats = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Hour.Equal(tt.want[i].Hour) {
+					t.Errorf("stats[%d].Hour = %v, want %v", i, got[i].Hour, tt.want[i].Hour)
+				}
+				got[i].Hour = tt.want[i].Hour
+				if got[i] != tt.want[i] {
+					t.Errorf("stats[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
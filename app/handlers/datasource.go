@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/utils"
+)
+
+// ListDatasources 列出全部数据源及其连接状态、检查耗时与连接池统计。
+// 响应中只包含地址与库名，不返回用户名和密码。
+// GET /api/v1/admin/datasources
+func ListDatasources(c *gin.Context) {
+	infos := config.DescribeDatasources(c.Request.Context(), config.Current().Server.ReadinessTimeout)
+	c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: "查询成功", Data: infos})
+}
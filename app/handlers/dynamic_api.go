@@ -5,35 +5,152 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
-	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go-gin-gorm-api/app/audit"
 	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/metrics"
+	"go-gin-gorm-api/app/middleware"
 	"go-gin-gorm-api/app/models"
+	"go-gin-gorm-api/app/tracing"
 	"go-gin-gorm-api/app/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
-// allowedQueryPrefixes 定义了允许通过动态服务执行的只读查询语句前缀。
-// 仅允许 SELECT, WITH, EXPLAIN 和 DESCRIBE/DESC 等不会修改数据库状态的语句。
-var allowedQueryPrefixes = []string{"SELECT", "WITH", "EXPLAIN", "DESCRIBE", "DESC "}
+// dynamicServiceKey 是 gin.Context 中保存当前动态服务名称的键，供指标等按服务统计
+const dynamicServiceKey = "dynamic_service"
 
-// isAllowedQuery 检查 SQL 语句是否以允许的只读前缀开始。
-func isAllowedQuery(sql string) bool {
-	sqlUpper := strings.ToUpper(strings.TrimSpace(sql))
-	for _, prefix := range allowedQueryPrefixes {
-		if strings.HasPrefix(sqlUpper, prefix) {
-			return true
+// ValidateService 校验并规范化动态服务定义，HTTP 注册接口与命令行 (app service register/import) 共用:
+// 检查必填字段、ParamKeys 与 ParamTypes、数据源、占位符数量与 AllowedOrigins，
+// 并补全路径前缀、将方法转为大写。返回的错误信息可直接展示给调用方。
+func ValidateService(service *models.APIService) error {
+	service.Method = strings.ToUpper(service.Method)
+	if err := binding.Validator.ValidateStruct(service); err != nil {
+		return fmt.Errorf("请求参数错误或缺失: %w", err)
+	}
+	// 名称与路径会写入日志、OpenAPI 文档和生成的 SDK 注释，不允许换行等控制字符与注释结束符 */
+	for _, v := range []string{service.Name, service.Path} {
+		if strings.Contains(v, "*/") || strings.ContainsFunc(v, func(r rune) bool {
+			return unicode.IsControl(r) || r == '\u2028' || r == '\u2029'
+		}) {
+			return errors.New("Name 与 Path 不能包含换行等控制字符或 */")
+		}
+	}
+
+	// 检查 ParamKeys 和 ParamTypes 的数量是否一致
+	var paramKeys []string
+	var paramTypes []string
+
+	if service.ParamKeys != "" && json.Unmarshal([]byte(service.ParamKeys), &paramKeys) != nil {
+		return errors.New("ParamKeys 格式错误 (非 JSON 数组)")
+	}
+	if service.ParamTypes != "" && json.Unmarshal([]byte(service.ParamTypes), &paramTypes) != nil {
+		return errors.New("ParamTypes 格式错误 (非 JSON 数组)")
+	}
+
+	if len(paramKeys) != len(paramTypes) {
+		return errors.New("ParamKeys 和 ParamTypes 数量不匹配")
+	}
+
+	// 校验数据源是否已在配置中定义
+	if service.Datasource == config.DefaultDatasource {
+		service.Datasource = ""
+	}
+	if !config.HasDatasource(service.Datasource) {
+		return errors.New("未知的数据源: " + service.Datasource)
+	}
+
+	// 按数据源的方言校验占位符数量（? 或 PostgreSQL 的 $N）与 ParamKeys 一致；
+	// pipeline 服务的占位符在各步骤中按 params 校验
+	if service.Kind != models.ServiceKindPipeline {
+		placeholders, err := countPlaceholders(service.SQL, config.DatasourceDriver(service.Datasource))
+		if err != nil {
+			return errors.New("SQL 占位符格式错误: " + err.Error())
+		}
+		if placeholders != len(paramKeys) {
+			return fmt.Errorf("SQL 占位符数量 (%d) 与 ParamKeys 数量 (%d) 不匹配", placeholders, len(paramKeys))
+		}
+	}
+
+	// 校验服务级跨域来源
+	if _, err := parseAllowedOrigins(service.AllowedOrigins); err != nil {
+		return errors.New("AllowedOrigins 格式错误: " + err.Error())
+	}
+
+	// 写服务需显式启用，且只允许单条带 WHERE 条件的 INSERT/UPDATE/DELETE
+	if service.Kind == "" {
+		service.Kind = models.ServiceKindQuery
+	}
+	writes := false
+	switch service.Kind {
+	case models.ServiceKindCommand:
+		if _, err := checkCommand(service.SQL, config.DatasourceDriver(service.Datasource)); err != nil {
+			return err
 		}
+		writes = true
+	case models.ServiceKindPipeline:
+		// 流水线的语句由 steps 定义，包含写步骤时与写服务的约束相同
+		if strings.TrimSpace(service.SQL) != "" {
+			return errors.New("pipeline 服务的语句由 steps 定义，sql 必须为空")
+		}
+		steps, err := parsePipeline(service)
+		if err != nil {
+			return err
+		}
+		writes = pipelineWrites(steps)
+	}
+	if service.Kind != models.ServiceKindPipeline && service.Steps != "" {
+		return errors.New("steps 仅用于 pipeline 服务")
 	}
-	return false
+	if service.Kind == models.ServiceKindQuery && service.IsolationLevel != "" {
+		return errors.New("isolation_level 仅用于 command 与 pipeline 服务")
+	}
+	if writes {
+		if !config.Current().Dynamic.CommandsEnabled {
+			return errors.New("写服务 (kind=command 或包含写步骤的 pipeline) 未启用，请设置 dynamic.commands_enabled")
+		}
+		if service.Method == http.MethodGet {
+			return errors.New("写服务不能使用 GET 方法")
+		}
+	}
+
+	// 发布状态与生效时间窗口
+	if service.Status == "" {
+		service.Status = models.ServiceStatusActive
+	}
+	if service.ActivatesAt != nil && service.ExpiresAt != nil && !service.ExpiresAt.After(*service.ActivatesAt) {
+		return errors.New("expires_at 必须晚于 activates_at")
+	}
+
+	if !strings.HasPrefix(service.Path, "/") {
+		service.Path = "/" + service.Path
+	}
+	return nil
 }
 
+// CheckNotManaged 检查名称或路径是否属于由服务定义目录管理的服务，是则返回错误。
+// 由文件管理的服务只读，只能通过修改 dynamic.services_dir 中的文件变更。
+func CheckNotManaged(db *gorm.DB, name, path string) error {
+	var managed models.APIService
+	err := db.Unscoped().Select("name", "managed_by").
+		Where("(name = ? OR path = ?) AND managed_by <> ''", name, path).Limit(1).Find(&managed).Error
+	if err != nil {
+		return err
+	}
+	if managed.ManagedBy != "" {
+		return fmt.Errorf("动态服务 %s 由文件 %s 管理，只能通过修改服务定义目录变更", managed.Name, managed.ManagedBy)
+	}
+	return nil
+}
 
 // RegisterService 处理动态服务注册请求。
 // 此函数现在接收并存储 ParamTypes 字段，并检查 ParamKeys 与 ParamTypes 数量的一致性。
@@ -47,29 +164,24 @@ func RegisterService(c *gin.Context) {
 		return
 	}
 	
-	// 检查 ParamKeys 和 ParamTypes 的数量是否一致
-	var paramKeys []string
-	var paramTypes []string
-	
-	if service.ParamKeys != "" && json.Unmarshal([]byte(service.ParamKeys), &paramKeys) != nil {
-		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "ParamKeys 格式错误 (非 JSON 数组)"})
+	if err := ValidateService(&service); err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: err.Error()})
 		return
 	}
-	if service.ParamTypes != "" && json.Unmarshal([]byte(service.ParamTypes), &paramTypes) != nil {
-		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "ParamTypes 格式错误 (非 JSON 数组)"})
-		return
-	}
-	
-	if len(paramKeys) != len(paramTypes) {
-		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "ParamKeys 和 ParamTypes 数量不匹配"})
+
+	// 写服务只能由管理员通过服务定义目录、批量导入或命令行注册
+	if requiresCommandScope(&service) {
+		c.JSON(http.StatusForbidden, utils.APIResponse{Code: 403, Message: "写服务 (kind=command 或包含写步骤的 pipeline) 只能通过服务定义目录、管理接口导入或命令行注册"})
 		return
 	}
 
-	if !strings.HasPrefix(service.Path, "/") {
-		service.Path = "/" + service.Path
+	// 由服务定义目录管理的服务只读，名称或路径与其冲突时拒绝注册
+	if err := CheckNotManaged(config.DB, service.Name, service.Path); err != nil {
+		c.JSON(http.StatusConflict, utils.APIResponse{Code: 409, Message: err.Error()})
+		return
 	}
-
-	service.Method = strings.ToUpper(service.Method)
+	service.ManagedBy = ""
+	service.TrackDeprecation(nil, time.Now())
 
 	result := config.DB.Create(&service)
 	if result.Error != nil {
@@ -88,29 +200,106 @@ func RegisterService(c *gin.Context) {
 	})
 }
 
+// finishExecution 向客户端返回响应，并将本次执行尝试（无论成功与否）写入审计表。
+// outcome 取值见 models.AuditOutcome* 常量；execErr 为底层错误，为 nil 时失败记录使用响应消息作为错误描述。
+func finishExecution(c *gin.Context, rec *models.Audit, outcome string, status int, resp utils.APIResponse, execErr error) {
+	rec.Outcome = outcome
+	rec.HTTPStatus = status
+	rec.BizCode = resp.Code
+	if execErr != nil {
+		rec.Error = execErr.Error()
+	} else if outcome != models.AuditOutcomeSuccess {
+		rec.Error = resp.Message
+	}
+
+	ctx := c.Request.Context()
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("dynamic.service", c.GetString(dynamicServiceKey)),
+		attribute.String("dynamic.outcome", outcome),
+	)
+
+	// 由后台审计写入器异步批量落库，不阻塞请求路径
+	_, auditSpan := tracing.Tracer().Start(ctx, "dynamic.audit")
+	audit.Record(config.DB, rec)
+	auditSpan.End()
+	metrics.ObserveDynamicExecution(c.GetString(dynamicServiceKey), outcome,
+		time.Duration(rec.DurationMs)*time.Millisecond, rec.Rows, outcome == models.AuditOutcomeBlocked)
+
+	_, encodeSpan := tracing.Tracer().Start(ctx, "dynamic.encode_response")
+	c.JSON(status, resp)
+	encodeSpan.End()
+}
+
+// datasourceName 返回用于日志与追踪的数据源名称，空值表示主库
+func datasourceName(name string) string {
+	if name == "" {
+		return config.DefaultDatasource
+	}
+	return name
+}
+
+// datasourceUnavailable 记录服务的数据源不可用 (未配置或连接失败)，返回 503 并写入审计记录
+func datasourceUnavailable(c *gin.Context, rec *models.Audit, service *models.APIService, err error) {
+	slog.ErrorContext(c.Request.Context(), "动态服务数据源不可用", "service", service.Name, "datasource", datasourceName(service.Datasource), "error", err)
+	finishExecution(c, rec, models.AuditOutcomeSQLError, http.StatusServiceUnavailable,
+		utils.APIResponse{Code: 503, Message: "数据源不可用", Data: gin.H{"detail": err.Error()}}, err)
+}
+
 // ExecuteService 是动态 SQL 服务的核心执行逻辑，已实现强制类型转换。
 // 【安全修复】此函数现在只允许执行预定义的只读查询操作。非查询操作将被阻止并仅记录。
+// 每一次执行尝试都会通过 finishExecution 写入审计表，便于安全审查发现滥用行为。
 func ExecuteService(c *gin.Context) {
 	reqMethod := c.Request.Method
 	path := c.Param("path")
+
+	audit := &models.Audit{
+		Path:      path,
+		Method:    reqMethod,
+		ClientIP:  c.ClientIP(),
+		Principal: c.GetString(middleware.PrincipalKey),
+		RequestID: c.GetString(middleware.RequestIDKey),
+	}
 	
 	if path == "" {
-		c.JSON(http.StatusNotFound, utils.APIResponse{Code: 404, Message: "动态服务路径未指定"})
+		finishExecution(c, audit, models.AuditOutcomeRejected, http.StatusNotFound,
+			utils.APIResponse{Code: 404, Message: "动态服务路径未指定"}, nil)
 		return
 	}
 
 	var service models.APIService
 	
 	// 1. 根据 Method 和 Path 查找注册的服务
-	err := config.DB.Where("method = ? AND path = ?", reqMethod, path).First(&service).Error
+	lookupCtx, lookupSpan := tracing.Tracer().Start(c.Request.Context(), "dynamic.lookup_service")
+	err := config.DB.WithContext(lookupCtx).Where("method = ? AND path = ?", reqMethod, path).First(&service).Error
+	lookupSpan.End()
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) { 
-			c.JSON(http.StatusNotFound, utils.APIResponse{Code: 404, Message: fmt.Sprintf("未找到方法为 %s, 路径为 %s 的动态服务配置", reqMethod, path)})
+			finishExecution(c, audit, models.AuditOutcomeRejected, http.StatusNotFound,
+				utils.APIResponse{Code: 404, Message: fmt.Sprintf("未找到方法为 %s, 路径为 %s 的动态服务配置", reqMethod, path)}, nil)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "查询服务配置失败", Data: gin.H{"detail": err.Error()}})
+		finishExecution(c, audit, models.AuditOutcomeSQLError, http.StatusInternalServerError,
+			utils.APIResponse{Code: 500, Message: "查询服务配置失败", Data: gin.H{"detail": err.Error()}}, err)
+		return
+	}
+	audit.SQL = service.SQL
+	c.Set(dynamicServiceKey, service.Name)
+
+	// 按发布状态与生效时间窗口决定是否执行，弃用与即将到期的服务通过响应头提示调用方
+	if status, resp := serviceAvailability(&service, time.Now()); status != http.StatusOK {
+		finishExecution(c, audit, models.AuditOutcomeRejected, status, resp, nil)
 		return
 	}
+	setLifecycleHeaders(c, &service)
+
+	// 写服务 (command 或包含写步骤的 pipeline) 需启用 dynamic.commands_enabled 且调用方拥有 command 权限范围
+	isCommand := service.Kind == models.ServiceKindCommand
+	if requiresCommandScope(&service) {
+		if status, resp := commandPermission(c); status != http.StatusOK {
+			finishExecution(c, audit, models.AuditOutcomeRejected, status, resp, nil)
+			return
+		}
+	}
 
 	// 2. 解析 ParamKeys 和 ParamTypes 获取参数顺序和类型
 	var paramKeys []string
@@ -118,38 +307,64 @@ func ExecuteService(c *gin.Context) {
 	
 	if service.ParamKeys != "" {
 		if err := json.Unmarshal([]byte(service.ParamKeys), &paramKeys); err != nil {
-			c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "服务配置错误：ParamKeys 格式无效"})
+			finishExecution(c, audit, models.AuditOutcomeRejected, http.StatusInternalServerError,
+				utils.APIResponse{Code: 500, Message: "服务配置错误：ParamKeys 格式无效"}, err)
 			return
 		}
 	}
 	if service.ParamTypes != "" {
 		if err := json.Unmarshal([]byte(service.ParamTypes), &paramTypes); err != nil {
-			c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "服务配置错误：ParamTypes 格式无效"})
+			finishExecution(c, audit, models.AuditOutcomeRejected, http.StatusInternalServerError,
+				utils.APIResponse{Code: 500, Message: "服务配置错误：ParamTypes 格式无效"}, err)
 			return
 		}
 	}
 
 	if len(paramKeys) != len(paramTypes) {
-		c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "服务配置错误：ParamKeys 和 ParamTypes 数量不匹配"})
+		finishExecution(c, audit, models.AuditOutcomeRejected, http.StatusInternalServerError,
+			utils.APIResponse{Code: 500, Message: "服务配置错误：ParamKeys 和 ParamTypes 数量不匹配"}, nil)
 		return
 	}
 
-	// 【安全检查】使用辅助函数检查是否为允许的只读查询
-	if !isAllowedQuery(service.SQL) {
+	// 【安全检查】按服务数据源的方言检查是否为允许的只读查询
+	driver := config.DatasourceDriver(service.Datasource)
+	var command *commandStatement
+	var steps []pipelineStep
+	if service.Kind == models.ServiceKindPipeline {
+		if steps, err = parsePipeline(&service); err != nil {
+			slog.WarnContext(c.Request.Context(), "Security Alert: 已拦截不符合约束的流水线服务",
+				"path", path, "method", reqMethod, "service", service.Name, "client_ip", c.ClientIP(), "error", err)
+			finishExecution(c, audit, models.AuditOutcomeBlocked, http.StatusOK,
+				utils.APIResponse{Code: 1, Message: "安全限制: " + err.Error()}, err)
+			return
+		}
+		audit.SQL = pipelineSQL(steps)
+	} else if isCommand {
+		// 写服务在注册时已校验，这里再次检查，防止绕过注册接口直接修改数据库中的定义
+		if command, err = checkCommand(service.SQL, driver); err != nil {
+			slog.WarnContext(c.Request.Context(), "Security Alert: 已拦截不符合约束的写服务",
+				"path", path, "method", reqMethod, "service", service.Name, "sql", service.SQL, "client_ip", c.ClientIP(), "error", err)
+			finishExecution(c, audit, models.AuditOutcomeBlocked, http.StatusOK,
+				utils.APIResponse{Code: 1, Message: "安全限制: " + err.Error()}, err)
+			return
+		}
+	} else if !isAllowedQuery(service.SQL, driver) {
 		sqlUpper := strings.ToUpper(strings.TrimSpace(service.SQL))
 		
-		log.Printf("Security Alert: Blocked execution of write/unauthorized dynamic SQL. Path=%s, Method=%s, SQL=%s", path, reqMethod, service.SQL)
+		slog.WarnContext(c.Request.Context(), "Security Alert: 已拦截写操作或未授权的动态 SQL",
+			"path", path, "method", reqMethod, "service", service.Name, "sql", service.SQL, "client_ip", c.ClientIP())
 
 		// 返回成功状态码（HTTP 200），但使用非 0 的业务代码和警告消息，表示操作被安全策略拦截/跳过
-		c.JSON(http.StatusOK, utils.APIResponse{
+		finishExecution(c, audit, models.AuditOutcomeBlocked, http.StatusOK, utils.APIResponse{
 			Code:    1, // 使用非 0 状态码表示操作被安全策略拦截/跳过
-			Message: fmt.Sprintf("安全限制: 动态服务只允许执行 %v 查询操作。非查询操作已被阻止。", allowedQueryPrefixes),
+			Message: fmt.Sprintf("安全限制: 动态服务只允许执行 %v 查询操作。非查询操作已被阻止。", allowedQueryPrefixes(driver)),
 			Data:    gin.H{"sql_statement_type": strings.Split(sqlUpper, " ")[0]},
-		})
+		}, nil)
 		return
 	}
 
 	// 3. 收集原始请求参数
+	_, convertSpan := tracing.Tracer().Start(c.Request.Context(), "dynamic.convert_params")
 	rawParams := make(map[string]interface{})
 	
 	if reqMethod == http.MethodGet {
@@ -166,7 +381,9 @@ func ExecuteService(c *gin.Context) {
 			if err := c.ShouldBindJSON(&rawParams); err != nil {
 				// 忽略 EOF 错误，表示请求体为空，但这通常意味着参数缺失，后续检查会捕获
 				if !errors.Is(err, errors.New("EOF")) { 
-					c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "请求体解析失败或格式错误", Data: gin.H{"detail": err.Error()}})
+					convertSpan.End()
+					finishExecution(c, audit, models.AuditOutcomeBadRequest, http.StatusBadRequest,
+						utils.APIResponse{Code: 400, Message: "请求体解析失败或格式错误", Data: gin.H{"detail": err.Error()}}, err)
 					return
 				}
 			}
@@ -174,117 +391,108 @@ func ExecuteService(c *gin.Context) {
 	}
 
 	// 4. 严格按照 ParamKeys 和 ParamTypes 顺序进行类型转换和参数收集
-	var args []interface{}
-	for i, key := range paramKeys {
-		expectedType := strings.ToLower(paramTypes[i])
-		rawValue, ok := rawParams[key]
-		
-		if !ok {
-			c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: fmt.Sprintf("请求参数缺失: %s", key)})
-			return
+	args, unknownTypes, perr := convertParams(paramKeys, paramTypes, rawParams)
+	if perr != nil {
+		convertSpan.End()
+		resp := utils.APIResponse{Code: 400, Message: perr.Error()}
+		if perr.Err != nil {
+			resp.Data = gin.H{"error": perr.Err.Error()}
 		}
+		finishExecution(c, audit, models.AuditOutcomeBadRequest, http.StatusBadRequest, resp, perr.Err)
+		return
+	}
+	for _, t := range unknownTypes {
+		slog.WarnContext(c.Request.Context(), "未知的参数类型，按 string 处理", "param", t, "service", service.Name)
+	}
+	convertSpan.End()
 
-		var convertedValue interface{}
-		var err error
-		
-		// 统一将原始值转换为字符串以便使用 strconv 进行精确转换
-		var strValue string
-		switch v := rawValue.(type) {
-		case string:
-			strValue = v
-		case float64: // JSON 解析数字默认是 float64
-			// 转换为字符串时，使用 -1 精度，以确保保留原始数字的所有有效位，避免科学计数法或精度丢失。
-			strValue = strconv.FormatFloat(v, 'f', -1, 64) 
-		case bool:
-			strValue = strconv.FormatBool(v)
-		default:
-			// 兜底：尝试将其他类型转换为字符串
-			strValue = fmt.Sprintf("%v", v)
-		}
-		
-		// 执行类型转换
-		switch expectedType {
-		case "int", "int64":
-			var v int64
-			v, err = strconv.ParseInt(strValue, 10, 64)
-			convertedValue = v
-		case "float", "float64":
-			var v float64
-			v, err = strconv.ParseFloat(strValue, 64)
-			convertedValue = v
-		case "bool":
-			var v bool
-			// ParseBool 接受多种格式 (t, f, 1, 0, true, false)
-			v, err = strconv.ParseBool(strValue)
-			convertedValue = v
-		case "string":
-			convertedValue = strValue
-		default:
-			// 如果类型未指定或未知，默认使用字符串，并记录警告
-			log.Printf("Warning: Unknown type '%s' specified for key '%s'. Defaulting to string.", expectedType, key)
-			convertedValue = strValue
-		}
+	argsBytes, _ := json.Marshal(args)
+	audit.Args = string(argsBytes)
 
-		if err != nil {
-			c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: fmt.Sprintf("参数 '%s' 无法转换为预期类型 '%s'", key, expectedType), Data: gin.H{"error": err.Error()}})
-			return
-		}
+	if service.Kind == models.ServiceKindPipeline {
+		slog.InfoContext(c.Request.Context(), "执行流水线服务", "path", path, "method", reqMethod, "service", service.Name, "steps", len(steps))
+		executePipeline(c, audit, &service, steps, paramKeys, args)
+		return
+	}
 
-		args = append(args, convertedValue)
+	// PostgreSQL 的 $N 编号占位符转换为 GORM 的 ? 占位符
+	sqlText, args, err := bindPlaceholders(service.SQL, driver, args)
+	if err != nil {
+		finishExecution(c, audit, models.AuditOutcomeRejected, http.StatusInternalServerError,
+			utils.APIResponse{Code: 500, Message: "服务配置错误：SQL 占位符与参数不匹配", Data: gin.H{"detail": err.Error()}}, err)
+		return
 	}
 	
-	log.Printf("执行动态服务: Path=%s, Method=%s, SQL=%s, 参数=%v", path, reqMethod, service.SQL, args)
+	slog.InfoContext(c.Request.Context(), "执行动态服务", "path", path, "method", reqMethod, "service", service.Name, "sql", service.SQL)
 
-	// 5. 执行 SQL 并扫描结果（带超时与行数限制），并写入审计表
-	var results []map[string]interface{}
-
-	// 从环境变量读取可配置项，提供默认值
-	maxRows := 1000
-	if v := os.Getenv("DYNAMIC_MAX_ROWS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			maxRows = n
-		}
+	if isCommand {
+		executeCommand(c, audit, &service, command, sqlText, args)
+		return
 	}
 
-	timeoutSec := 5
-	if v := os.Getenv("DYNAMIC_QUERY_TIMEOUT_SECONDS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			timeoutSec = n
-		}
-	}
+	// 5. 执行 SQL 并扫描结果（带超时与行数限制），并写入审计表
+	var results []map[string]interface{}
 
-	queryTimeout := time.Duration(timeoutSec) * time.Second
+	// 行数与超时限制来自当前生效的配置（支持热加载）
+	limits := config.Current().Dynamic
+	maxRows := limits.MaxRows
+	queryTimeout := limits.QueryTimeout
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), queryTimeout)
 	defer cancel()
 
+	// 按服务配置的数据源路由查询；主库的只读查询优先使用健康的只读副本，
+	// 服务标记 requires_fresh_data 时强制读取主库
+	target, err := config.ReadDatasource(service.Datasource, service.RequiresFreshData)
+	if err != nil {
+		datasourceUnavailable(c, audit, &service, err)
+		return
+	}
+
+	ctx, execSpan := tracing.Tracer().Start(ctx, "dynamic.execute_sql",
+		trace.WithAttributes(attribute.String("dynamic.datasource", datasourceName(service.Datasource)),
+			attribute.Bool("dynamic.requires_fresh_data", service.RequiresFreshData)))
 	start := time.Now()
 
 	// 使用带上下文的 DB 执行查询
-	db := config.DB.WithContext(ctx).Raw(service.SQL, args...)
+	db := target.WithContext(ctx).Raw(sqlText, args...)
 	if db.Error != nil {
-		log.Printf("SQL 执行失败: %v", db.Error)
-		c.JSON(http.StatusInternalServerError, utils.APIResponse{
+		execSpan.RecordError(db.Error)
+		execSpan.SetStatus(codes.Error, db.Error.Error())
+		execSpan.End()
+		slog.ErrorContext(c.Request.Context(), "动态 SQL 执行失败", "service", service.Name, "error", db.Error)
+		audit.DurationMs = time.Since(start).Milliseconds()
+		finishExecution(c, audit, models.AuditOutcomeSQLError, http.StatusInternalServerError, utils.APIResponse{
 			Code:    500,
 			Message: "SQL 执行失败，请检查 SQL 语句、ParamKeys 和 ParamTypes 配置。",
 			Data:    gin.H{"detail": db.Error.Error()},
-		})
+		}, db.Error)
 		return
 	}
 
-	if err := db.Find(&results).Error; err != nil {
+	err = db.Find(&results).Error
+	execSpan.SetAttributes(attribute.Int("db.rows_returned", len(results)))
+	if err != nil {
+		execSpan.RecordError(err)
+		execSpan.SetStatus(codes.Error, err.Error())
+	}
+	execSpan.End()
+	if err != nil {
+		audit.DurationMs = time.Since(start).Milliseconds()
 		if errors.Is(err, context.DeadlineExceeded) {
-			log.Printf("SQL 执行超时: Path=%s, Method=%s, SQL=%s, err=%v", path, reqMethod, service.SQL, err)
-			c.JSON(http.StatusOK, utils.APIResponse{Code: 2, Message: "查询超时，已取消执行"})
+			slog.WarnContext(c.Request.Context(), "动态 SQL 执行超时", "path", path, "method", reqMethod, "service", service.Name,
+				"sql", service.SQL, "timeout", queryTimeout.String(), "error", err)
+			finishExecution(c, audit, models.AuditOutcomeTimeout, http.StatusOK,
+				utils.APIResponse{Code: 2, Message: "查询超时，已取消执行"}, err)
 			return
 		}
 
-		log.Printf("结果扫描失败: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.APIResponse{
+		slog.ErrorContext(c.Request.Context(), "动态 SQL 结果扫描失败", "service", service.Name, "error", err)
+		finishExecution(c, audit, models.AuditOutcomeSQLError, http.StatusInternalServerError, utils.APIResponse{
 			Code:    500,
 			Message: "结果处理失败。",
 			Data:    gin.H{"detail": err.Error()},
-		})
+		}, err)
 		return
 	}
 
@@ -296,27 +504,15 @@ func ExecuteService(c *gin.Context) {
 		truncated = true
 	}
 
-	// 持久化审计记录
-	argsBytes, _ := json.Marshal(args)
-	audit := models.Audit{
-		Path:       path,
-		Method:     reqMethod,
-		ClientIP:   c.ClientIP(),
-		SQL:        service.SQL,
-		Args:       string(argsBytes),
-		DurationMs: duration.Milliseconds(),
-		Rows:       rows,
-		Truncated:  truncated,
-	}
-	if err := config.DB.Create(&audit).Error; err != nil {
-		log.Printf("AUDIT WRITE FAILED: %v", err)
-	}
+	audit.DurationMs = duration.Milliseconds()
+	audit.Rows = rows
+	audit.Truncated = truncated
 
-	// 返回查询结果（包含截断提示）
+	// 返回查询结果（包含截断提示），并持久化审计记录
 	resp := utils.APIResponse{Code: 0, Message: "查询成功", Data: results}
 	if truncated {
 		resp.Message = "查询成功（结果已被限制为最大行数）"
 		resp.Data = gin.H{"rows_returned": len(results), "truncated": true, "data": results}
 	}
-	c.JSON(http.StatusOK, resp)
+	finishExecution(c, audit, models.AuditOutcomeSuccess, http.StatusOK, resp, nil)
 }
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/middleware"
+	"go-gin-gorm-api/app/migrate"
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 在临时目录中创建 SQLite 数据库并执行全部迁移，设为全局 DB 与当前配置 (启用写服务)
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Database.Driver = config.DriverSQLite
+	cfg.Database.Name = filepath.Join(t.TempDir(), "test.db")
+	cfg.Dynamic.CommandsEnabled = true
+	config.SetCurrent(cfg)
+	t.Cleanup(func() { config.SetCurrent(config.Default()) })
+
+	db, err := config.OpenDatabase(cfg)
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if _, err := migrate.Up(context.Background(), db); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+	config.UseDatabase(db)
+	t.Cleanup(func() { config.CloseDatabase() })
+	return db
+}
+
+// execSQL 在测试数据库中执行建表、插入等准备语句
+func execSQL(t *testing.T, db *gorm.DB, statements ...string) {
+	t.Helper()
+	for _, s := range statements {
+		if err := db.Exec(s).Error; err != nil {
+			t.Fatalf("执行 %q 失败: %v", s, err)
+		}
+	}
+}
+
+// createTestService 校验并保存动态服务
+func createTestService(t *testing.T, db *gorm.DB, service *models.APIService) {
+	t.Helper()
+	if err := ValidateService(service); err != nil {
+		t.Fatalf("服务 %s 校验失败: %v", service.Name, err)
+	}
+	if err := db.Create(service).Error; err != nil {
+		t.Fatalf("保存服务 %s 失败: %v", service.Name, err)
+	}
+}
+
+// newTestRouter 返回挂载 ExecuteService 的路由。scopes 非空时模拟携带该权限范围 API Key 的调用方
+func newTestRouter(scopes string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if scopes != "" {
+			c.Set(middleware.PrincipalKey, "apikey:test")
+			c.Set(middleware.APIKeyKey, &models.APIKey{Name: "test", Scopes: scopes})
+		}
+	})
+	r.Any("/api/v1/dynamic/run/*path", ExecuteService)
+	return r
+}
+
+// testResponse 是 utils.APIResponse 的解码形式，Data 留给各测试按需解析
+type testResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// doRequest 发送请求并解码响应
+func doRequest(t *testing.T, r http.Handler, method, target, body string) (int, testResponse) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp testResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v, body: %s", err, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+// lastAudit 返回最近写入的审计记录
+func lastAudit(t *testing.T, db *gorm.DB) models.Audit {
+	t.Helper()
+	var a models.Audit
+	if err := db.Order("id DESC").First(&a).Error; err != nil {
+		t.Fatalf("读取审计记录失败: %v", err)
+	}
+	return a
+}
+
+// countRows 返回 SELECT COUNT(*) 语句的结果
+func countRows(t *testing.T, db *gorm.DB, sql string) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Raw(sql).Scan(&n).Error; err != nil {
+		t.Fatalf("执行 %q 失败: %v", sql, err)
+	}
+	return n
+}
+
+func TestValidateService(t *testing.T) {
+	setupTestDB(t)
+	base := func() *models.APIService {
+		return &models.APIService{
+			Name: "notes", Method: "get", Path: "/notes",
+			SQL: "SELECT * FROM notes WHERE id = ?", ParamKeys: `["id"]`, ParamTypes: `["int"]`,
+		}
+	}
+	tests := []struct {
+		name    string
+		modify  func(s *models.APIService)
+		wantErr string
+	}{
+		{"合法的查询服务", func(s *models.APIService) {}, ""},
+		{"缺少 SQL", func(s *models.APIService) { s.SQL = "" }, "请求参数错误或缺失"},
+		{"ParamKeys 与 ParamTypes 数量不同", func(s *models.APIService) { s.ParamTypes = `[]` }, "数量不匹配"},
+		{"占位符数量不同", func(s *models.APIService) { s.SQL = "SELECT * FROM notes" }, "占位符数量"},
+		{"未知数据源", func(s *models.APIService) { s.Datasource = "reports" }, "未知的数据源"},
+		{"名称包含换行", func(s *models.APIService) { s.Name = "notes\nfunc init() {}" }, "控制字符"},
+		{"路径包含注释结束符", func(s *models.APIService) { s.Path = "/notes*/" }, "控制字符"},
+		{"写服务不能使用 GET", func(s *models.APIService) {
+			s.Kind, s.SQL = models.ServiceKindCommand, "DELETE FROM notes WHERE id = ?"
+		}, "GET"},
+		{"写服务缺少 WHERE", func(s *models.APIService) {
+			s.Kind, s.Method, s.SQL, s.ParamKeys, s.ParamTypes = models.ServiceKindCommand, "POST", "DELETE FROM notes", "", ""
+		}, "WHERE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := base()
+			tt.modify(s)
+			err := ValidateService(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateService() error = %v", err)
+				}
+				if s.Method != http.MethodGet {
+					t.Errorf("Method = %q, want GET", s.Method)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateService() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteService(t *testing.T) {
+	db := setupTestDB(t)
+	execSQL(t, db,
+		"CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL)",
+		"INSERT INTO notes (body) VALUES ('a'), ('b'), ('c')",
+	)
+	createTestService(t, db, &models.APIService{
+		Name: "note", Method: "GET", Path: "/note",
+		SQL: "SELECT id, body FROM notes WHERE id = ?", ParamKeys: `["id"]`, ParamTypes: `["int"]`,
+	})
+	createTestService(t, db, &models.APIService{
+		Name: "search", Method: "POST", Path: "/search",
+		SQL: "SELECT body FROM notes WHERE id >= ? ORDER BY id", ParamKeys: `["min"]`, ParamTypes: `["int"]`,
+	})
+	// 绕过注册校验直接写入数据库的多语句服务，执行时必须被再次拦截
+	execSQL(t, db, "INSERT INTO api_services (name, method, path, sql, param_keys, param_types, created_at, updated_at) "+
+		"VALUES ('evil', 'GET', '/evil', 'SELECT 1; DELETE FROM notes', '[]', '[]', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)")
+
+	r := newTestRouter("")
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		wantStatus  int
+		wantCode    int
+		wantData    string
+		wantOutcome string
+	}{
+		{"GET 查询参数", http.MethodGet, "/api/v1/dynamic/run/note?id=2", "",
+			http.StatusOK, 0, `[{"body":"b","id":2}]`, models.AuditOutcomeSuccess},
+		{"POST JSON 参数", http.MethodPost, "/api/v1/dynamic/run/search", `{"min": 2}`,
+			http.StatusOK, 0, `[{"body":"b"},{"body":"c"}]`, models.AuditOutcomeSuccess},
+		{"参数类型错误", http.MethodGet, "/api/v1/dynamic/run/note?id=abc", "",
+			http.StatusBadRequest, 400, "", models.AuditOutcomeBadRequest},
+		{"缺少参数", http.MethodGet, "/api/v1/dynamic/run/note", "",
+			http.StatusBadRequest, 400, "", models.AuditOutcomeBadRequest},
+		{"未注册的服务", http.MethodGet, "/api/v1/dynamic/run/missing", "",
+			http.StatusNotFound, 404, "", models.AuditOutcomeRejected},
+		{"方法不匹配", http.MethodPost, "/api/v1/dynamic/run/note", `{"id": 1}`,
+			http.StatusNotFound, 404, "", models.AuditOutcomeRejected},
+		{"多条语句被拦截", http.MethodGet, "/api/v1/dynamic/run/evil", "",
+			http.StatusOK, 1, "", models.AuditOutcomeBlocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := doRequest(t, r, tt.method, tt.target, tt.body)
+			if status != tt.wantStatus || resp.Code != tt.wantCode {
+				t.Fatalf("status = %d, code = %d (%s); want %d, %d", status, resp.Code, resp.Message, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantData != "" && string(resp.Data) != tt.wantData {
+				t.Errorf("data = %s, want %s", resp.Data, tt.wantData)
+			}
+			if a := lastAudit(t, db); a.Outcome != tt.wantOutcome || a.HTTPStatus != tt.wantStatus {
+				t.Errorf("audit outcome = %s, status = %d; want %s, %d", a.Outcome, a.HTTPStatus, tt.wantOutcome, tt.wantStatus)
+			}
+		})
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM notes"); n != 3 {
+		t.Errorf("notes 行数 = %d, want 3 (被拦截的语句不能执行)", n)
+	}
+}
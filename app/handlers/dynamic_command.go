@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/middleware"
+	"go-gin-gorm-api/app/models"
+	"go-gin-gorm-api/app/tracing"
+	"go-gin-gorm-api/app/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"gorm.io/gorm"
+)
+
+// commandVerbs 是写服务 (kind=command) 允许执行的语句
+var commandVerbs = map[string]bool{"INSERT": true, "UPDATE": true, "DELETE": true}
+
+// conditionKeywords 是 WHERE 条件中不构成真实过滤条件的关键字，
+// 只由这些关键字与常量组成的条件 (例如 WHERE 1 = 1、WHERE TRUE) 视为缺少 WHERE。
+// 这只是尽力而为的启发式检查，见 checkCommand
+var conditionKeywords = map[string]bool{"AND": true, "OR": true, "NOT": true, "TRUE": true, "FALSE": true, "NULL": true, "IS": true}
+
+// CommandResult 是写服务的执行结果。LastInsertID 仅在 MySQL 与 SQLite 的 INSERT 中返回；
+// 语句带 RETURNING 子句 (PostgreSQL、SQLite) 时返回的行在 Returning 中
+type CommandResult struct {
+	RowsAffected int64                    `json:"rows_affected"`
+	LastInsertID *int64                   `json:"last_insert_id,omitempty"`
+	Returning    []map[string]interface{} `json:"returning,omitempty"`
+}
+
+// commandStatement 是 checkCommand 解析出的语句信息
+type commandStatement struct {
+	Verb      string
+	Returning bool
+}
+
+// affectedRowsError 表示写服务影响的行数超过上限，事务已回滚
+type affectedRowsError struct {
+	Affected, Max int64
+}
+
+func (e *affectedRowsError) Error() string {
+	return fmt.Sprintf("影响行数 %d 超过上限 %d，事务已回滚", e.Affected, e.Max)
+}
+
+// checkCommand 检查写服务的 SQL: 必须是单条 INSERT、UPDATE 或 DELETE 语句；
+// UPDATE 与 DELETE 必须带顶层 WHERE 子句，且条件中至少出现一个非关键字的标识符。
+// 条件检查只是尽力而为的启发式: 可以拦截 WHERE 1 = 1、WHERE TRUE 等明显的恒真条件，
+// 但无法识别 WHERE id = id、WHERE 1 = 1 OR x 之类仍会匹配全表的写法。
+// 真正限制影响范围的是执行时的 max_affected_rows 检查 (超出时回滚)。
+// MySQL 可执行注释中的分号与条件同样参与检查 (见 tokenizeSQL)
+func checkCommand(sql, driver string) (*commandStatement, error) {
+	tokens := tokenizeSQL(sql, driver)
+	// 允许末尾的分号，不允许多条语句
+	for len(tokens) > 0 && tokens[len(tokens)-1].kind == tokenPunct && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 || tokens[0].kind != tokenWord || !commandVerbs[strings.ToUpper(tokens[0].text)] {
+		return nil, errors.New("写服务只允许执行单条 INSERT、UPDATE 或 DELETE 语句")
+	}
+	stmt := &commandStatement{Verb: strings.ToUpper(tokens[0].text)}
+
+	depth, where := 0, -1
+	for i, t := range tokens {
+		switch {
+		case t.kind == tokenPunct && t.text == ";":
+			return nil, errors.New("写服务只允许执行单条语句")
+		case t.kind == tokenPunct && t.text == "(":
+			depth++
+		case t.kind == tokenPunct && t.text == ")":
+			depth--
+		case t.kind == tokenWord && depth == 0:
+			switch strings.ToUpper(t.text) {
+			case "WHERE":
+				if where < 0 {
+					where = i
+				}
+			case "RETURNING":
+				stmt.Returning = true
+			}
+		}
+	}
+	if stmt.Verb == "INSERT" {
+		return stmt, nil
+	}
+
+	if where < 0 {
+		return nil, fmt.Errorf("%s 语句必须带 WHERE 条件", stmt.Verb)
+	}
+	depth = 0
+	for _, t := range tokens[where+1:] {
+		if t.kind == tokenPunct {
+			if t.text == "(" {
+				depth++
+			} else if t.text == ")" {
+				depth--
+			}
+			continue
+		}
+		word := strings.ToUpper(t.text)
+		if depth == 0 && (word == "ORDER" || word == "LIMIT" || word == "RETURNING") {
+			break
+		}
+		if t.kind == tokenWord && !conditionKeywords[word] {
+			return stmt, nil
+		}
+	}
+	return nil, fmt.Errorf("%s 语句的 WHERE 条件未引用任何列，疑似恒真条件 (启发式检查，影响行数仍受 max_affected_rows 限制)", stmt.Verb)
+}
+
+// commandPermission 检查是否允许执行写服务: 需启用 dynamic.commands_enabled，
+// 且调用方携带同时拥有 run 与 command 权限范围的 API Key。允许时返回 http.StatusOK
+func commandPermission(c *gin.Context) (int, utils.APIResponse) {
+	if !config.Current().Dynamic.CommandsEnabled {
+		return http.StatusForbidden, utils.APIResponse{Code: 403, Message: "写服务未启用 (dynamic.commands_enabled)"}
+	}
+	if c.GetString(middleware.PrincipalKey) == "" {
+		return http.StatusUnauthorized, utils.APIResponse{Code: 401, Message: "写服务需要携带 command 权限范围的 API Key"}
+	}
+	if !middleware.HasScope(c, models.ScopeCommand) {
+		return http.StatusForbidden, utils.APIResponse{Code: 403, Message: "API Key 缺少权限范围: " + models.ScopeCommand}
+	}
+	return http.StatusOK, utils.APIResponse{}
+}
+
+// maxAffectedRows 返回服务单次执行允许影响的最大行数
+func maxAffectedRows(service *models.APIService) int64 {
+	if service.MaxAffectedRows > 0 {
+		return int64(service.MaxAffectedRows)
+	}
+	return int64(config.Current().Dynamic.CommandMaxAffectedRows)
+}
+
+// runCommand 在事务 tx 中执行写语句，返回影响行数、最后插入的 ID 与 RETURNING 返回的行
+func runCommand(tx *gorm.DB, driver string, stmt *commandStatement, sqlText string, args []interface{}) (*CommandResult, error) {
+	result := &CommandResult{}
+	if stmt.Returning {
+		if err := tx.Raw(sqlText, args...).Find(&result.Returning).Error; err != nil {
+			return nil, err
+		}
+		result.RowsAffected = int64(len(result.Returning))
+		return result, nil
+	}
+
+	res := tx.Exec(sqlText, args...)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	result.RowsAffected = res.RowsAffected
+	if stmt.Verb != "INSERT" || result.RowsAffected == 0 {
+		return result, nil
+	}
+	// 事务固定使用同一连接，可以读取本次插入的 ID；PostgreSQL 请使用 RETURNING
+	var lastIDQuery string
+	switch driver {
+	case config.DriverMySQL:
+		lastIDQuery = "SELECT LAST_INSERT_ID()"
+	case config.DriverSQLite:
+		lastIDQuery = "SELECT last_insert_rowid()"
+	default:
+		return result, nil
+	}
+	var id int64
+	if err := tx.Raw(lastIDQuery).Scan(&id).Error; err != nil {
+		return nil, err
+	}
+	result.LastInsertID = &id
+	return result, nil
+}
+
+// executeCommand 在主库的事务中执行写服务，影响行数超过上限时回滚，并写入审计记录。
+// 写操作不路由到只读副本，也不受 requires_fresh_data 影响
+func executeCommand(c *gin.Context, rec *models.Audit, service *models.APIService, stmt *commandStatement, sqlText string, args []interface{}) {
+	driver := config.DatasourceDriver(service.Datasource)
+	limits := config.Current().Dynamic
+	max := maxAffectedRows(service)
+
+	target, err := config.Datasource(service.Datasource)
+	if err != nil {
+		datasourceUnavailable(c, rec, service, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), limits.QueryTimeout)
+	defer cancel()
+	ctx, span := tracing.Tracer().Start(ctx, "dynamic.execute_command")
+	span.SetAttributes(attribute.String("dynamic.datasource", datasourceName(service.Datasource)),
+		attribute.String("dynamic.command", stmt.Verb))
+	start := time.Now()
+
+	var result *CommandResult
+	err = target.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if result, err = runCommand(tx, driver, stmt, sqlText, args); err != nil {
+			return err
+		}
+		if result.RowsAffected > max {
+			return &affectedRowsError{Affected: result.RowsAffected, Max: max}
+		}
+		return nil
+	}, txOptions(service))
+	rec.DurationMs = time.Since(start).Milliseconds()
+	if result != nil {
+		rec.Rows = int(result.RowsAffected)
+		span.SetAttributes(attribute.Int64("db.rows_affected", result.RowsAffected))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	var tooMany *affectedRowsError
+	switch {
+	case err == nil:
+		slog.InfoContext(c.Request.Context(), "写服务执行成功", "service", service.Name, "rows_affected", result.RowsAffected)
+		finishExecution(c, rec, models.AuditOutcomeSuccess, http.StatusOK, utils.APIResponse{Code: 0, Message: "执行成功", Data: result}, nil)
+	case errors.As(err, &tooMany):
+		slog.WarnContext(c.Request.Context(), "写服务影响行数超过上限，已回滚", "service", service.Name,
+			"rows_affected", tooMany.Affected, "max_affected_rows", tooMany.Max)
+		finishExecution(c, rec, models.AuditOutcomeRolledBack, http.StatusUnprocessableEntity, utils.APIResponse{
+			Code:    3,
+			Message: err.Error(),
+			Data:    gin.H{"rows_affected": tooMany.Affected, "max_affected_rows": tooMany.Max},
+		}, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.WarnContext(c.Request.Context(), "写服务执行超时，已回滚", "service", service.Name, "timeout", limits.QueryTimeout.String(), "error", err)
+		finishExecution(c, rec, models.AuditOutcomeTimeout, http.StatusOK,
+			utils.APIResponse{Code: 2, Message: "执行超时，已取消并回滚"}, err)
+	default:
+		slog.ErrorContext(c.Request.Context(), "写服务执行失败", "service", service.Name, "error", err)
+		finishExecution(c, rec, models.AuditOutcomeSQLError, http.StatusInternalServerError, utils.APIResponse{
+			Code:    500,
+			Message: "SQL 执行失败，事务已回滚。",
+			Data:    gin.H{"detail": err.Error()},
+		}, err)
+	}
+}
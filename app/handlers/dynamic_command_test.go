@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/models"
+)
+
+func TestCheckCommand(t *testing.T) {
+	tests := []struct {
+		sql           string
+		driver        string
+		wantVerb      string
+		wantReturning bool
+		wantErr       bool
+	}{
+		{"INSERT INTO notes (body) VALUES (?)", config.DriverSQLite, "INSERT", false, false},
+		{"insert into notes (body) values (?) returning id;", config.DriverSQLite, "INSERT", true, false},
+		{"UPDATE notes SET body = ? WHERE id = ?", config.DriverSQLite, "UPDATE", false, false},
+		{"DELETE FROM notes WHERE id IN (SELECT id FROM old) RETURNING id", config.DriverSQLite, "DELETE", true, false},
+		{"UPDATE notes SET body = ? WHERE id = ? ORDER BY id LIMIT 1", config.DriverSQLite, "UPDATE", false, false},
+		// 子查询中的 RETURNING 与 WHERE 不属于顶层语句
+		{"INSERT INTO a SELECT * FROM (SELECT 1 WHERE 1 = 1)", config.DriverSQLite, "INSERT", false, false},
+
+		{"SELECT * FROM notes", config.DriverSQLite, "", false, true},
+		{"DROP TABLE notes", config.DriverSQLite, "", false, true},
+		{"", config.DriverSQLite, "", false, true},
+		{"DELETE FROM notes", config.DriverSQLite, "", false, true},
+		{"UPDATE notes SET body = ?", config.DriverSQLite, "", false, true},
+		{"DELETE FROM notes WHERE 1 = 1", config.DriverSQLite, "", false, true},
+		{"DELETE FROM notes WHERE TRUE", config.DriverSQLite, "", false, true},
+		{"DELETE FROM notes WHERE NOT FALSE OR NULL IS NULL", config.DriverSQLite, "", false, true},
+		{"UPDATE notes SET a = (SELECT b FROM c WHERE d = 1)", config.DriverSQLite, "", false, true},
+		{"DELETE FROM notes WHERE id = ?; DELETE FROM users WHERE id = ?", config.DriverSQLite, "", false, true},
+		{"DELETE FROM notes WHERE id = 1; SELECT 1", config.DriverSQLite, "", false, true},
+
+		// MySQL 会执行可执行注释中的内容，其他方言中只是注释
+		{"DELETE FROM notes WHERE id = ? /*!; DROP TABLE users */", config.DriverMySQL, "", false, true},
+		{"DELETE FROM notes WHERE id = ? /*!; DROP TABLE users */", config.DriverSQLite, "DELETE", false, false},
+		{"DELETE FROM notes /*! WHERE 1 = 1 */", config.DriverMySQL, "", false, true},
+		{"UPDATE /*+ NO_MERGE(n) */ notes n SET body = ? WHERE id = ?", config.DriverMySQL, "UPDATE", false, false},
+		{"DELETE FROM notes WHERE id = ? # ; DROP TABLE users", config.DriverMySQL, "DELETE", false, false},
+	}
+	for _, tt := range tests {
+		stmt, err := checkCommand(tt.sql, tt.driver)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkCommand(%q, %s) error = %v, wantErr %v", tt.sql, tt.driver, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (stmt.Verb != tt.wantVerb || stmt.Returning != tt.wantReturning) {
+			t.Errorf("checkCommand(%q, %s) = %+v, want verb %s, returning %v", tt.sql, tt.driver, stmt, tt.wantVerb, tt.wantReturning)
+		}
+	}
+}
+
+func TestExecuteCommand(t *testing.T) {
+	db := setupTestDB(t)
+	execSQL(t, db,
+		"CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL, archived INTEGER NOT NULL DEFAULT 0)",
+		"INSERT INTO notes (body) VALUES ('a'), ('b'), ('c')",
+	)
+	createTestService(t, db, &models.APIService{
+		Name: "add_note", Method: "POST", Path: "/notes/add", Kind: models.ServiceKindCommand,
+		SQL: "INSERT INTO notes (body) VALUES (?)", ParamKeys: `["body"]`, ParamTypes: `["string"]`,
+	})
+	createTestService(t, db, &models.APIService{
+		Name: "archive_notes", Method: "POST", Path: "/notes/archive", Kind: models.ServiceKindCommand, MaxAffectedRows: 2,
+		SQL: "UPDATE notes SET archived = 1 WHERE id >= ?", ParamKeys: `["min"]`, ParamTypes: `["int"]`,
+	})
+
+	t.Run("需要 API Key", func(t *testing.T) {
+		status, resp := doRequest(t, newTestRouter(""), http.MethodPost, "/api/v1/dynamic/run/notes/add", `{"body": "d"}`)
+		if status != http.StatusUnauthorized || resp.Code != 401 {
+			t.Fatalf("status = %d, code = %d; want 401", status, resp.Code)
+		}
+	})
+
+	t.Run("需要 command 权限范围", func(t *testing.T) {
+		status, resp := doRequest(t, newTestRouter(models.ScopeRun), http.MethodPost, "/api/v1/dynamic/run/notes/add", `{"body": "d"}`)
+		if status != http.StatusForbidden || resp.Code != 403 {
+			t.Fatalf("status = %d, code = %d; want 403", status, resp.Code)
+		}
+	})
+
+	r := newTestRouter(models.ScopeRun + "," + models.ScopeCommand)
+
+	t.Run("INSERT 返回 last_insert_id", func(t *testing.T) {
+		status, resp := doRequest(t, r, http.MethodPost, "/api/v1/dynamic/run/notes/add", `{"body": "d"}`)
+		if status != http.StatusOK || resp.Code != 0 {
+			t.Fatalf("status = %d, code = %d (%s); want 200, 0", status, resp.Code, resp.Message)
+		}
+		var result CommandResult
+		if err := json.Unmarshal(resp.Data, &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.RowsAffected != 1 || result.LastInsertID == nil || *result.LastInsertID != 4 {
+			t.Errorf("result = %+v, want 1 row and last_insert_id 4", result)
+		}
+		if a := lastAudit(t, db); a.Outcome != models.AuditOutcomeSuccess || a.Rows != 1 {
+			t.Errorf("audit outcome = %s, rows = %d", a.Outcome, a.Rows)
+		}
+	})
+
+	t.Run("超过 max_affected_rows 时回滚", func(t *testing.T) {
+		status, resp := doRequest(t, r, http.MethodPost, "/api/v1/dynamic/run/notes/archive", `{"min": 1}`)
+		if status != http.StatusUnprocessableEntity || resp.Code != 3 {
+			t.Fatalf("status = %d, code = %d (%s); want 422, 3", status, resp.Code, resp.Message)
+		}
+		if n := countRows(t, db, "SELECT COUNT(*) FROM notes WHERE archived = 1"); n != 0 {
+			t.Errorf("archived = %d, want 0 (事务应已回滚)", n)
+		}
+		if a := lastAudit(t, db); a.Outcome != models.AuditOutcomeRolledBack {
+			t.Errorf("audit outcome = %s, want %s", a.Outcome, models.AuditOutcomeRolledBack)
+		}
+	})
+
+	t.Run("未超过上限时提交", func(t *testing.T) {
+		status, resp := doRequest(t, r, http.MethodPost, "/api/v1/dynamic/run/notes/archive", `{"min": 3}`)
+		if status != http.StatusOK || resp.Code != 0 {
+			t.Fatalf("status = %d, code = %d (%s); want 200, 0", status, resp.Code, resp.Message)
+		}
+		if n := countRows(t, db, "SELECT COUNT(*) FROM notes WHERE archived = 1"); n != 2 {
+			t.Errorf("archived = %d, want 2", n)
+		}
+	})
+
+	t.Run("未启用写服务", func(t *testing.T) {
+		cfg := *config.Current()
+		cfg.Dynamic.CommandsEnabled = false
+		config.SetCurrent(&cfg)
+		defer func() {
+			cfg.Dynamic.CommandsEnabled = true
+			config.SetCurrent(&cfg)
+		}()
+		status, _ := doRequest(t, r, http.MethodPost, "/api/v1/dynamic/run/notes/add", `{"body": "e"}`)
+		if status != http.StatusForbidden {
+			t.Fatalf("status = %d, want 403", status)
+		}
+	})
+}
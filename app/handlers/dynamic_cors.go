@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/models"
+)
+
+// DynamicRunPrefix 是动态服务执行路由的路径前缀
+const DynamicRunPrefix = "/api/v1/dynamic/run"
+
+// parseAllowedOrigins 解析并校验 APIService.AllowedOrigins (JSON 数组字符串)。
+// 服务级来源不允许使用 "*"，任意来源应通过全局 cors.run 策略配置。
+func parseAllowedOrigins(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var origins []string
+	if err := json.Unmarshal([]byte(raw), &origins); err != nil {
+		return nil, errors.New("非 JSON 字符串数组")
+	}
+	for _, o := range origins {
+		if o == "*" {
+			return nil, errors.New("服务级来源不能为 \"*\"")
+		}
+		if err := config.ValidateOrigin(o); err != nil {
+			return nil, err
+		}
+	}
+	return origins, nil
+}
+
+// ServiceAllowsOrigin 判断当前请求对应的动态服务是否在其 AllowedOrigins 中允许 origin。
+// 预检请求 (OPTIONS) 按 Access-Control-Request-Method 指定的方法查找服务。
+func ServiceAllowsOrigin(c *gin.Context, origin string) bool {
+	if !config.MigrationsCompleted() {
+		return false
+	}
+	path := strings.TrimPrefix(c.Request.URL.Path, DynamicRunPrefix)
+	method := c.Request.Method
+	if method == http.MethodOptions {
+		method = strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))
+	}
+
+	var service models.APIService
+	err := config.DB.WithContext(c.Request.Context()).Select("allowed_origins").
+		Where("method = ? AND path = ?", method, path).Limit(1).Find(&service).Error
+	if err != nil {
+		return false
+	}
+	origins, _ := parseAllowedOrigins(service.AllowedOrigins)
+	for _, pattern := range origins {
+		if config.OriginMatches(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
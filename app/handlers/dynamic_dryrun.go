@@ -0,0 +1,469 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/middleware"
+	"go-gin-gorm-api/app/models"
+	"go-gin-gorm-api/app/utils"
+	"gorm.io/gorm"
+)
+
+// 试运行的返回行数限制: 默认 dryRunDefaultRows 行，请求最多可指定 dryRunMaxRows 行
+const (
+	dryRunDefaultRows = 10
+	dryRunMaxRows     = 100
+)
+
+// maxDryRunRequestSize 是试运行接口允许的请求体大小上限
+const maxDryRunRequestSize = 1 << 20
+
+// errReadOnlyRollback 用于在只读执行结束后回滚事务
+var errReadOnlyRollback = errors.New("read-only rollback")
+
+// errDryRunRollback 用于在写服务试运行结束后回滚事务
+var errDryRunRollback = errors.New("dry-run rollback")
+
+// readOnlyRollback 在 db 上开启只读事务执行 fn，结束后始终回滚，返回 fn 或事务本身的错误。
+// SQLite 驱动不强制只读事务，此时使用 PRAGMA query_only 在连接上禁止写入
+func readOnlyRollback(db *gorm.DB, driver string, fn func(tx *gorm.DB) error) error {
+	var fnErr error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if driver == config.DriverSQLite {
+			if err := tx.Exec("PRAGMA query_only = ON").Error; err != nil {
+				return err
+			}
+			// 连接会归还连接池，超时取消后也必须恢复
+			defer tx.WithContext(context.Background()).Exec("PRAGMA query_only = OFF")
+		}
+		fnErr = fn(tx)
+		return errReadOnlyRollback
+	}, &sql.TxOptions{ReadOnly: true})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil && !errors.Is(err, errReadOnlyRollback) {
+		return err
+	}
+	return nil
+}
+
+// writesExecutedWarning 是写语句在试运行中真实执行时附带的警告
+const writesExecutedWarning = "写语句已在事务中真实执行后回滚: 触发器的外部副作用、自增序列 (AUTO_INCREMENT) 的消耗、" +
+	"非事务表 (如 MyISAM) 的修改不会撤销，执行期间持有的行锁会阻塞其他写入"
+
+// alwaysRollback 在 db 上开启读写事务执行 fn，结束后始终回滚，返回 fn 或事务本身的错误。
+// 用于试运行写服务: 只获取执行计划，或在 execute_writes 时真实执行语句以得到影响行数，但不会提交
+func alwaysRollback(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	var fnErr error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		fnErr = fn(tx)
+		return errDryRunRollback
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil && !errors.Is(err, errDryRunRollback) {
+		return err
+	}
+	return nil
+}
+
+// DryRunRequest 是试运行请求: 提供未保存的服务定义 (service)，或已注册服务（通常为草稿）的名称 (name)。
+// ExecuteWrites 为 true 时写服务与包含写步骤的流水线会真实执行写语句后回滚，否则写服务只返回执行计划
+type DryRunRequest struct {
+	Service       *models.APIService     `json:"service"`
+	Name          string                 `json:"name"`
+	Params        map[string]interface{} `json:"params"`
+	MaxRows       int                    `json:"max_rows"`
+	ExecuteWrites bool                   `json:"execute_writes"`
+}
+
+// DryRunResult 是试运行结果
+type DryRunResult struct {
+	Service    *models.APIService       `json:"service"`
+	SQL        string                   `json:"sql"`
+	Args       []interface{}            `json:"args"`
+	Rows       []map[string]interface{} `json:"rows"`
+	RowCount   int                      `json:"row_count"`
+	Truncated  bool                     `json:"truncated"`
+	DurationMs int64                    `json:"duration_ms"`
+	Explain    []map[string]interface{} `json:"explain,omitempty"`
+	Command    *CommandResult           `json:"command,omitempty"`
+	Steps      []*StepResult            `json:"steps,omitempty"`
+	// WritesExecuted 表示写语句已真实执行 (随后回滚)
+	WritesExecuted bool     `json:"writes_executed"`
+	Warnings       []string `json:"warnings"`
+}
+
+// DryRunService 试运行动态服务: 执行与 ExecuteService 相同的定义校验、只读检查与参数转换，
+// 在只读事务中执行 SQL（最多返回 max_rows 行，执行后回滚）并附带 EXPLAIN 执行计划与警告。
+// 写服务 (kind=command) 默认只返回 EXPLAIN 执行计划而不执行语句；请求设置 execute_writes 时在读写事务中
+// 真实执行以返回影响行数，执行后回滚 (触发器、自增序列等副作用不会撤销)。流水线服务返回每个步骤的结果。
+// 试运行不写入审计表，也不计入动态服务指标，便于在发布前用样例参数验证服务。
+// POST /api/v1/admin/services/test
+func DryRunService(c *gin.Context) {
+	// 不使用 ShouldBindJSON: 未保存的定义允许省略必填字段，由下方补全后再统一校验
+	var req DryRunRequest
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDryRunRequestSize)
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, utils.APIResponse{Code: 413, Message: "请求体超过大小上限"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "请求参数错误", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+	if (req.Service == nil) == (req.Name == "") {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "必须且只能提供 service（未保存的定义）或 name（已注册的服务）之一"})
+		return
+	}
+	if req.MaxRows <= 0 {
+		req.MaxRows = dryRunDefaultRows
+	}
+	if req.MaxRows > dryRunMaxRows {
+		req.MaxRows = dryRunMaxRows
+	}
+
+	// 1. 取得并校验服务定义。未保存的定义可以省略名称与路径
+	service := req.Service
+	if service == nil {
+		service = &models.APIService{}
+		if err := config.DB.WithContext(c.Request.Context()).Where("name = ?", req.Name).First(service).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, utils.APIResponse{Code: 404, Message: fmt.Sprintf("动态服务 %s 不存在", req.Name)})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "查询服务配置失败", Data: gin.H{"detail": err.Error()}})
+			return
+		}
+	} else {
+		if service.Name == "" {
+			service.Name = "dry-run"
+		}
+		if service.Path == "" {
+			service.Path = "/dry-run"
+		}
+		if service.Method == "" {
+			service.Method = http.MethodGet
+			if service.Kind == models.ServiceKindCommand {
+				service.Method = http.MethodPost
+			}
+		}
+	}
+	if err := ValidateService(service); err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: err.Error()})
+		return
+	}
+
+	if service.Kind == models.ServiceKindPipeline {
+		dryRunPipeline(c, &req, service)
+		return
+	}
+
+	driver := config.DatasourceDriver(service.Datasource)
+	var command *commandStatement
+	if service.Kind == models.ServiceKindCommand {
+		// ValidateService 已检查过写服务的语句
+		command, _ = checkCommand(service.SQL, driver)
+	} else if !isAllowedQuery(service.SQL, driver) {
+		c.JSON(http.StatusUnprocessableEntity, utils.APIResponse{
+			Code:    1,
+			Message: fmt.Sprintf("安全限制: 动态服务只允许执行 %v 查询操作，该服务发布后会被拦截。", allowedQueryPrefixes(driver)),
+		})
+		return
+	}
+
+	// 2. 参数转换，与 ExecuteService 相同
+	def := bundleServiceFromModel(service)
+	paramKeys, paramTypes := def.ParamKeys, def.ParamTypes
+	if req.Params == nil {
+		req.Params = map[string]interface{}{}
+	}
+	args, unknownTypes, perr := convertParams(paramKeys, paramTypes, req.Params)
+	if perr != nil {
+		resp := utils.APIResponse{Code: 400, Message: perr.Error()}
+		if perr.Err != nil {
+			resp.Data = gin.H{"error": perr.Err.Error()}
+		}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	sqlText, args, err := bindPlaceholders(service.SQL, driver, args)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "SQL 占位符与参数不匹配", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+
+	result := &DryRunResult{Service: service, SQL: sqlText, Args: args, Rows: []map[string]interface{}{}, Warnings: []string{}}
+	result.Warnings = append(result.Warnings, dryRunWarnings(service, paramKeys, req.Params, unknownTypes)...)
+
+	// 3. 在只读事务中执行，结束后始终回滚；写服务在主库的读写事务中获取执行计划 (execute_writes 时执行语句) 后回滚
+	var target *gorm.DB
+	if command != nil {
+		target, err = config.Datasource(service.Datasource)
+	} else {
+		target, err = config.ReadDatasource(service.Datasource, service.RequiresFreshData)
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, utils.APIResponse{Code: 503, Message: "数据源不可用", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+	limits := config.Current()
+	ctx, cancel := context.WithTimeout(c.Request.Context(), limits.Dynamic.QueryTimeout)
+	defer cancel()
+
+	slog.InfoContext(c.Request.Context(), "试运行动态服务", "service", service.Name, "sql", service.SQL,
+		"principal", c.GetString(middleware.PrincipalKey))
+
+	var execErr error
+	if command != nil {
+		execErr = alwaysRollback(target.WithContext(ctx), func(tx *gorm.DB) error {
+			// 先获取执行计划，EXPLAIN 不会执行语句
+			if explain, err := explainQuery(tx, driver, sqlText, args); err != nil {
+				result.Warnings = append(result.Warnings, "获取执行计划失败: "+err.Error())
+			} else {
+				result.Explain = explain
+			}
+			if !req.ExecuteWrites {
+				result.Warnings = append(result.Warnings, "写语句未执行，仅返回执行计划；设置 execute_writes 可真实执行后回滚以得到影响行数")
+				return nil
+			}
+			result.WritesExecuted = true
+			result.Warnings = append(result.Warnings, writesExecutedWarning)
+			start := time.Now()
+			cmd, err := runCommand(tx, driver, command, sqlText, args)
+			result.DurationMs = time.Since(start).Milliseconds()
+			if err != nil {
+				return err
+			}
+			result.Command = cmd
+			if len(cmd.Returning) > req.MaxRows {
+				result.Rows, result.Truncated = cmd.Returning[:req.MaxRows], true
+			} else if cmd.Returning != nil {
+				result.Rows = cmd.Returning
+			}
+			result.RowCount = len(result.Rows)
+			if max := maxAffectedRows(service); cmd.RowsAffected > max {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("影响行数 %d 超过上限 %d，发布后执行将被回滚", cmd.RowsAffected, max))
+			}
+			return nil
+		})
+	} else {
+		execErr = readOnlyRollback(target.WithContext(ctx), driver, func(tx *gorm.DB) error {
+			start := time.Now()
+			err := scanLimited(tx, sqlText, args, req.MaxRows, result)
+			result.DurationMs = time.Since(start).Milliseconds()
+			if err != nil {
+				return err
+			}
+
+			if explain, err := explainQuery(tx, driver, sqlText, args); err != nil {
+				result.Warnings = append(result.Warnings, "获取执行计划失败: "+err.Error())
+			} else {
+				result.Explain = explain
+			}
+			return nil
+		})
+	}
+
+	if execErr != nil {
+		// 与 ExecuteService 一致，超时返回业务码 2；SQL 错误属于服务定义问题，返回 422
+		if errors.Is(execErr, context.DeadlineExceeded) {
+			c.JSON(http.StatusOK, utils.APIResponse{Code: 2, Message: "查询超时，已取消执行", Data: result})
+			return
+		}
+		c.JSON(http.StatusUnprocessableEntity, utils.APIResponse{
+			Code:    422,
+			Message: "SQL 执行失败，请检查 SQL 语句、ParamKeys 和 ParamTypes 配置。",
+			Data:    gin.H{"detail": execErr.Error(), "result": result},
+		})
+		return
+	}
+
+	if result.Truncated {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("结果超过试运行行数限制 %d，仅返回前 %d 行", req.MaxRows, req.MaxRows))
+	}
+	if threshold := limits.Logging.SlowQueryThreshold; threshold > 0 && time.Duration(result.DurationMs)*time.Millisecond >= threshold {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("查询耗时 %dms，超过慢查询阈值 %s", result.DurationMs, threshold))
+	}
+	c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: dryRunMessage(result), Data: result})
+}
+
+// dryRunMessage 返回试运行成功的提示，写语句真实执行过时明确说明
+func dryRunMessage(result *DryRunResult) string {
+	if result.WritesExecuted {
+		return "试运行成功（未写入审计记录，写语句已真实执行，事务已回滚）"
+	}
+	return "试运行成功（未写入审计记录，事务已回滚）"
+}
+
+// dryRunPipeline 试运行流水线服务: 在事务中按顺序执行全部步骤（每个查询步骤最多返回 max_rows 行）后回滚。
+// 只读流水线在只读事务中执行；包含写步骤的流水线必须设置 execute_writes，写步骤会真实执行后回滚。
+// 试运行使用数据库默认的隔离级别
+func dryRunPipeline(c *gin.Context, req *DryRunRequest, service *models.APIService) {
+	// ValidateService 已检查过步骤
+	steps, _ := parsePipeline(service)
+	writes := pipelineWrites(steps)
+	if writes && !req.ExecuteWrites {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{
+			Code:    400,
+			Message: "流水线包含写步骤，写步骤无法只获取执行计划；设置 execute_writes 以真实执行后回滚",
+		})
+		return
+	}
+	def := bundleServiceFromModel(service)
+	args, unknownTypes, perr := convertParams(def.ParamKeys, def.ParamTypes, req.Params)
+	if perr != nil {
+		resp := utils.APIResponse{Code: 400, Message: perr.Error()}
+		if perr.Err != nil {
+			resp.Data = gin.H{"error": perr.Err.Error()}
+		}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	result := &DryRunResult{Service: service, SQL: pipelineSQL(steps), Args: args, Rows: []map[string]interface{}{}, Warnings: []string{}}
+	result.Warnings = append(result.Warnings, dryRunWarnings(service, def.ParamKeys, req.Params, unknownTypes)...)
+
+	target, err := pipelineTarget(service, steps)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, utils.APIResponse{Code: 503, Message: "数据源不可用", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), config.Current().Dynamic.QueryTimeout)
+	defer cancel()
+
+	slog.InfoContext(c.Request.Context(), "试运行流水线服务", "service", service.Name, "steps", len(steps),
+		"principal", c.GetString(middleware.PrincipalKey))
+
+	run := func(tx *gorm.DB) error {
+		var err error
+		result.Steps, err = runPipeline(tx, service, steps, pipelineParams(def.ParamKeys, args), req.MaxRows)
+		return err
+	}
+	start := time.Now()
+	var execErr error
+	if writes {
+		result.WritesExecuted = true
+		result.Warnings = append(result.Warnings, writesExecutedWarning)
+		execErr = alwaysRollback(target.WithContext(ctx), run)
+	} else {
+		execErr = readOnlyRollback(target.WithContext(ctx), config.DatasourceDriver(service.Datasource), run)
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	if execErr != nil {
+		if errors.Is(execErr, context.DeadlineExceeded) {
+			c.JSON(http.StatusOK, utils.APIResponse{Code: 2, Message: "执行超时，已取消执行", Data: result})
+			return
+		}
+		// 返回失败前已执行步骤的结果，便于定位问题
+		data := gin.H{"detail": execErr.Error(), "result": result}
+		var failed *stepError
+		if errors.As(execErr, &failed) {
+			data["step"] = failed.Step
+		}
+		c.JSON(http.StatusUnprocessableEntity, utils.APIResponse{Code: 422, Message: "流水线执行失败，发布后执行将整体回滚。", Data: data})
+		return
+	}
+	for _, step := range result.Steps {
+		if step.Truncated {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("步骤 %s 的结果超过试运行行数限制 %d，仅返回前 %d 行", step.Name, req.MaxRows, req.MaxRows))
+		}
+	}
+	c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: dryRunMessage(result), Data: result})
+}
+
+// scanLimited 执行查询并最多读取 maxRows 行到 result，多出的行只用于判断是否截断，不会被全部读取
+func scanLimited(tx *gorm.DB, sqlText string, args []interface{}, maxRows int, result *DryRunResult) error {
+	rows, err := tx.Raw(sqlText, args...).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if len(result.Rows) == maxRows {
+			result.Truncated = true
+			break
+		}
+		row := map[string]interface{}{}
+		if err := tx.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	result.RowCount = len(result.Rows)
+	return rows.Err()
+}
+
+// explainQuery 按方言获取查询的执行计划 (不会真正执行语句): MySQL 与 PostgreSQL 使用 EXPLAIN，
+// SQLite 使用 EXPLAIN QUERY PLAN。语句本身是 EXPLAIN/DESCRIBE 时不再获取
+func explainQuery(tx *gorm.DB, driver, sqlText string, args []interface{}) ([]map[string]interface{}, error) {
+	upper := strings.ToUpper(strings.TrimSpace(sqlText))
+	if strings.HasPrefix(upper, "EXPLAIN") || strings.HasPrefix(upper, "DESC") {
+		return nil, nil
+	}
+	prefix := "EXPLAIN "
+	if driver == config.DriverSQLite {
+		prefix = "EXPLAIN QUERY PLAN "
+	}
+	var plan []map[string]interface{}
+	if err := tx.Raw(prefix+sqlText, args...).Find(&plan).Error; err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// dryRunWarnings 检查发布前值得注意的问题: 未知的参数类型、请求中未使用的参数、SELECT *、
+// 以及服务当前的发布状态与生效时间窗口
+func dryRunWarnings(service *models.APIService, paramKeys []string, params map[string]interface{}, unknownTypes []string) []string {
+	var warnings []string
+	for _, t := range unknownTypes {
+		warnings = append(warnings, "未知的参数类型，将按 string 处理: "+t)
+	}
+
+	known := make(map[string]bool, len(paramKeys))
+	for _, k := range paramKeys {
+		known[k] = true
+	}
+	var unused []string
+	for k := range params {
+		if !known[k] {
+			unused = append(unused, k)
+		}
+	}
+	sort.Strings(unused)
+	if len(unused) > 0 {
+		warnings = append(warnings, "请求参数未在 param_keys 中声明，将被忽略: "+strings.Join(unused, ", "))
+	}
+
+	for i, t := range tokenizeSQL(service.SQL, config.DatasourceDriver(service.Datasource)) {
+		if i > 0 || t.kind != tokenWord || !strings.EqualFold(t.text, "SELECT") {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(service.SQL[t.end:]), "*") {
+			warnings = append(warnings, "使用了 SELECT *，表结构变化会改变返回字段，建议显式列出字段")
+		}
+	}
+
+	if status, resp := serviceAvailability(service, time.Now()); status != http.StatusOK {
+		warnings = append(warnings, fmt.Sprintf("按当前状态发布后调用将返回 %d: %s", status, resp.Message))
+	}
+	if service.Status == models.ServiceStatusDeprecated {
+		warnings = append(warnings, "服务已弃用，响应将带 Deprecation 头")
+	}
+	return warnings
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDryRunServiceRequestBody(t *testing.T) {
+	db := setupTestDB(t)
+	execSQL(t, db, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL)")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/v1/admin/services/test", DryRunService)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"未保存的定义", `{"service": {"sql": "SELECT id FROM notes"}}`, http.StatusOK},
+		{"格式错误", `{"service": `, http.StatusBadRequest},
+		{"超过大小上限", `{"service": {"sql": "SELECT '` + strings.Repeat("x", maxDryRunRequestSize) + `'"}}`,
+			http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := doRequest(t, r, http.MethodPost, "/api/v1/admin/services/test", tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", status, resp.Message, tt.wantStatus)
+			}
+		})
+	}
+}
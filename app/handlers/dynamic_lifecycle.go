@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/models"
+	"go-gin-gorm-api/app/utils"
+)
+
+// serviceAvailability 根据发布状态与生效时间窗口判断服务在 now 时刻是否可以执行。
+// 可以执行时返回 http.StatusOK；否则返回对应的 HTTP 状态码与响应:
+// 草稿或尚未生效 404，已停用 503，已到期 410。
+func serviceAvailability(service *models.APIService, now time.Time) (int, utils.APIResponse) {
+	switch {
+	case service.Status == models.ServiceStatusDraft:
+		return http.StatusNotFound, utils.APIResponse{Code: 404, Message: fmt.Sprintf("动态服务 %s 尚未发布", service.Name)}
+	case service.Status == models.ServiceStatusDisabled:
+		return http.StatusServiceUnavailable, utils.APIResponse{Code: 503, Message: fmt.Sprintf("动态服务 %s 已停用", service.Name)}
+	case service.ExpiresAt != nil && !now.Before(*service.ExpiresAt):
+		return http.StatusGone, utils.APIResponse{
+			Code:    410,
+			Message: fmt.Sprintf("动态服务 %s 已于 %s 停止提供", service.Name, service.ExpiresAt.Format(time.RFC3339)),
+		}
+	case service.ActivatesAt != nil && now.Before(*service.ActivatesAt):
+		return http.StatusNotFound, utils.APIResponse{
+			Code:    404,
+			Message: fmt.Sprintf("动态服务 %s 将于 %s 生效", service.Name, service.ActivatesAt.Format(time.RFC3339)),
+		}
+	}
+	return http.StatusOK, utils.APIResponse{}
+}
+
+// setLifecycleHeaders 为弃用的服务设置 Deprecation 头 (RFC 9745，值为弃用时间的结构化日期 @<unix 秒>)，
+// 为设置了到期时间的服务设置 Sunset 头 (RFC 8594)，提示调用方尽早迁移
+func setLifecycleHeaders(c *gin.Context, service *models.APIService) {
+	if service.Status == models.ServiceStatusDeprecated {
+		// 早于 deprecated_at 列弃用的服务没有记录弃用时间，以最后更新时间代替
+		since := service.UpdatedAt
+		if service.DeprecatedAt != nil {
+			since = *service.DeprecatedAt
+		}
+		c.Header("Deprecation", "@"+strconv.FormatInt(since.Unix(), 10))
+	}
+	if service.ExpiresAt != nil {
+		c.Header("Sunset", service.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/models"
+)
+
+func TestServiceAvailability(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Second), now.Add(time.Second)
+	tests := []struct {
+		name        string
+		status      string
+		activatesAt *time.Time
+		expiresAt   *time.Time
+		want        int
+	}{
+		{"正常服务", models.ServiceStatusActive, nil, nil, http.StatusOK},
+		{"弃用服务仍可执行", models.ServiceStatusDeprecated, nil, nil, http.StatusOK},
+		{"草稿", models.ServiceStatusDraft, nil, nil, http.StatusNotFound},
+		{"已停用", models.ServiceStatusDisabled, nil, nil, http.StatusServiceUnavailable},
+		{"草稿优先于生效时间", models.ServiceStatusDraft, &before, nil, http.StatusNotFound},
+		{"停用优先于到期", models.ServiceStatusDisabled, nil, &before, http.StatusServiceUnavailable},
+		{"尚未生效", models.ServiceStatusActive, &after, nil, http.StatusNotFound},
+		{"恰好生效", models.ServiceStatusActive, &now, nil, http.StatusOK},
+		{"尚未到期", models.ServiceStatusActive, nil, &after, http.StatusOK},
+		{"恰好到期", models.ServiceStatusActive, nil, &now, http.StatusGone},
+		{"弃用且已到期", models.ServiceStatusDeprecated, nil, &before, http.StatusGone},
+		{"生效窗口内", models.ServiceStatusActive, &before, &after, http.StatusOK},
+		{"到期优先于尚未生效", models.ServiceStatusActive, &after, &before, http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &models.APIService{Name: "report", Status: tt.status, ActivatesAt: tt.activatesAt, ExpiresAt: tt.expiresAt}
+			got, resp := serviceAvailability(service, now)
+			if got != tt.want || (got != http.StatusOK && resp.Code != got) {
+				t.Errorf("serviceAvailability() = %d, %+v; want %d", got, resp, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLifecycleHeaders(t *testing.T) {
+	deprecatedAt := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2026, 12, 31, 0, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	tests := []struct {
+		name            string
+		service         models.APIService
+		wantDeprecation string
+		wantSunset      string
+	}{
+		{"正常服务", models.APIService{Status: models.ServiceStatusActive}, "", ""},
+		{"弃用时间", models.APIService{Status: models.ServiceStatusDeprecated, DeprecatedAt: &deprecatedAt, UpdatedAt: updatedAt},
+			"@1772352000", ""},
+		{"未记录弃用时间时使用更新时间", models.APIService{Status: models.ServiceStatusDeprecated, UpdatedAt: updatedAt},
+			"@1775001600", ""},
+		{"到期时间", models.APIService{Status: models.ServiceStatusActive, ExpiresAt: &expiresAt},
+			"", "Wed, 30 Dec 2026 16:00:00 GMT"},
+		{"弃用且设置到期时间", models.APIService{Status: models.ServiceStatusDeprecated, DeprecatedAt: &deprecatedAt, ExpiresAt: &expiresAt},
+			"@1772352000", "Wed, 30 Dec 2026 16:00:00 GMT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			setLifecycleHeaders(c, &tt.service)
+			if got := w.Header().Get("Deprecation"); got != tt.wantDeprecation {
+				t.Errorf("Deprecation = %q, want %q", got, tt.wantDeprecation)
+			}
+			if got := w.Header().Get("Sunset"); got != tt.wantSunset {
+				t.Errorf("Sunset = %q, want %q", got, tt.wantSunset)
+			}
+		})
+	}
+}
+
+func TestTrackDeprecation(t *testing.T) {
+	earlier := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		status   string
+		previous *models.APIService
+		want     *time.Time
+	}{
+		{"新建的弃用服务", models.ServiceStatusDeprecated, nil, &now},
+		{"新建的正常服务", models.ServiceStatusActive, nil, nil},
+		{"从正常变为弃用", models.ServiceStatusDeprecated, &models.APIService{Status: models.ServiceStatusActive}, &now},
+		{"保持弃用时保留原时间", models.ServiceStatusDeprecated,
+			&models.APIService{Status: models.ServiceStatusDeprecated, DeprecatedAt: &earlier}, &earlier},
+		{"之前弃用但未记录时间", models.ServiceStatusDeprecated, &models.APIService{Status: models.ServiceStatusDeprecated}, &now},
+		{"从弃用恢复为正常", models.ServiceStatusActive,
+			&models.APIService{Status: models.ServiceStatusDeprecated, DeprecatedAt: &earlier}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &models.APIService{Status: tt.status, DeprecatedAt: &earlier}
+			s.TrackDeprecation(tt.previous, now)
+			if (s.DeprecatedAt == nil) != (tt.want == nil) || (tt.want != nil && !s.DeprecatedAt.Equal(*tt.want)) {
+				t.Errorf("DeprecatedAt = %v, want %v", s.DeprecatedAt, tt.want)
+			}
+		})
+	}
+}
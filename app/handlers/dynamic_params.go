@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// paramError 描述单个请求参数缺失或无法转换为预期类型
+type paramError struct {
+	Key          string
+	ExpectedType string
+	Missing      bool
+	Err          error
+}
+
+func (e *paramError) Error() string {
+	if e.Missing {
+		return fmt.Sprintf("请求参数缺失: %s", e.Key)
+	}
+	return fmt.Sprintf("参数 '%s' 无法转换为预期类型 '%s'", e.Key, e.ExpectedType)
+}
+
+// convertParams 严格按照 ParamKeys 和 ParamTypes 的顺序从原始请求参数中取值并进行类型转换，
+// 返回 SQL 参数与未知参数类型的列表（按 string 处理）。参数缺失或转换失败时返回 *paramError。
+func convertParams(paramKeys, paramTypes []string, rawParams map[string]interface{}) ([]interface{}, []string, *paramError) {
+	var args []interface{}
+	var unknownTypes []string
+	for i, key := range paramKeys {
+		expectedType := strings.ToLower(paramTypes[i])
+		rawValue, ok := rawParams[key]
+
+		if !ok {
+			return nil, nil, &paramError{Key: key, ExpectedType: expectedType, Missing: true}
+		}
+
+		var convertedValue interface{}
+		var err error
+
+		// 统一将原始值转换为字符串以便使用 strconv 进行精确转换
+		var strValue string
+		switch v := rawValue.(type) {
+		case string:
+			strValue = v
+		case float64: // JSON 解析数字默认是 float64
+			// 转换为字符串时，使用 -1 精度，以确保保留原始数字的所有有效位，避免科学计数法或精度丢失。
+			strValue = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			strValue = strconv.FormatBool(v)
+		default:
+			// 兜底：尝试将其他类型转换为字符串
+			strValue = fmt.Sprintf("%v", v)
+		}
+
+		// 执行类型转换
+		switch expectedType {
+		case "int", "int64":
+			var v int64
+			v, err = strconv.ParseInt(strValue, 10, 64)
+			convertedValue = v
+		case "float", "float64":
+			var v float64
+			v, err = strconv.ParseFloat(strValue, 64)
+			convertedValue = v
+		case "bool":
+			var v bool
+			// ParseBool 接受多种格式 (t, f, 1, 0, true, false)
+			v, err = strconv.ParseBool(strValue)
+			convertedValue = v
+		case "string":
+			convertedValue = strValue
+		default:
+			// 如果类型未指定或未知，默认使用字符串，由调用方记录警告
+			unknownTypes = append(unknownTypes, key+":"+expectedType)
+			convertedValue = strValue
+		}
+
+		if err != nil {
+			return nil, nil, &paramError{Key: key, ExpectedType: expectedType, Err: err}
+		}
+
+		args = append(args, convertedValue)
+	}
+	return args, unknownTypes, nil
+}
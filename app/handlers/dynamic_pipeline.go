@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/models"
+	"go-gin-gorm-api/app/tracing"
+	"go-gin-gorm-api/app/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"gorm.io/gorm"
+)
+
+// maxPipelineSteps 是流水线服务允许的最大步骤数
+const maxPipelineSteps = 20
+
+// stepNamePattern 是步骤名称的格式，步骤名称用于在后续步骤中引用结果列 (step.column)
+var stepNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// 写步骤除结果列外还可以引用的执行结果
+const (
+	stepRefRowsAffected = "rows_affected"
+	stepRefLastInsertID = "last_insert_id"
+)
+
+// PipelineStep 是流水线服务 (kind=pipeline) 的一个步骤。Params 按顺序对应 SQL 中的占位符:
+// "step.column" 引用前面步骤结果第一行的列，写步骤还可引用 step.rows_affected 与 step.last_insert_id；
+// 其余为 ParamKeys 中声明的请求参数。RequireRows 为 true 时步骤未返回或影响任何行即失败并回滚
+type PipelineStep struct {
+	Name        string   `yaml:"name" json:"name"`
+	SQL         string   `yaml:"sql" json:"sql"`
+	Params      []string `yaml:"params,omitempty" json:"params,omitempty"`
+	RequireRows bool     `yaml:"require_rows,omitempty" json:"require_rows,omitempty"`
+}
+
+// pipelineStep 是解析后的步骤，command 为 nil 表示只读查询
+type pipelineStep struct {
+	PipelineStep
+	command *commandStatement
+}
+
+// StepResult 是流水线中单个步骤的执行结果。Rows 为查询结果或写步骤 RETURNING 返回的行，
+// RowsAffected 与 LastInsertID 仅用于写步骤
+type StepResult struct {
+	Name         string                   `json:"name"`
+	Rows         []map[string]interface{} `json:"rows"`
+	Truncated    bool                     `json:"truncated,omitempty"`
+	RowsAffected *int64                   `json:"rows_affected,omitempty"`
+	LastInsertID *int64                   `json:"last_insert_id,omitempty"`
+	DurationMs   int64                    `json:"duration_ms"`
+}
+
+// PipelineResult 是流水线服务的执行结果，按顺序包含每个步骤的结果
+type PipelineResult struct {
+	Steps []*StepResult `json:"steps"`
+}
+
+// stepError 表示流水线某个步骤执行失败，事务已回滚
+type stepError struct {
+	Step string
+	Err  error
+}
+
+func (e *stepError) Error() string {
+	return fmt.Sprintf("步骤 %s: %v", e.Step, e.Err)
+}
+
+func (e *stepError) Unwrap() error { return e.Err }
+
+// errStepNoRows 表示 require_rows 的步骤未返回或影响任何行
+var errStepNoRows = errors.New("未返回或影响任何行")
+
+// stepRefError 表示步骤引用的前序结果不存在 (前序步骤没有返回行或没有该列)
+type stepRefError struct {
+	Ref, Reason string
+}
+
+func (e *stepRefError) Error() string {
+	return fmt.Sprintf("无法解析引用 %s: %s", e.Ref, e.Reason)
+}
+
+// parsePipeline 解析并校验服务的步骤: 步骤名称唯一，每个步骤为单条只读查询或符合写服务约束的 INSERT/UPDATE/DELETE，
+// 占位符数量与 params 一致，params 只能引用 ParamKeys 中的请求参数或前面步骤的结果
+func parsePipeline(service *models.APIService) ([]pipelineStep, error) {
+	if service.Steps == "" {
+		return nil, errors.New("pipeline 服务必须定义 steps")
+	}
+	var defs []PipelineStep
+	dec := json.NewDecoder(bytes.NewReader([]byte(service.Steps)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&defs); err != nil {
+		return nil, errors.New("steps 格式错误: " + err.Error())
+	}
+	if len(defs) == 0 || len(defs) > maxPipelineSteps {
+		return nil, fmt.Errorf("steps 必须包含 1 到 %d 个步骤", maxPipelineSteps)
+	}
+
+	var paramKeys []string
+	if service.ParamKeys != "" && json.Unmarshal([]byte(service.ParamKeys), &paramKeys) != nil {
+		return nil, errors.New("ParamKeys 格式错误 (非 JSON 数组)")
+	}
+	declared := make(map[string]bool, len(paramKeys))
+	for _, k := range paramKeys {
+		declared[k] = true
+	}
+
+	driver := config.DatasourceDriver(service.Datasource)
+	steps := make([]pipelineStep, 0, len(defs))
+	seen := make(map[string]bool, len(defs))
+	for i, def := range defs {
+		label := fmt.Sprintf("第 %d 个步骤", i+1)
+		if !stepNamePattern.MatchString(def.Name) {
+			return nil, fmt.Errorf("%s 的名称 %q 无效 (字母或下划线开头，只能包含字母、数字与下划线)", label, def.Name)
+		}
+		if seen[def.Name] {
+			return nil, fmt.Errorf("步骤名称 %s 重复", def.Name)
+		}
+		label = "步骤 " + def.Name
+
+		step := pipelineStep{PipelineStep: def}
+		tokens := tokenizeSQL(def.SQL, driver)
+		if len(tokens) > 0 && tokens[0].kind == tokenWord && commandVerbs[strings.ToUpper(tokens[0].text)] {
+			cmd, err := checkCommand(def.SQL, driver)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", label, err)
+			}
+			step.command = cmd
+		} else if !isAllowedQuery(def.SQL, driver) {
+			return nil, fmt.Errorf("%s: 只允许单条 %v 查询或 INSERT、UPDATE、DELETE 语句", label, allowedQueryPrefixes(driver))
+		}
+
+		placeholders, err := countPlaceholders(def.SQL, driver)
+		if err != nil {
+			return nil, fmt.Errorf("%s: SQL 占位符格式错误: %w", label, err)
+		}
+		if placeholders != len(def.Params) {
+			return nil, fmt.Errorf("%s: SQL 占位符数量 (%d) 与 params 数量 (%d) 不匹配", label, placeholders, len(def.Params))
+		}
+		for _, ref := range def.Params {
+			if declared[ref] {
+				continue
+			}
+			prev, _, ok := strings.Cut(ref, ".")
+			if !ok || !seen[prev] {
+				return nil, fmt.Errorf("%s: 参数 %s 既不是 ParamKeys 中的请求参数，也不是前面步骤的结果 (step.column)", label, ref)
+			}
+		}
+
+		seen[def.Name] = true
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// pipelineWrites 返回流水线是否包含写步骤
+func pipelineWrites(steps []pipelineStep) bool {
+	for _, s := range steps {
+		if s.command != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// pipelineSQL 返回全部步骤的 SQL，用于审计记录
+func pipelineSQL(steps []pipelineStep) string {
+	stmts := make([]string, 0, len(steps))
+	for _, s := range steps {
+		stmts = append(stmts, "-- "+s.Name+"\n"+strings.TrimSpace(s.SQL))
+	}
+	return strings.Join(stmts, ";\n")
+}
+
+// requiresCommandScope 返回执行服务是否需要 command 权限范围: command 服务，或包含写步骤的 pipeline 服务。
+// 步骤无法解析时按需要处理
+func requiresCommandScope(service *models.APIService) bool {
+	switch service.Kind {
+	case models.ServiceKindCommand:
+		return true
+	case models.ServiceKindPipeline:
+		steps, err := parsePipeline(service)
+		return err != nil || pipelineWrites(steps)
+	}
+	return false
+}
+
+// txOptions 返回服务配置的事务隔离级别，为空时使用数据库默认级别。
+// SQLite 的事务始终是可串行化的，驱动会忽略隔离级别
+func txOptions(service *models.APIService) *sql.TxOptions {
+	levels := map[string]sql.IsolationLevel{
+		models.IsolationReadUncommitted: sql.LevelReadUncommitted,
+		models.IsolationReadCommitted:   sql.LevelReadCommitted,
+		models.IsolationRepeatableRead:  sql.LevelRepeatableRead,
+		models.IsolationSerializable:    sql.LevelSerializable,
+	}
+	level, ok := levels[service.IsolationLevel]
+	if !ok {
+		return nil
+	}
+	return &sql.TxOptions{Isolation: level}
+}
+
+// resolveStepArgs 按步骤的 params 取得参数值: 请求参数来自 params，step.column 来自前面步骤的结果。
+// 写步骤的 rows_affected 与 last_insert_id 优先于 RETURNING 返回行中的同名列
+func resolveStepArgs(step pipelineStep, params map[string]interface{}, results map[string]*StepResult) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(step.Params))
+	for _, ref := range step.Params {
+		if v, ok := params[ref]; ok {
+			args = append(args, v)
+			continue
+		}
+		name, column, _ := strings.Cut(ref, ".")
+		prev := results[name]
+		switch {
+		case prev == nil:
+			return nil, &stepRefError{Ref: ref, Reason: fmt.Sprintf("步骤 %s 尚未执行", name)}
+		case column == stepRefRowsAffected && prev.RowsAffected != nil:
+			args = append(args, *prev.RowsAffected)
+		case column == stepRefLastInsertID && prev.LastInsertID != nil:
+			args = append(args, *prev.LastInsertID)
+		case len(prev.Rows) > 0:
+			v, ok := prev.Rows[0][column]
+			if !ok {
+				return nil, &stepRefError{Ref: ref, Reason: fmt.Sprintf("步骤 %s 的结果中没有列 %s", name, column)}
+			}
+			args = append(args, v)
+		default:
+			return nil, &stepRefError{Ref: ref, Reason: fmt.Sprintf("步骤 %s 没有返回行", name)}
+		}
+	}
+	return args, nil
+}
+
+// runPipeline 在事务 tx 中按顺序执行步骤，任一步骤失败时返回 *stepError（由调用方回滚事务）。
+// 查询步骤最多返回 maxRows 行，写步骤影响的行数不能超过服务的 max_affected_rows
+func runPipeline(tx *gorm.DB, service *models.APIService, steps []pipelineStep, params map[string]interface{}, maxRows int) ([]*StepResult, error) {
+	driver := config.DatasourceDriver(service.Datasource)
+	max := maxAffectedRows(service)
+	results := make([]*StepResult, 0, len(steps))
+	byName := make(map[string]*StepResult, len(steps))
+	for _, step := range steps {
+		fail := func(err error) ([]*StepResult, error) {
+			return results, &stepError{Step: step.Name, Err: err}
+		}
+		args, err := resolveStepArgs(step, params, byName)
+		if err != nil {
+			return fail(err)
+		}
+		sqlText, args, err := bindPlaceholders(step.SQL, driver, args)
+		if err != nil {
+			return fail(err)
+		}
+
+		result := &StepResult{Name: step.Name, Rows: []map[string]interface{}{}}
+		start := time.Now()
+		var rows int64
+		if step.command != nil {
+			cmd, err := runCommand(tx, driver, step.command, sqlText, args)
+			if err != nil {
+				return fail(err)
+			}
+			if cmd.RowsAffected > max {
+				return fail(&affectedRowsError{Affected: cmd.RowsAffected, Max: max})
+			}
+			if cmd.Returning != nil {
+				result.Rows = cmd.Returning
+			}
+			result.RowsAffected, result.LastInsertID = &cmd.RowsAffected, cmd.LastInsertID
+			rows = cmd.RowsAffected
+		} else {
+			if err := tx.Raw(sqlText, args...).Find(&result.Rows).Error; err != nil {
+				return fail(err)
+			}
+			rows = int64(len(result.Rows))
+		}
+		result.DurationMs = time.Since(start).Milliseconds()
+		if step.RequireRows && rows == 0 {
+			return fail(errStepNoRows)
+		}
+		// 后续步骤只引用第一行，截断不影响引用
+		if len(result.Rows) > maxRows {
+			result.Rows, result.Truncated = result.Rows[:maxRows], true
+		}
+		results = append(results, result)
+		byName[step.Name] = result
+	}
+	return results, nil
+}
+
+// pipelineParams 将按 ParamKeys 顺序转换后的参数值映射为参数名到值
+func pipelineParams(paramKeys []string, args []interface{}) map[string]interface{} {
+	params := make(map[string]interface{}, len(paramKeys))
+	for i, k := range paramKeys {
+		params[k] = args[i]
+	}
+	return params
+}
+
+// pipelineTarget 返回执行流水线的数据源: 包含写步骤时使用主库，否则与只读查询相同，可路由到只读副本
+func pipelineTarget(service *models.APIService, steps []pipelineStep) (*gorm.DB, error) {
+	if pipelineWrites(steps) {
+		return config.Datasource(service.Datasource)
+	}
+	return config.ReadDatasource(service.Datasource, service.RequiresFreshData)
+}
+
+// executePipeline 在单个事务中按顺序执行流水线服务的步骤，任一步骤失败时整体回滚，并写入审计记录。
+// 成功时返回每个步骤的结果
+func executePipeline(c *gin.Context, rec *models.Audit, service *models.APIService, steps []pipelineStep, paramKeys []string, args []interface{}) {
+	limits := config.Current().Dynamic
+
+	target, err := pipelineTarget(service, steps)
+	if err != nil {
+		datasourceUnavailable(c, rec, service, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), limits.QueryTimeout)
+	defer cancel()
+	ctx, span := tracing.Tracer().Start(ctx, "dynamic.execute_pipeline")
+	span.SetAttributes(attribute.String("dynamic.datasource", datasourceName(service.Datasource)),
+		attribute.Int("dynamic.pipeline_steps", len(steps)))
+	start := time.Now()
+
+	var results []*StepResult
+	err = target.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		results, err = runPipeline(tx, service, steps, pipelineParams(paramKeys, args), limits.MaxRows)
+		return err
+	}, txOptions(service))
+	rec.DurationMs = time.Since(start).Milliseconds()
+	for _, r := range results {
+		if r.RowsAffected != nil {
+			rec.Rows += int(*r.RowsAffected)
+		} else {
+			rec.Rows += len(r.Rows)
+		}
+		rec.Truncated = rec.Truncated || r.Truncated
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	var (
+		failed  *stepError
+		tooMany *affectedRowsError
+		badRef  *stepRefError
+	)
+	errors.As(err, &failed)
+	switch {
+	case err == nil:
+		slog.InfoContext(c.Request.Context(), "流水线服务执行成功", "service", service.Name, "steps", len(results))
+		finishExecution(c, rec, models.AuditOutcomeSuccess, http.StatusOK,
+			utils.APIResponse{Code: 0, Message: "执行成功", Data: PipelineResult{Steps: results}}, nil)
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.WarnContext(c.Request.Context(), "流水线服务执行超时，已回滚", "service", service.Name, "timeout", limits.QueryTimeout.String(), "error", err)
+		finishExecution(c, rec, models.AuditOutcomeTimeout, http.StatusOK,
+			utils.APIResponse{Code: 2, Message: "执行超时，已取消并回滚"}, err)
+	case failed == nil:
+		// 开启或提交事务失败
+		slog.ErrorContext(c.Request.Context(), "流水线服务执行失败", "service", service.Name, "error", err)
+		finishExecution(c, rec, models.AuditOutcomeSQLError, http.StatusInternalServerError,
+			utils.APIResponse{Code: 500, Message: "事务执行失败，已回滚。", Data: gin.H{"detail": err.Error()}}, err)
+	case errors.As(err, &tooMany):
+		slog.WarnContext(c.Request.Context(), "流水线步骤影响行数超过上限，已回滚", "service", service.Name, "step", failed.Step,
+			"rows_affected", tooMany.Affected, "max_affected_rows", tooMany.Max)
+		finishExecution(c, rec, models.AuditOutcomeRolledBack, http.StatusUnprocessableEntity, utils.APIResponse{
+			Code:    3,
+			Message: err.Error(),
+			Data:    gin.H{"step": failed.Step, "rows_affected": tooMany.Affected, "max_affected_rows": tooMany.Max},
+		}, err)
+	case errors.Is(err, errStepNoRows) || errors.As(err, &badRef):
+		slog.WarnContext(c.Request.Context(), "流水线步骤条件不满足，已回滚", "service", service.Name, "step", failed.Step, "error", failed.Err)
+		finishExecution(c, rec, models.AuditOutcomeRolledBack, http.StatusUnprocessableEntity, utils.APIResponse{
+			Code:    4,
+			Message: err.Error() + "，事务已回滚",
+			Data:    gin.H{"step": failed.Step},
+		}, err)
+	default:
+		slog.ErrorContext(c.Request.Context(), "流水线步骤执行失败", "service", service.Name, "step", failed.Step, "error", failed.Err)
+		finishExecution(c, rec, models.AuditOutcomeSQLError, http.StatusInternalServerError, utils.APIResponse{
+			Code:    500,
+			Message: "SQL 执行失败，事务已回滚。",
+			Data:    gin.H{"step": failed.Step, "detail": failed.Err.Error()},
+		}, err)
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-gin-gorm-api/app/models"
+)
+
+func TestParsePipeline(t *testing.T) {
+	tests := []struct {
+		name       string
+		steps      string
+		paramKeys  string
+		wantSteps  []string
+		wantWrites bool
+		wantErr    string
+	}{
+		{"读取后写入", `[
+			{"name": "stock", "sql": "SELECT qty FROM stock WHERE sku = ?", "params": ["sku"], "require_rows": true},
+			{"name": "take", "sql": "UPDATE stock SET qty = qty - ? WHERE sku = ?", "params": ["qty", "sku"]},
+			{"name": "order", "sql": "INSERT INTO orders (sku, qty) VALUES (?, ?)", "params": ["sku", "stock.qty"]},
+			{"name": "line", "sql": "INSERT INTO lines (order_id) VALUES (?)", "params": ["order.last_insert_id"]}
+		]`, `["sku", "qty"]`, []string{"stock", "take", "order", "line"}, true, ""},
+		{"只读步骤", `[{"name": "a", "sql": "SELECT 1"}, {"name": "b", "sql": "SELECT ? AS x", "params": ["a.x"]}]`,
+			"", []string{"a", "b"}, false, ""},
+		{"缺少 steps", ``, "", nil, false, "必须定义 steps"},
+		{"空数组", `[]`, "", nil, false, "1 到"},
+		{"未知字段", `[{"name": "a", "sql": "SELECT 1", "param": ["x"]}]`, "", nil, false, "格式错误"},
+		{"名称无效", `[{"name": "1a", "sql": "SELECT 1"}]`, "", nil, false, "名称"},
+		{"名称重复", `[{"name": "a", "sql": "SELECT 1"}, {"name": "a", "sql": "SELECT 2"}]`, "", nil, false, "重复"},
+		{"不允许的语句", `[{"name": "a", "sql": "DROP TABLE t"}]`, "", nil, false, "只允许"},
+		{"读取步骤包含多条语句", `[{"name": "a", "sql": "SELECT 1; DELETE FROM t"}]`, "", nil, false, "只允许"},
+		{"写步骤缺少 WHERE", `[{"name": "a", "sql": "DELETE FROM t"}]`, "", nil, false, "WHERE"},
+		{"占位符数量不符", `[{"name": "a", "sql": "SELECT ?", "params": []}]`, "", nil, false, "占位符数量"},
+		{"引用未声明的请求参数", `[{"name": "a", "sql": "SELECT ?", "params": ["x"]}]`, "", nil, false, "既不是"},
+		{"引用后面的步骤", `[{"name": "a", "sql": "SELECT ?", "params": ["b.x"]}, {"name": "b", "sql": "SELECT 1 AS x"}]`,
+			"", nil, false, "既不是"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := parsePipeline(&models.APIService{Kind: models.ServiceKindPipeline, Steps: tt.steps, ParamKeys: tt.paramKeys})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parsePipeline() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePipeline() error = %v", err)
+			}
+			var names []string
+			for _, s := range steps {
+				names = append(names, s.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantSteps) || pipelineWrites(steps) != tt.wantWrites {
+				t.Errorf("steps = %v, writes = %v; want %v, %v", names, pipelineWrites(steps), tt.wantSteps, tt.wantWrites)
+			}
+		})
+	}
+}
+
+func TestResolveStepArgs(t *testing.T) {
+	one, two, id := int64(1), int64(2), int64(42)
+	results := map[string]*StepResult{
+		"read":  {Name: "read", Rows: []map[string]interface{}{{"qty": 5, "sku": "x"}, {"qty": 9}}},
+		"empty": {Name: "empty", Rows: []map[string]interface{}{}},
+		"write": {Name: "write", RowsAffected: &one, LastInsertID: &id},
+		// 带 RETURNING 的写步骤同时有返回行与影响行数
+		"returning": {Name: "returning", Rows: []map[string]interface{}{{"id": 7}, {"id": 8}}, RowsAffected: &two},
+	}
+	params := map[string]interface{}{"sku": "x", "read.qty": "请求参数优先"}
+	tests := []struct {
+		name    string
+		refs    []string
+		want    []interface{}
+		wantErr bool
+	}{
+		{"请求参数", []string{"sku"}, []interface{}{"x"}, false},
+		{"与步骤引用同名的请求参数", []string{"read.qty"}, []interface{}{"请求参数优先"}, false},
+		{"第一行的列", []string{"read.sku"}, []interface{}{"x"}, false},
+		{"影响行数与插入 ID", []string{"write.rows_affected", "write.last_insert_id"}, []interface{}{one, id}, false},
+		{"RETURNING 步骤的影响行数", []string{"returning.rows_affected"}, []interface{}{two}, false},
+		{"RETURNING 步骤的列", []string{"returning.id"}, []interface{}{7}, false},
+		{"没有该列", []string{"read.price"}, nil, true},
+		{"没有返回行", []string{"empty.qty"}, nil, true},
+		{"未返回插入 ID", []string{"returning.last_insert_id"}, nil, true},
+		{"写步骤没有返回行", []string{"write.id"}, nil, true},
+		{"步骤不存在", []string{"missing.qty"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := pipelineStep{PipelineStep: PipelineStep{Name: "next", Params: tt.refs}}
+			got, err := resolveStepArgs(step, params, results)
+			if tt.wantErr {
+				var refErr *stepRefError
+				if !errors.As(err, &refErr) {
+					t.Fatalf("resolveStepArgs() error = %v, want *stepRefError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveStepArgs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveStepArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutePipeline(t *testing.T) {
+	db := setupTestDB(t)
+	execSQL(t, db,
+		"CREATE TABLE stock (sku TEXT PRIMARY KEY, qty INTEGER NOT NULL)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, sku TEXT NOT NULL, qty INTEGER NOT NULL)",
+		"CREATE TABLE order_events (order_id INTEGER NOT NULL, note TEXT NOT NULL)",
+		"INSERT INTO stock (sku, qty) VALUES ('apple', 5)",
+	)
+	createTestService(t, db, &models.APIService{
+		Name: "place_order", Method: "POST", Path: "/orders", Kind: models.ServiceKindPipeline,
+		ParamKeys: `["sku", "qty"]`, ParamTypes: `["string", "int"]`,
+		Steps: `[
+			{"name": "take", "sql": "UPDATE stock SET qty = qty - ? WHERE sku = ? AND qty >= ?", "params": ["qty", "sku", "qty"], "require_rows": true},
+			{"name": "order", "sql": "INSERT INTO orders (sku, qty) VALUES (?, ?)", "params": ["sku", "qty"]},
+			{"name": "event", "sql": "INSERT INTO order_events (order_id, note) VALUES (?, 'placed') RETURNING order_id", "params": ["order.last_insert_id"]},
+			{"name": "left", "sql": "SELECT qty FROM stock WHERE sku = ?", "params": ["sku"]}
+		]`,
+	})
+	r := newTestRouter(models.ScopeRun + "," + models.ScopeCommand)
+
+	t.Run("全部步骤成功后提交", func(t *testing.T) {
+		status, resp := doRequest(t, r, http.MethodPost, "/api/v1/dynamic/run/orders", `{"sku": "apple", "qty": 2}`)
+		if status != http.StatusOK || resp.Code != 0 {
+			t.Fatalf("status = %d, code = %d (%s); want 200, 0", status, resp.Code, resp.Message)
+		}
+		var result PipelineResult
+		if err := json.Unmarshal(resp.Data, &result); err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Steps) != 4 {
+			t.Fatalf("steps = %d, want 4", len(result.Steps))
+		}
+		if rows := result.Steps[2].Rows; len(rows) != 1 || rows[0]["order_id"] != float64(1) {
+			t.Errorf("event rows = %v, want order_id 1", rows)
+		}
+		if rows := result.Steps[3].Rows; len(rows) != 1 || rows[0]["qty"] != float64(3) {
+			t.Errorf("left rows = %v, want qty 3", rows)
+		}
+		if n := countRows(t, db, "SELECT COUNT(*) FROM order_events WHERE order_id = 1"); n != 1 {
+			t.Errorf("order_events = %d, want 1", n)
+		}
+	})
+
+	t.Run("require_rows 失败时整体回滚", func(t *testing.T) {
+		status, resp := doRequest(t, r, http.MethodPost, "/api/v1/dynamic/run/orders", `{"sku": "apple", "qty": 10}`)
+		if status != http.StatusUnprocessableEntity || resp.Code != 4 {
+			t.Fatalf("status = %d, code = %d (%s); want 422, 4", status, resp.Code, resp.Message)
+		}
+		if n := countRows(t, db, "SELECT COUNT(*) FROM orders"); n != 1 {
+			t.Errorf("orders = %d, want 1", n)
+		}
+		if a := lastAudit(t, db); a.Outcome != models.AuditOutcomeRolledBack {
+			t.Errorf("audit outcome = %s, want %s", a.Outcome, models.AuditOutcomeRolledBack)
+		}
+	})
+
+	t.Run("后续步骤出错时回滚前面的写入", func(t *testing.T) {
+		execSQL(t, db, "DROP TABLE order_events")
+		status, _ := doRequest(t, r, http.MethodPost, "/api/v1/dynamic/run/orders", `{"sku": "apple", "qty": 1}`)
+		if status != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500", status)
+		}
+		if n := countRows(t, db, "SELECT qty FROM stock WHERE sku = 'apple'"); n != 3 {
+			t.Errorf("stock qty = %d, want 3 (扣减应已回滚)", n)
+		}
+		if n := countRows(t, db, "SELECT COUNT(*) FROM orders"); n != 1 {
+			t.Errorf("orders = %d, want 1", n)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go-gin-gorm-api/app/config"
+)
+
+// readOnlyPrefixes 按数据库方言定义允许通过动态服务执行的只读语句前缀。
+// DESCRIBE/DESC 仅 MySQL 支持；PostgreSQL 与 SQLite 查看表结构请查询 information_schema 或 sqlite_master。
+var readOnlyPrefixes = map[string][]string{
+	config.DriverMySQL:    {"SELECT", "WITH", "EXPLAIN", "DESCRIBE", "DESC "},
+	config.DriverPostgres: {"SELECT", "WITH", "EXPLAIN"},
+	config.DriverSQLite:   {"SELECT", "WITH", "EXPLAIN"},
+}
+
+// writeKeywords 是会修改数据的语句关键字。WITH 子句后可以跟随这些语句
+// (MySQL 8、PostgreSQL 与 SQLite 均支持 WITH ... DELETE 等写法)，必须单独检查
+var writeKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "REPLACE": true, "MERGE": true,
+}
+
+// allowedQueryPrefixes 返回方言允许的只读语句前缀，未知方言按 MySQL 处理
+func allowedQueryPrefixes(driver string) []string {
+	if p, ok := readOnlyPrefixes[driver]; ok {
+		return p
+	}
+	return readOnlyPrefixes[config.DriverMySQL]
+}
+
+// isAllowedQuery 检查 SQL 语句是否为 driver 方言下允许的只读查询:
+// 必须以允许的前缀开始；WITH 语句中不能出现写操作关键字；
+// PostgreSQL 的 EXPLAIN ANALYZE 会真正执行语句，同样被拒绝；
+// 不允许多条语句 (部分驱动会依次执行分号分隔的所有语句)。
+func isAllowedQuery(sql, driver string) bool {
+	sqlUpper := strings.ToUpper(strings.TrimSpace(sql))
+	allowed := false
+	for _, prefix := range allowedQueryPrefixes(driver) {
+		if strings.HasPrefix(sqlUpper, prefix) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false
+	}
+
+	tokens := tokenizeSQL(sql, driver)
+	if len(tokens) == 0 || multipleStatements(tokens) {
+		return false
+	}
+	switch strings.ToUpper(tokens[0].text) {
+	case "WITH":
+		for _, t := range tokens {
+			if t.kind == tokenWord && writeKeywords[strings.ToUpper(t.text)] {
+				return false
+			}
+		}
+	case "EXPLAIN":
+		if driver == config.DriverPostgres {
+			for _, t := range tokens {
+				if w := strings.ToUpper(t.text); t.kind == tokenWord && (w == "ANALYZE" || w == "ANALYSE") {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// multipleStatements 返回语句是否包含多条语句 (末尾的分号除外)
+func multipleStatements(tokens []sqlToken) bool {
+	for i, t := range tokens {
+		if t.kind == tokenPunct && t.text == ";" {
+			for _, rest := range tokens[i+1:] {
+				if rest.kind != tokenPunct || rest.text != ";" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// tokenKind 是 SQL 词法单元的类型
+type tokenKind int
+
+const (
+	tokenWord        tokenKind = iota // 关键字或标识符
+	tokenPlaceholder                  // ? 或 $N
+	tokenPunct                        // 括号与分号，用于判断子查询层级与语句边界
+)
+
+// sqlToken 是 tokenizeSQL 识别出的词法单元，start/end 为其在原 SQL 中的字节范围
+type sqlToken struct {
+	kind       tokenKind
+	text       string
+	start, end int
+}
+
+// tokenizeSQL 扫描 SQL 中的关键字/标识符、占位符、括号与分号，跳过字符串、带引号的标识符与注释，
+// 以免其中的 ?、$1 或关键字被误判。MySQL 的可执行注释 (/*!、/*M!) 与优化器提示 (/*+) 不跳过，
+// 其内容与注释外的 SQL 一样扫描。只识别本包需要的单元，不是完整的 SQL 解析器。
+func tokenizeSQL(sql, driver string) []sqlToken {
+	var tokens []sqlToken
+	execComment := false
+	n := len(sql)
+	for i := 0; i < n; {
+		ch := sql[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			i = skipQuoted(sql, i, ch, driver == config.DriverMySQL)
+		case ch == '-' && i+1 < n && sql[i+1] == '-', ch == '#' && driver == config.DriverMySQL:
+			for i < n && sql[i] != '\n' {
+				i++
+			}
+		case ch == '/' && i+1 < n && sql[i+1] == '*' && driver == config.DriverMySQL && mysqlExecutableComment(sql[i:]) > 0:
+			// MySQL 会执行 /*! ... */ 中的语句，/*+ ... */ 是优化器提示，其内容按普通 SQL 扫描
+			i += mysqlExecutableComment(sql[i:])
+			execComment = true
+		case ch == '*' && execComment && i+1 < n && sql[i+1] == '/':
+			execComment = false
+			i += 2
+		case ch == '/' && i+1 < n && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case ch == '?':
+			tokens = append(tokens, sqlToken{kind: tokenPlaceholder, text: "?", start: i, end: i + 1})
+			i++
+		case ch == '(' || ch == ')' || ch == ';':
+			tokens = append(tokens, sqlToken{kind: tokenPunct, text: sql[i : i+1], start: i, end: i + 1})
+			i++
+		case ch == '$' && driver == config.DriverPostgres:
+			j := i + 1
+			for j < n && isDigit(sql[j]) {
+				j++
+			}
+			if j > i+1 {
+				tokens = append(tokens, sqlToken{kind: tokenPlaceholder, text: sql[i:j], start: i, end: j})
+				i = j
+				continue
+			}
+			// $tag$ ... $tag$ 形式的 dollar-quoted 字符串
+			for j < n && isWordChar(sql[j]) {
+				j++
+			}
+			if j < n && sql[j] == '$' {
+				tag := sql[i : j+1]
+				end := strings.Index(sql[j+1:], tag)
+				if end < 0 {
+					return tokens
+				}
+				i = j + 1 + end + len(tag)
+				continue
+			}
+			i = j
+		case isWordStart(ch):
+			j := i + 1
+			for j < n && (isWordChar(sql[j]) || sql[j] == '$') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: tokenWord, text: sql[i:j], start: i, end: j})
+			i = j
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// mysqlExecutableComment 返回 s 开头的 MySQL 可执行注释或优化器提示起始部分 (/*!50110、/*M!100100、/*+) 的长度，
+// 不是这类注释时返回 0
+func mysqlExecutableComment(s string) int {
+	switch {
+	case strings.HasPrefix(s, "/*+"):
+		return 3
+	case strings.HasPrefix(s, "/*!"), strings.HasPrefix(s, "/*M!"):
+		i := strings.IndexByte(s, '!') + 1
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		return i
+	}
+	return 0
+}
+
+// skipQuoted 跳过从 start 开始、以 quote 包围的字符串或标识符，返回其后的位置。
+// 连续两个引号表示转义；backslash 为 true 时 (MySQL) 反斜杠同样转义下一个字符
+func skipQuoted(sql string, start int, quote byte, backslash bool) int {
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if backslash && quote != '`' {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+func isDigit(c byte) bool     { return c >= '0' && c <= '9' }
+func isWordStart(c byte) bool { return c == '_' || (c|0x20) >= 'a' && (c|0x20) <= 'z' }
+func isWordChar(c byte) bool  { return isWordStart(c) || isDigit(c) }
+
+// countPlaceholders 返回 SQL 需要的参数个数: ? 占位符的数量，
+// 或 PostgreSQL 编号占位符 ($1、$2 …) 的最大编号。两种写法不能混用
+func countPlaceholders(sql, driver string) (int, error) {
+	question, maxNum := 0, 0
+	for _, t := range tokenizeSQL(sql, driver) {
+		if t.kind != tokenPlaceholder {
+			continue
+		}
+		if t.text == "?" {
+			question++
+			continue
+		}
+		num, err := strconv.Atoi(t.text[1:])
+		if err != nil || num < 1 {
+			return 0, fmt.Errorf("无效的占位符 %s", t.text)
+		}
+		if num > maxNum {
+			maxNum = num
+		}
+	}
+	if question > 0 && maxNum > 0 {
+		return 0, fmt.Errorf("SQL 不能同时使用 ? 与 $N 两种占位符")
+	}
+	return question + maxNum, nil
+}
+
+// bindPlaceholders 将服务 SQL 转换为 GORM 使用的 ? 占位符形式。
+// PostgreSQL 服务可以使用原生的 $1、$2 编号占位符（同一编号可以多次引用），
+// 此时按出现顺序替换为 ? 并重排参数；使用 ? 的 SQL 原样返回，由 GORM 按方言转换。
+func bindPlaceholders(sql, driver string, args []interface{}) (string, []interface{}, error) {
+	if driver != config.DriverPostgres {
+		return sql, args, nil
+	}
+	var b strings.Builder
+	var bound []interface{}
+	last := 0
+	for _, t := range tokenizeSQL(sql, driver) {
+		if t.kind != tokenPlaceholder || t.text == "?" {
+			continue
+		}
+		num, err := strconv.Atoi(t.text[1:])
+		if err != nil || num < 1 || num > len(args) {
+			return "", nil, fmt.Errorf("占位符 %s 超出参数个数 %d", t.text, len(args))
+		}
+		b.WriteString(sql[last:t.start])
+		b.WriteByte('?')
+		bound = append(bound, args[num-1])
+		last = t.end
+	}
+	if bound == nil {
+		return sql, args, nil
+	}
+	b.WriteString(sql[last:])
+	return b.String(), bound, nil
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"go-gin-gorm-api/app/config"
+)
+
+// tokenTexts 将词法单元转为便于比较的文本: 占位符带 "?:" 前缀，括号与分号原样输出
+func tokenTexts(tokens []sqlToken) []string {
+	texts := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		switch t.kind {
+		case tokenPlaceholder:
+			texts = append(texts, "?:"+t.text)
+		default:
+			texts = append(texts, t.text)
+		}
+	}
+	return texts
+}
+
+func TestTokenizeSQL(t *testing.T) {
+	tests := []struct {
+		name   string
+		sql    string
+		driver string
+		want   []string
+	}{
+		{"基本查询", "SELECT id FROM users WHERE id = ?", config.DriverMySQL,
+			[]string{"SELECT", "id", "FROM", "users", "WHERE", "id", "?:?"}},
+		{"字符串中的占位符与关键字", "SELECT 'a ? DELETE', x FROM t", config.DriverSQLite,
+			[]string{"SELECT", "x", "FROM", "t"}},
+		{"转义的单引号", "SELECT 'it''s ?' FROM t", config.DriverSQLite,
+			[]string{"SELECT", "FROM", "t"}},
+		{"MySQL 反斜杠转义", `SELECT 'a\' ?' FROM t WHERE b = ?`, config.DriverMySQL,
+			[]string{"SELECT", "FROM", "t", "WHERE", "b", "?:?"}},
+		{"带引号的标识符", "SELECT `select ?`, \"delete\" FROM t", config.DriverMySQL,
+			[]string{"SELECT", "FROM", "t"}},
+		{"行注释与块注释", "SELECT 1 -- DELETE ?\nFROM /* UPDATE ? */ t", config.DriverSQLite,
+			[]string{"SELECT", "FROM", "t"}},
+		{"MySQL # 注释", "SELECT a # ?\nFROM t", config.DriverMySQL,
+			[]string{"SELECT", "a", "FROM", "t"}},
+		{"# 在其他方言中不是注释", "SELECT a # b", config.DriverSQLite,
+			[]string{"SELECT", "a", "b"}},
+		{"PostgreSQL 编号占位符", "SELECT * FROM t WHERE a = $2 AND b = $1", config.DriverPostgres,
+			[]string{"SELECT", "FROM", "t", "WHERE", "a", "?:$2", "AND", "b", "?:$1"}},
+		{"PostgreSQL dollar-quoted 字符串", "SELECT $tag$ DELETE ? $1 $tag$, $$x$$ FROM t", config.DriverPostgres,
+			[]string{"SELECT", "FROM", "t"}},
+		{"括号与分号", "DELETE FROM t WHERE id IN (SELECT 1); SELECT", config.DriverSQLite,
+			[]string{"DELETE", "FROM", "t", "WHERE", "id", "IN", "(", "SELECT", ")", ";", "SELECT"}},
+		{"未闭合的块注释", "SELECT a /* DELETE", config.DriverSQLite,
+			[]string{"SELECT", "a"}},
+		{"MySQL 可执行注释与优化器提示", "SELECT /*+ NO_ICP(t) */ a /*!50110 , ? */ /*M!100100 ; */ FROM t", config.DriverMySQL,
+			[]string{"SELECT", "NO_ICP", "(", "t", ")", "a", "?:?", ";", "FROM", "t"}},
+		{"可执行注释在其他方言中是普通注释", "SELECT a /*! DELETE */ FROM t", config.DriverSQLite,
+			[]string{"SELECT", "a", "FROM", "t"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tokenTexts(tokenizeSQL(tt.sql, tt.driver))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenizeSQL(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAllowedQuery(t *testing.T) {
+	tests := []struct {
+		sql    string
+		driver string
+		want   bool
+	}{
+		{"SELECT * FROM users", config.DriverMySQL, true},
+		{"  select id from users where id = ?", config.DriverSQLite, true},
+		{"SELECT 1;", config.DriverSQLite, true},
+		{"SELECT 1; ;", config.DriverSQLite, true},
+		{"WITH a AS (SELECT 1) SELECT * FROM a", config.DriverPostgres, true},
+		{"EXPLAIN SELECT * FROM users", config.DriverPostgres, true},
+		{"DESCRIBE users", config.DriverMySQL, true},
+		{"SELECT 'a; DELETE FROM users'", config.DriverSQLite, true},
+		{"SELECT 1 -- ; DELETE FROM users", config.DriverSQLite, true},
+		{"SELECT /*+ MAX_EXECUTION_TIME(1000) */ * FROM users", config.DriverMySQL, true},
+		{"SELECT 1 /*!; DELETE FROM users */", config.DriverSQLite, true},
+
+		{"DELETE FROM users", config.DriverMySQL, false},
+		{"UPDATE users SET a = 1", config.DriverSQLite, false},
+		{"", config.DriverSQLite, false},
+		{"DESCRIBE users", config.DriverPostgres, false},
+		{"DESCRIBE users", config.DriverSQLite, false},
+		{"WITH a AS (DELETE FROM users RETURNING *) SELECT * FROM a", config.DriverPostgres, false},
+		{"WITH a AS (SELECT 1) INSERT INTO t SELECT * FROM a", config.DriverSQLite, false},
+		{"EXPLAIN ANALYZE DELETE FROM users", config.DriverPostgres, false},
+		{"EXPLAIN (ANALYSE) SELECT 1", config.DriverPostgres, false},
+		{"SELECT 1; DELETE FROM users", config.DriverSQLite, false},
+		{"SELECT 1; DELETE FROM users", config.DriverMySQL, false},
+		{"SELECT 1;DROP TABLE users;", config.DriverPostgres, false},
+		{"SELECT 1; SELECT 2", config.DriverSQLite, false},
+		// MySQL 会执行 /*! ... */ 中的内容
+		{"WITH a AS (SELECT 1) /*! DELETE FROM users */", config.DriverMySQL, false},
+		{"SELECT 1 /*!; DELETE FROM users */", config.DriverMySQL, false},
+		{"SELECT 1 /*!50000 ; DROP TABLE users */", config.DriverMySQL, false},
+	}
+	for _, tt := range tests {
+		if got := isAllowedQuery(tt.sql, tt.driver); got != tt.want {
+			t.Errorf("isAllowedQuery(%q, %s) = %v, want %v", tt.sql, tt.driver, got, tt.want)
+		}
+	}
+}
+
+func TestCountPlaceholders(t *testing.T) {
+	tests := []struct {
+		sql     string
+		driver  string
+		want    int
+		wantErr bool
+	}{
+		{"SELECT * FROM t WHERE a = ? AND b = ?", config.DriverMySQL, 2, false},
+		{"SELECT '?' FROM t", config.DriverMySQL, 0, false},
+		{"SELECT a /*! , ? */ FROM t WHERE b = ?", config.DriverMySQL, 2, false},
+		{"SELECT * FROM t WHERE a = $1 OR b = $1 OR c = $3", config.DriverPostgres, 3, false},
+		{"SELECT * FROM t WHERE a = $1 AND b = ?", config.DriverPostgres, 0, true},
+		{"SELECT * FROM t WHERE a = $0", config.DriverPostgres, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := countPlaceholders(tt.sql, tt.driver)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("countPlaceholders(%q) = %d, %v; want %d, error %v", tt.sql, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestBindPlaceholders(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		driver   string
+		args     []interface{}
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{"非 PostgreSQL 原样返回", "SELECT * FROM t WHERE a = ? AND b = ?", config.DriverMySQL,
+			[]interface{}{1, 2}, "SELECT * FROM t WHERE a = ? AND b = ?", []interface{}{1, 2}, false},
+		{"PostgreSQL 的 ? 原样返回", "SELECT * FROM t WHERE a = ?", config.DriverPostgres,
+			[]interface{}{1}, "SELECT * FROM t WHERE a = ?", []interface{}{1}, false},
+		{"按编号重排参数", "SELECT * FROM t WHERE a = $2 AND b = $1", config.DriverPostgres,
+			[]interface{}{"x", "y"}, "SELECT * FROM t WHERE a = ? AND b = ?", []interface{}{"y", "x"}, false},
+		{"同一编号多次引用", "SELECT * FROM t WHERE a = $1 OR b = $1", config.DriverPostgres,
+			[]interface{}{7}, "SELECT * FROM t WHERE a = ? OR b = ?", []interface{}{7, 7}, false},
+		{"字符串中的 $1 不替换", "SELECT '$1' FROM t WHERE a = $1", config.DriverPostgres,
+			[]interface{}{7}, "SELECT '$1' FROM t WHERE a = ?", []interface{}{7}, false},
+		{"编号超出参数个数", "SELECT * FROM t WHERE a = $2", config.DriverPostgres,
+			[]interface{}{1}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := bindPlaceholders(tt.sql, tt.driver, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bindPlaceholders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sql != tt.wantSQL || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("bindPlaceholders() = %q, %v; want %q, %v", sql, args, tt.wantSQL, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/health"
+	"go-gin-gorm-api/app/utils"
+)
+
+// Healthz 存活检查：进程能够处理请求即返回 200，不检查外部依赖
+// GET /healthz
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: "ok"})
+}
+
+// Readyz 就绪检查：执行全部已注册的依赖检查（数据库连通性、迁移状态、审计写入器等），
+// 全部通过返回 200，否则返回 503，Data 中包含每项检查的详细结果。
+// GET /readyz
+func Readyz(c *gin.Context) {
+	ok, results := health.Run(c.Request.Context(), config.Current().Server.ReadinessTimeout)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, utils.APIResponse{Code: 503, Message: "服务未就绪", Data: results})
+		return
+	}
+	c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: "ready", Data: results})
+}
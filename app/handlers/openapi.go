@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/models"
+	"go-gin-gorm-api/app/utils"
+	"gorm.io/gorm"
+)
+
+// OpenAPIVersion 是生成文档使用的 OpenAPI 规范版本
+const OpenAPIVersion = "3.0.3"
+
+// object 是 OpenAPI 文档中的 JSON 对象
+type object = map[string]interface{}
+
+// nonIdentChars 匹配名称中的分隔字符（字母与数字以外的字符）
+var nonIdentChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// OpenAPISpec 返回运行时生成的 OpenAPI 3 文档，覆盖用户管理接口与当前可调用的全部动态服务。
+// 文档每次请求时根据 api_services 表生成，服务注册、变更或下线后立即反映。
+// GET /api/v1/openapi.json
+func OpenAPISpec(c *gin.Context) {
+	spec, err := BuildOpenAPI(c.Request.Context(), config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "生成 OpenAPI 文档失败", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+	// 文档本身即响应体，不使用 APIResponse 包装，以便 Swagger UI 等工具直接加载
+	c.JSON(http.StatusOK, spec)
+}
+
+// BuildOpenAPI 生成 OpenAPI 3 文档: 用户管理接口为静态定义，动态服务由 DescribeActiveServices 生成，
+// 参数类型来自 ParamTypes，响应的行结构来自推断出的结果列
+func BuildOpenAPI(ctx context.Context, db *gorm.DB) (object, error) {
+	services, err := DescribeActiveServices(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := userPaths()
+	for _, s := range services {
+		item, ok := paths[s.RunPath].(object)
+		if !ok {
+			item = object{}
+			paths[s.RunPath] = item
+		}
+		item[strings.ToLower(s.Service.Method)] = serviceOperation(s)
+	}
+
+	return object{
+		"openapi": OpenAPIVersion,
+		"info": object{
+			"title":       "Go Gin Gorm API",
+			"version":     time.Now().UTC().Format("2006.01.02"),
+			"description": "用户管理接口与当前已发布的动态 SQL 服务。动态服务部分根据服务注册信息在运行时生成。",
+		},
+		"servers": []object{{"url": "/"}},
+		"tags": []object{
+			{"name": "users", "description": "用户管理"},
+			{"name": "dynamic", "description": "动态 SQL 服务"},
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": object{
+				"APIResponse": object{
+					"type":     "object",
+					"required": []string{"code", "message"},
+					"properties": object{
+						"code":    object{"type": "integer", "description": "业务码，0 表示成功"},
+						"message": object{"type": "string"},
+						"data":    object{},
+					},
+				},
+				"User": object{
+					"type": "object",
+					"properties": object{
+						"id":         object{"type": "integer", "format": "int64"},
+						"created_at": object{"type": "string", "format": "date-time"},
+						"updated_at": object{"type": "string", "format": "date-time"},
+						"username":   object{"type": "string"},
+						"email":      object{"type": "string"},
+					},
+				},
+				"CommandResult": object{
+					"type":     "object",
+					"required": []string{"rows_affected"},
+					"properties": object{
+						"rows_affected":  object{"type": "integer", "format": "int64"},
+						"last_insert_id": object{"type": "integer", "format": "int64", "description": "MySQL 与 SQLite 的 INSERT 返回"},
+						"returning":      object{"type": "array", "items": object{"type": "object", "additionalProperties": true}},
+					},
+				},
+				"StepResult": object{
+					"type":     "object",
+					"required": []string{"name", "rows", "duration_ms"},
+					"properties": object{
+						"name":           object{"type": "string"},
+						"rows":           object{"type": "array", "items": object{"type": "object", "additionalProperties": true}},
+						"truncated":      object{"type": "boolean"},
+						"rows_affected":  object{"type": "integer", "format": "int64", "description": "仅写步骤返回"},
+						"last_insert_id": object{"type": "integer", "format": "int64", "description": "MySQL 与 SQLite 的 INSERT 步骤返回"},
+						"duration_ms":    object{"type": "integer", "format": "int64"},
+					},
+				},
+				"PipelineResult": object{
+					"type":       "object",
+					"required":   []string{"steps"},
+					"properties": object{"steps": object{"type": "array", "items": ref("schemas", "StepResult")}},
+				},
+				"UserInput": object{
+					"type":     "object",
+					"required": []string{"username", "email"},
+					"properties": object{
+						"username": object{"type": "string"},
+						"email":    object{"type": "string"},
+					},
+				},
+			},
+			"responses": object{
+				"Error": object{
+					"description": "错误，详情见 message 与 data.detail",
+					"content":     jsonContent(ref("schemas", "APIResponse")),
+				},
+			},
+			"securitySchemes": object{
+				"apiKey": object{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "可选的 API Key (ggk_...)，需要 run 权限范围。未携带时按匿名调用处理",
+				},
+			},
+		},
+	}, nil
+}
+
+// userPaths 返回用户管理接口的路径定义
+func userPaths() object {
+	idParam := object{"name": "id", "in": "path", "required": true, "schema": object{"type": "integer", "format": "int64"}}
+	userBody := object{"required": true, "content": jsonContent(ref("schemas", "UserInput"))}
+	user := envelope(ref("schemas", "User"))
+	errResp := ref("responses", "Error")
+
+	return object{
+		"/api/v1/users": object{
+			"get": object{
+				"tags": []string{"users"}, "operationId": "listUsers", "summary": "查询全部用户",
+				"responses": object{
+					"200": object{"description": "查询成功", "content": jsonContent(envelope(object{"type": "array", "items": ref("schemas", "User")}))},
+					"500": errResp,
+				},
+			},
+			"post": object{
+				"tags": []string{"users"}, "operationId": "createUser", "summary": "创建用户",
+				"requestBody": userBody,
+				"responses": object{
+					"201": object{"description": "创建成功", "content": jsonContent(user)},
+					"400": errResp,
+					"500": errResp,
+				},
+			},
+		},
+		"/api/v1/users/{id}": object{
+			"parameters": []object{idParam},
+			"get": object{
+				"tags": []string{"users"}, "operationId": "getUser", "summary": "按 ID 查询用户",
+				"responses": object{
+					"200": object{"description": "查询成功", "content": jsonContent(user)},
+					"400": errResp,
+					"404": errResp,
+				},
+			},
+			"put": object{
+				"tags": []string{"users"}, "operationId": "updateUser", "summary": "更新用户",
+				"requestBody": userBody,
+				"responses": object{
+					"200": object{"description": "更新成功", "content": jsonContent(user)},
+					"400": errResp,
+					"404": errResp,
+				},
+			},
+			"delete": object{
+				"tags": []string{"users"}, "operationId": "deleteUser", "summary": "删除用户",
+				"responses": object{
+					"200": object{"description": "删除成功", "content": jsonContent(envelope(object{"nullable": true}))},
+					"400": errResp,
+					"404": errResp,
+					"500": errResp,
+				},
+			},
+		},
+	}
+}
+
+// serviceOperation 返回单个动态服务的操作定义。GET 服务的参数为查询参数，其他方法为 JSON 请求体；
+// 结果超过 dynamic.max_rows 时 data 为包含截断信息的对象
+func serviceOperation(s ServiceSchema) object {
+	rows := object{"type": "array", "items": rowSchema(s)}
+	op := object{
+		"tags":        []string{"dynamic"},
+		"operationId": OperationID(s.Service.Name),
+		"summary":     s.Service.Name,
+		"security":    []object{{}, {"apiKey": []string{}}},
+		"responses": object{
+			"200": object{
+				"description": "查询成功。code 为 1 表示被安全策略拦截，为 2 表示查询超时",
+				"content": jsonContent(envelope(object{"oneOf": []object{rows, {
+					"type": "object",
+					"properties": object{
+						"rows_returned": object{"type": "integer"},
+						"truncated":     object{"type": "boolean"},
+						"data":          rows,
+					},
+				}}})),
+			},
+			"400": ref("responses", "Error"),
+			"404": ref("responses", "Error"),
+			"410": ref("responses", "Error"),
+			"500": ref("responses", "Error"),
+			"503": ref("responses", "Error"),
+		},
+	}
+
+	var notes []string
+	responses := op["responses"].(object)
+	switch s.Service.Kind {
+	case models.ServiceKindCommand:
+		responses["200"] = object{
+			"description": "执行成功。code 为 1 表示被安全策略拦截，为 2 表示执行超时",
+			"content":     jsonContent(envelope(ref("schemas", "CommandResult"))),
+		}
+		responses["422"] = ref("responses", "Error")
+		notes = append(notes, fmt.Sprintf("写服务: 在事务中执行，影响行数超过 %d 时回滚并返回 422。", maxAffectedRows(s.Service)))
+	case models.ServiceKindPipeline:
+		responses["200"] = object{
+			"description": "执行成功，data.steps 按顺序包含每个步骤的结果。code 为 1 表示被安全策略拦截，为 2 表示执行超时",
+			"content":     jsonContent(envelope(ref("schemas", "PipelineResult"))),
+		}
+		responses["422"] = ref("responses", "Error")
+		var names []string
+		if steps, err := parsePipeline(s.Service); err == nil {
+			for _, step := range steps {
+				names = append(names, step.Name)
+			}
+		}
+		notes = append(notes, fmt.Sprintf("流水线: 在单个事务中依次执行步骤 %s，任一步骤失败时整体回滚并返回 422 或 500。", strings.Join(names, " → ")))
+	}
+	if requiresCommandScope(s.Service) {
+		// 写服务必须携带拥有 command 权限范围的 API Key
+		responses["401"] = ref("responses", "Error")
+		responses["403"] = ref("responses", "Error")
+		op["security"] = []object{{"apiKey": []string{}}}
+		notes = append(notes, "需要拥有 command 权限范围的 API Key。")
+	}
+	if s.Service.Status == models.ServiceStatusDeprecated {
+		op["deprecated"] = true
+		notes = append(notes, "服务已弃用，响应带 Deprecation 头。")
+	}
+	if s.Service.ExpiresAt != nil {
+		notes = append(notes, fmt.Sprintf("服务将于 %s 停止提供，之后返回 410。", s.Service.ExpiresAt.UTC().Format(time.RFC3339)))
+	}
+	if s.ColumnsError != "" {
+		notes = append(notes, "无法推断结果列: "+s.ColumnsError)
+	}
+	if len(notes) > 0 {
+		op["description"] = strings.Join(notes, "\n\n")
+	}
+
+	if len(s.Params) == 0 {
+		return op
+	}
+	if s.Service.Method == http.MethodGet {
+		params := make([]object, 0, len(s.Params))
+		for _, p := range s.Params {
+			params = append(params, object{"name": p.Name, "in": "query", "required": true, "schema": object{"type": p.Type}})
+		}
+		op["parameters"] = params
+		return op
+	}
+	props := object{}
+	required := make([]string, 0, len(s.Params))
+	for _, p := range s.Params {
+		props[p.Name] = object{"type": p.Type}
+		required = append(required, p.Name)
+	}
+	op["requestBody"] = object{
+		"required": true,
+		"content":  jsonContent(object{"type": "object", "required": required, "properties": props}),
+	}
+	return op
+}
+
+// rowSchema 返回结果行的 Schema，结果列未知时为任意对象
+func rowSchema(s ServiceSchema) object {
+	if len(s.Columns) == 0 {
+		return object{"type": "object", "additionalProperties": true}
+	}
+	props := object{}
+	for _, col := range s.Columns {
+		prop := object{}
+		if col.Type != "" {
+			prop["type"] = col.Type
+		}
+		if col.Format != "" {
+			prop["format"] = col.Format
+		}
+		if col.Nullable {
+			prop["nullable"] = true
+		}
+		if col.DatabaseType != "" {
+			prop["description"] = col.DatabaseType
+		}
+		props[col.Name] = prop
+	}
+	return object{"type": "object", "properties": props}
+}
+
+// OperationID 将服务名称转换为 operationId 与客户端函数名使用的驼峰标识符，例如 user-report → userReport
+func OperationID(name string) string {
+	parts := nonIdentChars.Split(name, -1)
+	var b strings.Builder
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString(strings.ToLower(p[:1]) + p[1:])
+		} else {
+			b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+		}
+	}
+	id := b.String()
+	if id == "" || (id[0] >= '0' && id[0] <= '9') {
+		id = "service" + id
+	}
+	return id
+}
+
+// envelope 返回 APIResponse 包装 data 后的 Schema
+func envelope(data object) object {
+	return object{"allOf": []object{
+		ref("schemas", "APIResponse"),
+		{"type": "object", "properties": object{"data": data}},
+	}}
+}
+
+// jsonContent 返回 application/json 媒体类型定义
+func jsonContent(schema object) object {
+	return object{"application/json": object{"schema": schema}}
+}
+
+// ref 返回对 components 中定义的引用
+func ref(kind, name string) object {
+	return object{"$ref": "#/components/" + kind + "/" + name}
+}
@@ -0,0 +1,531 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/models"
+	"go-gin-gorm-api/app/utils"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// BundleVersion 是当前服务定义包 (bundle) 的格式版本
+const BundleVersion = 1
+
+// maxBundleSize 是导入接口允许的请求体大小上限
+const maxBundleSize = 8 << 20
+
+// ServiceBundle 是动态服务定义包，以 YAML 或 JSON 保存在 git 中，用于在环境之间迁移服务。
+// 与数据库模型不同，参数与来源列表使用原生数组，便于阅读与 diff；不包含 ID 与时间戳。
+type ServiceBundle struct {
+	Version  int             `yaml:"version" json:"version"`
+	Services []BundleService `yaml:"services" json:"services"`
+}
+
+// BundleService 是定义包中的单个动态服务，字段含义与 models.APIService 相同
+type BundleService struct {
+	Name              string   `yaml:"name" json:"name"`
+	Method            string   `yaml:"method" json:"method"`
+	Path              string   `yaml:"path" json:"path"`
+	SQL               string   `yaml:"sql,omitempty" json:"sql,omitempty"`
+	ParamKeys         []string `yaml:"param_keys,omitempty" json:"param_keys,omitempty"`
+	ParamTypes        []string `yaml:"param_types,omitempty" json:"param_types,omitempty"`
+	AllowedOrigins    []string `yaml:"allowed_origins,omitempty" json:"allowed_origins,omitempty"`
+	Datasource        string   `yaml:"datasource,omitempty" json:"datasource,omitempty"`
+	RequiresFreshData bool     `yaml:"requires_fresh_data,omitempty" json:"requires_fresh_data,omitempty"`
+	// Status 为空表示 active
+	Status      string     `yaml:"status,omitempty" json:"status,omitempty"`
+	ActivatesAt *time.Time `yaml:"activates_at,omitempty" json:"activates_at,omitempty"`
+	ExpiresAt   *time.Time `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+	// Kind 为空表示 query；MaxAffectedRows 仅用于 command 服务
+	Kind            string `yaml:"kind,omitempty" json:"kind,omitempty"`
+	MaxAffectedRows int    `yaml:"max_affected_rows,omitempty" json:"max_affected_rows,omitempty"`
+	// Steps 仅用于 pipeline 服务；IsolationLevel 用于 command 与 pipeline 服务
+	Steps          []PipelineStep `yaml:"steps,omitempty" json:"steps,omitempty"`
+	IsolationLevel string         `yaml:"isolation_level,omitempty" json:"isolation_level,omitempty"`
+}
+
+// 导入时与已有服务冲突（同名但定义不同）的处理策略
+const (
+	ConflictSkip      = "skip"      // 保留已有定义
+	ConflictOverwrite = "overwrite" // 以定义包为准覆盖
+	ConflictFail      = "fail"      // 任一冲突则整体失败 (默认)
+)
+
+// 导入计划中单个服务的动作
+const (
+	BundleActionCreate    = "create"
+	BundleActionUpdate    = "update"
+	BundleActionUnchanged = "unchanged"
+	BundleActionSkip      = "skip"
+	BundleActionConflict  = "conflict"
+)
+
+// FieldDiff 是服务单个字段在数据库与定义包之间的差异
+type FieldDiff struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// BundleChange 是导入计划中单个服务的动作与字段差异
+type BundleChange struct {
+	Name   string      `json:"name"`
+	Action string      `json:"action"`
+	Reason string      `json:"reason,omitempty"`
+	Diff   []FieldDiff `json:"diff,omitempty"`
+}
+
+// BundlePlan 是导入定义包的执行计划 (dry-run 的结果)
+type BundlePlan struct {
+	Strategy  string         `json:"strategy"`
+	DryRun    bool           `json:"dry_run"`
+	Applied   bool           `json:"applied"`
+	Changes   []BundleChange `json:"changes"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`
+	Unchanged int            `json:"unchanged"`
+	Skipped   int            `json:"skipped"`
+	Conflicts int            `json:"conflicts"`
+}
+
+// BundleValidationError 汇总定义包中全部无效的服务，任一服务无效时不会写入数据库
+type BundleValidationError struct {
+	Errors []string `json:"errors"`
+}
+
+func (e *BundleValidationError) Error() string {
+	return "定义包校验失败: " + strings.Join(e.Errors, "; ")
+}
+
+// ErrBundleConflict 表示导入计划存在无法按所选策略处理的冲突，数据库未做任何修改
+var ErrBundleConflict = errors.New("定义包与已有服务冲突，未做任何修改")
+
+// ValidConflictStrategy 返回 strategy 是否为支持的冲突策略
+func ValidConflictStrategy(strategy string) bool {
+	return strategy == ConflictSkip || strategy == ConflictOverwrite || strategy == ConflictFail
+}
+
+// bundleServiceFromModel 将数据库模型转换为定义包格式
+func bundleServiceFromModel(s *models.APIService) BundleService {
+	b := BundleService{
+		Name:              s.Name,
+		Method:            s.Method,
+		Path:              s.Path,
+		SQL:               s.SQL,
+		Datasource:        s.Datasource,
+		RequiresFreshData: s.RequiresFreshData,
+		ActivatesAt:       s.ActivatesAt,
+		ExpiresAt:         s.ExpiresAt,
+	}
+	if s.Status != models.ServiceStatusActive {
+		b.Status = s.Status
+	}
+	if s.Kind != models.ServiceKindQuery {
+		b.Kind = s.Kind
+	}
+	b.MaxAffectedRows = s.MaxAffectedRows
+	b.IsolationLevel = s.IsolationLevel
+	// 数据库中的值均已在注册时校验，解析失败时保持为空
+	json.Unmarshal([]byte(s.ParamKeys), &b.ParamKeys)
+	json.Unmarshal([]byte(s.ParamTypes), &b.ParamTypes)
+	json.Unmarshal([]byte(s.AllowedOrigins), &b.AllowedOrigins)
+	json.Unmarshal([]byte(s.Steps), &b.Steps)
+	return b
+}
+
+// Model 将定义包中的服务转换为数据库模型（未校验）
+func (b *BundleService) Model() models.APIService {
+	return models.APIService{
+		Name:              b.Name,
+		Method:            b.Method,
+		Path:              b.Path,
+		SQL:               b.SQL,
+		ParamKeys:         jsonList(b.ParamKeys),
+		ParamTypes:        jsonList(b.ParamTypes),
+		AllowedOrigins:    jsonList(b.AllowedOrigins),
+		Datasource:        b.Datasource,
+		RequiresFreshData: b.RequiresFreshData,
+		Status:            b.Status,
+		ActivatesAt:       b.ActivatesAt,
+		ExpiresAt:         b.ExpiresAt,
+		Kind:              b.Kind,
+		MaxAffectedRows:   b.MaxAffectedRows,
+		Steps:             jsonSteps(b.Steps),
+		IsolationLevel:    b.IsolationLevel,
+	}
+}
+
+// jsonSteps 将步骤编码为模型使用的 JSON 数组字符串，没有步骤时编码为空字符串
+func jsonSteps(steps []PipelineStep) string {
+	if len(steps) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(steps)
+	return string(data)
+}
+
+// jsonList 将列表编码为模型使用的 JSON 数组字符串，空列表编码为空字符串
+func jsonList(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+// ExportBundle 导出 names 指定的服务（为空时导出全部），按名称排序。指定的服务不存在时返回错误
+func ExportBundle(db *gorm.DB, names []string) (*ServiceBundle, error) {
+	query := db.Order("name")
+	if len(names) > 0 {
+		query = query.Where("name IN ?", names)
+	}
+	var services []models.APIService
+	if err := query.Find(&services).Error; err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		found := make(map[string]bool, len(services))
+		for _, s := range services {
+			found[s.Name] = true
+		}
+		for _, name := range names {
+			if !found[name] {
+				return nil, fmt.Errorf("动态服务 %s 不存在", name)
+			}
+		}
+	}
+	bundle := &ServiceBundle{Version: BundleVersion, Services: make([]BundleService, 0, len(services))}
+	for i := range services {
+		bundle.Services = append(bundle.Services, bundleServiceFromModel(&services[i]))
+	}
+	return bundle, nil
+}
+
+// EncodeBundle 以 format (yaml 或 json) 格式写出定义包
+func EncodeBundle(w io.Writer, bundle *ServiceBundle, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(bundle)
+	case "yaml", "":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(bundle); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("不支持的格式 %q (可选 yaml、json)", format)
+	}
+}
+
+// ParseBundle 解析 YAML 或 JSON 格式的定义包 (JSON 是 YAML 的子集)，未知字段视为错误
+func ParseBundle(data []byte) (*ServiceBundle, error) {
+	var bundle ServiceBundle
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&bundle); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("定义包为空")
+		}
+		return nil, fmt.Errorf("定义包格式错误: %w", err)
+	}
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("不支持的定义包版本 %d (当前版本 %d)", bundle.Version, BundleVersion)
+	}
+	return &bundle, nil
+}
+
+// ValidateBundle 校验定义包中的全部服务并返回规范化后的模型，同时检查包内名称与路径是否重复
+func ValidateBundle(bundle *ServiceBundle) ([]models.APIService, error) {
+	var errs []string
+	services := make([]models.APIService, len(bundle.Services))
+	names := make(map[string]int)
+	routes := make(map[string]string)
+	for i := range bundle.Services {
+		s := bundle.Services[i].Model()
+		label := fmt.Sprintf("第 %d 个服务 (%s)", i+1, s.Name)
+		if err := ValidateService(&s); err != nil {
+			errs = append(errs, label+": "+err.Error())
+			continue
+		}
+		if j, ok := names[s.Name]; ok {
+			errs = append(errs, fmt.Sprintf("%s: 名称与第 %d 个服务重复", label, j+1))
+		}
+		names[s.Name] = i
+		if other, ok := routes[s.Path]; ok {
+			errs = append(errs, fmt.Sprintf("%s: 路径 %s 与服务 %s 重复", label, s.Path, other))
+		}
+		routes[s.Path] = s.Name
+		services[i] = s
+	}
+	if len(errs) > 0 {
+		return nil, &BundleValidationError{Errors: errs}
+	}
+	return services, nil
+}
+
+// DiffServices 比较已有服务与期望的定义，返回不同的字段
+func DiffServices(current, desired *models.APIService) []FieldDiff {
+	o, n := bundleServiceFromModel(current), bundleServiceFromModel(desired)
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"method", o.Method, n.Method},
+		{"path", o.Path, n.Path},
+		{"sql", o.SQL, n.SQL},
+		{"param_keys", strings.Join(o.ParamKeys, ","), strings.Join(n.ParamKeys, ",")},
+		{"param_types", strings.Join(o.ParamTypes, ","), strings.Join(n.ParamTypes, ",")},
+		{"allowed_origins", strings.Join(o.AllowedOrigins, ","), strings.Join(n.AllowedOrigins, ",")},
+		{"datasource", datasourceName(o.Datasource), datasourceName(n.Datasource)},
+		{"requires_fresh_data", strconv.FormatBool(o.RequiresFreshData), strconv.FormatBool(n.RequiresFreshData)},
+		{"status", current.Status, desired.Status},
+		{"activates_at", formatTime(o.ActivatesAt), formatTime(n.ActivatesAt)},
+		{"expires_at", formatTime(o.ExpiresAt), formatTime(n.ExpiresAt)},
+		{"kind", current.Kind, desired.Kind},
+		{"max_affected_rows", strconv.Itoa(o.MaxAffectedRows), strconv.Itoa(n.MaxAffectedRows)},
+		{"steps", jsonSteps(o.Steps), jsonSteps(n.Steps)},
+		{"isolation_level", o.IsolationLevel, n.IsolationLevel},
+	}
+	var diff []FieldDiff
+	for _, f := range fields {
+		if f.old != f.new {
+			diff = append(diff, FieldDiff{Field: f.name, Old: f.old, New: f.new})
+		}
+	}
+	return diff
+}
+
+// formatTime 以 RFC 3339 格式输出可选时间，nil 输出空字符串
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ImportBundle 校验定义包中的全部服务，按 strategy 生成导入计划，dryRun 为 false 且没有未解决的冲突时
+// 在单个事务中应用全部变更（全部成功或全部回滚）。同名服务定义相同时不做修改；
+// 路径已被其他名称的服务占用或同名服务由文件管理 (ManagedBy) 时视为冲突，overwrite 策略也不会覆盖；
+// 占用路径的服务已软删除时不视为冲突，写入前将其彻底删除以释放路径。
+// 校验失败时返回 *BundleValidationError；存在冲突时返回计划与 ErrBundleConflict。
+func ImportBundle(db *gorm.DB, bundle *ServiceBundle, strategy string, dryRun bool) (*BundlePlan, error) {
+	if strategy == "" {
+		strategy = ConflictFail
+	}
+	if !ValidConflictStrategy(strategy) {
+		return nil, fmt.Errorf("未知的冲突策略 %q (可选 skip、overwrite、fail)", strategy)
+	}
+	services, err := ValidateBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &BundlePlan{Strategy: strategy, DryRun: dryRun, Changes: []BundleChange{}}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var writes []*models.APIService
+		var releases []uint
+		now := time.Now()
+		for i := range services {
+			s := &services[i]
+			change := BundleChange{Name: s.Name}
+
+			var existing models.APIService
+			if err := tx.Unscoped().Where("name = ?", s.Name).Limit(1).Find(&existing).Error; err != nil {
+				return err
+			}
+			var owner models.APIService
+			if err := tx.Select("id", "name").Where("path = ? AND name <> ?", s.Path, s.Name).
+				Limit(1).Find(&owner).Error; err != nil {
+				return err
+			}
+			// 已软删除的其他服务不再提供服务，但仍占用路径的唯一索引，写入前将其彻底删除
+			var released []models.APIService
+			if owner.ID == 0 {
+				if err := tx.Unscoped().Select("id", "name").Where("path = ? AND name <> ? AND deleted_at IS NOT NULL", s.Path, s.Name).
+					Find(&released).Error; err != nil {
+					return err
+				}
+			}
+
+			switch {
+			case owner.ID != 0 && strategy == ConflictSkip:
+				change.Action, change.Reason = BundleActionSkip, "路径已被服务 "+owner.Name+" 使用"
+			case owner.ID != 0:
+				change.Action, change.Reason = BundleActionConflict, "路径已被服务 "+owner.Name+" 使用"
+			case existing.ID == 0 || existing.DeletedAt.Valid:
+				// 已软删除的同名服务直接复用原记录，避免唯一索引冲突
+				change.Action = BundleActionCreate
+				s.ID, s.CreatedAt = existing.ID, existing.CreatedAt
+				s.TrackDeprecation(nil, now)
+				writes = append(writes, s)
+			default:
+				change.Diff = DiffServices(&existing, s)
+				switch {
+				case len(change.Diff) == 0:
+					change.Action = BundleActionUnchanged
+				case existing.ManagedBy != "" && strategy == ConflictSkip:
+					change.Action, change.Reason = BundleActionSkip, "由文件 "+existing.ManagedBy+" 管理"
+				case existing.ManagedBy != "":
+					// 由服务定义目录管理的服务只能通过修改文件变更，overwrite 也不会覆盖
+					change.Action, change.Reason = BundleActionConflict, "由文件 "+existing.ManagedBy+" 管理"
+				case strategy == ConflictOverwrite:
+					change.Action = BundleActionUpdate
+					s.ID, s.CreatedAt = existing.ID, existing.CreatedAt
+					s.TrackDeprecation(&existing, now)
+					writes = append(writes, s)
+				case strategy == ConflictSkip:
+					change.Action = BundleActionSkip
+				default:
+					change.Action, change.Reason = BundleActionConflict, "已存在定义不同的同名服务"
+				}
+			}
+			if len(released) > 0 && (change.Action == BundleActionCreate || change.Action == BundleActionUpdate) {
+				names := make([]string, len(released))
+				for i, r := range released {
+					names[i] = r.Name
+					releases = append(releases, r.ID)
+				}
+				change.Reason = "释放已删除服务 " + strings.Join(names, "、") + " 占用的路径"
+			}
+			plan.add(change)
+		}
+
+		if plan.Conflicts > 0 {
+			return ErrBundleConflict
+		}
+		if dryRun {
+			return nil
+		}
+		if len(releases) > 0 {
+			if err := tx.Unscoped().Delete(&models.APIService{}, releases).Error; err != nil {
+				return fmt.Errorf("释放已删除服务占用的路径失败: %w", err)
+			}
+		}
+		for _, s := range writes {
+			var err error
+			if s.ID == 0 {
+				err = tx.Create(s).Error
+			} else {
+				// Save 按主键更新全部字段，同时清除软删除标记
+				err = tx.Unscoped().Save(s).Error
+			}
+			if err != nil {
+				return fmt.Errorf("写入服务 %s 失败: %w", s.Name, err)
+			}
+		}
+		plan.Applied = true
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrBundleConflict) {
+			return plan, err
+		}
+		return nil, err
+	}
+	return plan, nil
+}
+
+// add 将单个服务的变更加入计划并更新统计
+func (p *BundlePlan) add(change BundleChange) {
+	p.Changes = append(p.Changes, change)
+	switch change.Action {
+	case BundleActionCreate:
+		p.Created++
+	case BundleActionUpdate:
+		p.Updated++
+	case BundleActionUnchanged:
+		p.Unchanged++
+	case BundleActionSkip:
+		p.Skipped++
+	case BundleActionConflict:
+		p.Conflicts++
+	}
+}
+
+// ExportServices 导出动态服务定义包。
+// GET /api/v1/admin/services/export?format=yaml|json&name=a&name=b （未指定 name 时导出全部）
+func ExportServices(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", "yaml"))
+	if format != "yaml" && format != "json" {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "format 仅支持 yaml 或 json"})
+		return
+	}
+	bundle, err := ExportBundle(config.DB.WithContext(c.Request.Context()), c.QueryArray("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, utils.APIResponse{Code: 404, Message: "导出失败", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeBundle(&buf, bundle, format); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "导出失败", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+	contentType := "application/yaml; charset=utf-8"
+	if format == "json" {
+		contentType = "application/json; charset=utf-8"
+	}
+	filename := fmt.Sprintf("services-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// ImportServices 导入动态服务定义包 (请求体为 YAML 或 JSON)。
+// POST /api/v1/admin/services/import?on_conflict=skip|overwrite|fail&dry_run=true
+// dry_run 时只返回导入计划与字段差异，不修改数据库。
+func ImportServices(c *gin.Context) {
+	strategy := c.DefaultQuery("on_conflict", ConflictFail)
+	if !ValidConflictStrategy(strategy) {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "on_conflict 仅支持 skip、overwrite 或 fail"})
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "dry_run 必须是布尔值"})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBundleSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "读取请求体失败", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+	if len(data) > maxBundleSize {
+		c.JSON(http.StatusRequestEntityTooLarge, utils.APIResponse{Code: 413, Message: "定义包超过大小上限"})
+		return
+	}
+	bundle, err := ParseBundle(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: err.Error()})
+		return
+	}
+
+	plan, err := ImportBundle(config.DB.WithContext(c.Request.Context()), bundle, strategy, dryRun)
+	var invalid *BundleValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusUnprocessableEntity, utils.APIResponse{Code: 422, Message: "定义包校验失败，未做任何修改", Data: invalid})
+	case errors.Is(err, ErrBundleConflict):
+		c.JSON(http.StatusConflict, utils.APIResponse{Code: 409, Message: err.Error(), Data: plan})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "导入失败，已全部回滚", Data: gin.H{"detail": err.Error()}})
+	case dryRun:
+		c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: "导入计划 (dry-run，未修改数据库)", Data: plan})
+	default:
+		c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: "导入成功", Data: plan})
+	}
+}
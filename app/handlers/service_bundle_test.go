@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+// setupBundleDB 创建导入测试使用的已有服务: 普通服务 report、由文件管理的 managed 与已软删除的 gone
+func setupBundleDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := setupTestDB(t)
+	createTestService(t, db, &models.APIService{Name: "report", Method: "GET", Path: "/report", SQL: "SELECT 1"})
+	createTestService(t, db, &models.APIService{Name: "managed", Method: "GET", Path: "/managed", SQL: "SELECT 1", ManagedBy: "managed.yaml"})
+	createTestService(t, db, &models.APIService{Name: "gone", Method: "GET", Path: "/gone", SQL: "SELECT 1"})
+	if err := db.Where("name = ?", "gone").Delete(&models.APIService{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestImportBundle(t *testing.T) {
+	svc := func(name, path, sql string) BundleService {
+		return BundleService{Name: name, Method: "GET", Path: path, SQL: sql}
+	}
+	tests := []struct {
+		name        string
+		strategy    string
+		services    []BundleService
+		wantActions []string
+		wantReasons []string
+		wantErr     error // ErrBundleConflict 或 nil
+	}{
+		{"新服务", ConflictFail, []BundleService{svc("new", "/new", "SELECT 2")},
+			[]string{BundleActionCreate}, []string{""}, nil},
+		{"定义相同", ConflictFail, []BundleService{svc("report", "/report", "SELECT 1")},
+			[]string{BundleActionUnchanged}, []string{""}, nil},
+		{"定义不同 fail", ConflictFail, []BundleService{svc("report", "/report", "SELECT 2")},
+			[]string{BundleActionConflict}, []string{"已存在定义不同的同名服务"}, ErrBundleConflict},
+		{"定义不同 skip", ConflictSkip, []BundleService{svc("report", "/report", "SELECT 2")},
+			[]string{BundleActionSkip}, []string{""}, nil},
+		{"定义不同 overwrite", ConflictOverwrite, []BundleService{svc("report", "/report", "SELECT 2")},
+			[]string{BundleActionUpdate}, []string{""}, nil},
+		{"由文件管理 overwrite", ConflictOverwrite, []BundleService{svc("managed", "/managed", "SELECT 2")},
+			[]string{BundleActionConflict}, []string{"由文件 managed.yaml 管理"}, ErrBundleConflict},
+		{"由文件管理 skip", ConflictSkip, []BundleService{svc("managed", "/managed", "SELECT 2")},
+			[]string{BundleActionSkip}, []string{"由文件 managed.yaml 管理"}, nil},
+		{"由文件管理但定义相同", ConflictFail, []BundleService{svc("managed", "/managed", "SELECT 1")},
+			[]string{BundleActionUnchanged}, []string{""}, nil},
+		{"路径被其他服务占用 overwrite", ConflictOverwrite, []BundleService{svc("other", "/report", "SELECT 1")},
+			[]string{BundleActionConflict}, []string{"路径已被服务 report 使用"}, ErrBundleConflict},
+		{"路径被其他服务占用 skip", ConflictSkip, []BundleService{svc("other", "/report", "SELECT 1")},
+			[]string{BundleActionSkip}, []string{"路径已被服务 report 使用"}, nil},
+		{"复用已软删除的同名服务", ConflictFail, []BundleService{svc("gone", "/gone", "SELECT 2")},
+			[]string{BundleActionCreate}, []string{""}, nil},
+		{"任一冲突时整体不写入", ConflictFail, []BundleService{svc("new", "/new", "SELECT 2"), svc("report", "/report", "SELECT 2")},
+			[]string{BundleActionCreate, BundleActionConflict}, []string{"", "已存在定义不同的同名服务"}, ErrBundleConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupBundleDB(t)
+			var before []models.APIService
+			if err := db.Unscoped().Order("id").Find(&before).Error; err != nil {
+				t.Fatal(err)
+			}
+			bundle := &ServiceBundle{Version: BundleVersion, Services: tt.services}
+
+			// dry-run 返回相同的计划但不修改数据库
+			for _, dryRun := range []bool{true, false} {
+				plan, err := ImportBundle(db, bundle, tt.strategy, dryRun)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ImportBundle(dry-run %v) error = %v, want %v", dryRun, err, tt.wantErr)
+				}
+				var actions, reasons []string
+				for _, c := range plan.Changes {
+					actions, reasons = append(actions, c.Action), append(reasons, c.Reason)
+				}
+				if !reflect.DeepEqual(actions, tt.wantActions) || !reflect.DeepEqual(reasons, tt.wantReasons) {
+					t.Fatalf("ImportBundle(dry-run %v) actions = %v %q, want %v %q", dryRun, actions, reasons, tt.wantActions, tt.wantReasons)
+				}
+				if plan.Applied != (!dryRun && err == nil) {
+					t.Errorf("ImportBundle(dry-run %v) applied = %v", dryRun, plan.Applied)
+				}
+				var after []models.APIService
+				if err := db.Unscoped().Order("id").Find(&after).Error; err != nil {
+					t.Fatal(err)
+				}
+				if dryRun || err != nil {
+					same := len(after) == len(before)
+					for j := 0; same && j < len(after); j++ {
+						same = after[j].SQL == before[j].SQL && after[j].DeletedAt == before[j].DeletedAt
+					}
+					if !same {
+						t.Errorf("ImportBundle(dry-run %v) 修改了数据库: %+v -> %+v", dryRun, before, after)
+					}
+				}
+			}
+
+			for i, s := range tt.services {
+				var got models.APIService
+				if err := db.Unscoped().Where("name = ?", s.Name).Limit(1).Find(&got).Error; err != nil {
+					t.Fatal(err)
+				}
+				written := tt.wantErr == nil && (tt.wantActions[i] == BundleActionCreate || tt.wantActions[i] == BundleActionUpdate)
+				if written && (got.SQL != s.SQL || got.DeletedAt.Valid) {
+					t.Errorf("服务 %s = %q (deleted %v), want %q", s.Name, got.SQL, got.DeletedAt.Valid, s.SQL)
+				}
+				if !written && got.ID != 0 && got.SQL != "SELECT 1" {
+					t.Errorf("服务 %s 不应被修改, sql = %q", s.Name, got.SQL)
+				}
+			}
+		})
+	}
+}
+
+func TestImportBundleReusesDeletedRecord(t *testing.T) {
+	db := setupBundleDB(t)
+	var old models.APIService
+	if err := db.Unscoped().Where("name = ?", "gone").First(&old).Error; err != nil {
+		t.Fatal(err)
+	}
+	bundle := &ServiceBundle{Version: BundleVersion, Services: []BundleService{{Name: "gone", Method: "GET", Path: "/gone", SQL: "SELECT 2"}}}
+	if _, err := ImportBundle(db, bundle, ConflictFail, false); err != nil {
+		t.Fatal(err)
+	}
+	var got models.APIService
+	if err := db.Where("name = ?", "gone").First(&got).Error; err != nil {
+		t.Fatalf("导入后服务不可见: %v", err)
+	}
+	if got.ID != old.ID || !got.CreatedAt.Equal(old.CreatedAt) {
+		t.Errorf("service id = %d, created_at = %v; want reused record %d, %v", got.ID, got.CreatedAt, old.ID, old.CreatedAt)
+	}
+}
+
+func TestImportBundleInvalid(t *testing.T) {
+	db := setupBundleDB(t)
+	tests := []struct {
+		name     string
+		strategy string
+		services []BundleService
+		wantErrs int // BundleValidationError 中的错误数，0 表示其他错误
+	}{
+		{"未知策略", "merge", []BundleService{{Name: "a", Method: "GET", Path: "/a", SQL: "SELECT 1"}}, 0},
+		{"服务无效", ConflictFail, []BundleService{{Name: "a", Method: "GET", Path: "/a", SQL: "SELECT * FROM t WHERE id = ?"}}, 1},
+		{"包内名称与路径重复", ConflictFail, []BundleService{
+			{Name: "a", Method: "GET", Path: "/a", SQL: "SELECT 1"},
+			{Name: "a", Method: "GET", Path: "/a", SQL: "SELECT 2"},
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := ImportBundle(db, &ServiceBundle{Version: BundleVersion, Services: tt.services}, tt.strategy, false)
+			if err == nil || plan != nil {
+				t.Fatalf("ImportBundle() = %+v, %v; want error", plan, err)
+			}
+			var verr *BundleValidationError
+			if errors.As(err, &verr) != (tt.wantErrs > 0) || (verr != nil && len(verr.Errors) != tt.wantErrs) {
+				t.Errorf("ImportBundle() error = %v, want %d validation errors", err, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestImportBundleReleasesDeletedPathOwner(t *testing.T) {
+	db := setupTestDB(t)
+	createTestService(t, db, &models.APIService{Name: "old_report", Method: "GET", Path: "/report", SQL: "SELECT 1"})
+	if err := db.Where("name = ?", "old_report").Delete(&models.APIService{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	bundle := &ServiceBundle{Version: BundleVersion, Services: []BundleService{
+		{Name: "report", Method: "GET", Path: "/report", SQL: "SELECT 2"},
+	}}
+
+	for _, strategy := range []string{ConflictFail, ConflictSkip} {
+		plan, err := ImportBundle(db, bundle, strategy, true)
+		if err != nil {
+			t.Fatalf("ImportBundle(%s, dry-run) error = %v", strategy, err)
+		}
+		if c := plan.Changes[0]; c.Action != BundleActionCreate || c.Reason != "释放已删除服务 old_report 占用的路径" {
+			t.Errorf("ImportBundle(%s, dry-run) change = %+v, want create releasing old_report", strategy, c)
+		}
+	}
+
+	if _, err := ImportBundle(db, bundle, ConflictFail, false); err != nil {
+		t.Fatalf("ImportBundle() error = %v", err)
+	}
+	var services []models.APIService
+	if err := db.Unscoped().Where("path = ?", "/report").Find(&services).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].Name != "report" || services[0].DeletedAt.Valid {
+		t.Errorf("services at /report = %+v, want only the live report service", services)
+	}
+}
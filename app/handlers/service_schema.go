@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+// ServiceParam 是动态服务的一个请求参数，Type 为 JSON Schema 类型 (integer | number | boolean | string)
+type ServiceParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ResultColumn 是动态服务结果集中的一列。Type 为 JSON Schema 类型，无法推断时为空（任意类型）；
+// Format 为 JSON Schema 格式 (int64、double、date、date-time、byte)
+type ResultColumn struct {
+	Name         string `json:"name"`
+	DatabaseType string `json:"database_type,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Format       string `json:"format,omitempty"`
+	Nullable     bool   `json:"nullable"`
+}
+
+// ServiceSchema 描述一个可调用的动态服务: 调用路径、参数与结果列。
+// 结果列无法推断时 Columns 为空，ColumnsError 说明原因
+type ServiceSchema struct {
+	Service      *models.APIService `json:"service"`
+	RunPath      string             `json:"run_path"`
+	Params       []ServiceParam     `json:"params"`
+	Columns      []ResultColumn     `json:"columns"`
+	ColumnsError string             `json:"columns_error,omitempty"`
+}
+
+// RunPathPrefix 是动态服务执行路由的前缀
+const RunPathPrefix = "/api/v1/dynamic/run"
+
+// columnCache 按服务 ID 与更新时间缓存推断出的结果列，服务定义变化后自动失效
+var columnCache sync.Map
+
+// DescribeActiveServices 返回当前可调用（发布状态为 active 或 deprecated 且处于生效时间窗口内）的
+// 全部动态服务的参数与结果列，按路径与方法排序。结果列通过在只读事务中执行不返回行的包装查询推断
+func DescribeActiveServices(ctx context.Context, db *gorm.DB) ([]ServiceSchema, error) {
+	var services []models.APIService
+	if err := db.WithContext(ctx).Order("path, method").Find(&services).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	schemas := make([]ServiceSchema, 0, len(services))
+	for i := range services {
+		s := &services[i]
+		if status, _ := serviceAvailability(s, now); status != http.StatusOK {
+			continue
+		}
+		def := bundleServiceFromModel(s)
+		schema := ServiceSchema{Service: s, RunPath: RunPathPrefix + s.Path, Params: []ServiceParam{}}
+		for j, key := range def.ParamKeys {
+			t := "string"
+			if j < len(def.ParamTypes) {
+				t = paramSchemaType(def.ParamTypes[j])
+			}
+			schema.Params = append(schema.Params, ServiceParam{Name: key, Type: t})
+		}
+		columns, err := describeResultColumns(ctx, s)
+		if err != nil {
+			slog.WarnContext(ctx, "推断动态服务结果列失败", "service", s.Name, "error", err)
+			schema.ColumnsError = err.Error()
+		}
+		schema.Columns = columns
+		schemas = append(schemas, schema)
+	}
+	sort.SliceStable(schemas, func(i, j int) bool { return schemas[i].RunPath < schemas[j].RunPath })
+	return schemas, nil
+}
+
+// paramSchemaType 将 ParamTypes 中的参数类型映射为 JSON Schema 类型，未知类型与 convertParams 一致按 string 处理
+func paramSchemaType(t string) string {
+	switch strings.ToLower(t) {
+	case "int", "int64":
+		return "integer"
+	case "float", "float64":
+		return "number"
+	case "bool":
+		return "boolean"
+	}
+	return "string"
+}
+
+// zeroParam 返回参数类型的零值，用于推断结果列时填充占位符
+func zeroParam(t string) interface{} {
+	switch paramSchemaType(t) {
+	case "integer":
+		return int64(0)
+	case "number":
+		return float64(0)
+	case "boolean":
+		return false
+	}
+	return ""
+}
+
+// describeResultColumns 推断服务结果集的列: 将 SELECT/WITH 查询包装为 SELECT * FROM (...) WHERE 1 = 0，
+// 以参数零值在只读事务中执行并读取列元数据，不返回任何行。其他语句 (SHOW、EXPLAIN 等) 无法推断，返回空。
+// 结果按服务 ID 与更新时间缓存
+func describeResultColumns(ctx context.Context, service *models.APIService) ([]ResultColumn, error) {
+	driver := config.DatasourceDriver(service.Datasource)
+	tokens := tokenizeSQL(service.SQL, driver)
+	if len(tokens) == 0 || tokens[0].kind != tokenWord ||
+		!(strings.EqualFold(tokens[0].text, "SELECT") || strings.EqualFold(tokens[0].text, "WITH")) {
+		return nil, nil
+	}
+
+	cacheKey := fmt.Sprintf("%d/%d", service.ID, service.UpdatedAt.UnixNano())
+	if cached, ok := columnCache.Load(cacheKey); ok {
+		return cached.([]ResultColumn), nil
+	}
+
+	def := bundleServiceFromModel(service)
+	args := make([]interface{}, len(def.ParamKeys))
+	for i := range args {
+		t := ""
+		if i < len(def.ParamTypes) {
+			t = def.ParamTypes[i]
+		}
+		args[i] = zeroParam(t)
+	}
+	sqlText, args, err := bindPlaceholders(service.SQL, driver, args)
+	if err != nil {
+		return nil, err
+	}
+	sqlText = strings.TrimRight(strings.TrimSpace(sqlText), ";")
+	probe := "SELECT * FROM (" + sqlText + "\n) schema_probe WHERE 1 = 0"
+
+	target, err := config.ReadDatasource(service.Datasource, service.RequiresFreshData)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, config.Current().Dynamic.QueryTimeout)
+	defer cancel()
+
+	var columns []ResultColumn
+	err = readOnlyRollback(target.WithContext(ctx), driver, func(tx *gorm.DB) error {
+		rows, err := tx.Raw(probe, args...).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		types, err := rows.ColumnTypes()
+		if err != nil {
+			return err
+		}
+		for _, ct := range types {
+			col := ResultColumn{Name: ct.Name(), DatabaseType: strings.ToUpper(ct.DatabaseTypeName()), Nullable: true}
+			col.Type, col.Format = columnSchemaType(col.DatabaseType)
+			if nullable, ok := ct.Nullable(); ok {
+				col.Nullable = nullable
+			}
+			columns = append(columns, col)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	columnCache.Store(cacheKey, columns)
+	return columns, nil
+}
+
+// columnSchemaType 将数据库列类型名映射为 JSON Schema 类型与格式，无法识别时返回空（任意类型）
+func columnSchemaType(dbType string) (string, string) {
+	switch {
+	case dbType == "":
+		return "", ""
+	case dbType == "BOOL" || dbType == "BOOLEAN":
+		return "boolean", ""
+	case strings.HasPrefix(dbType, "INT") && dbType != "INTERVAL", strings.HasSuffix(dbType, "INT"),
+		strings.Contains(dbType, "INTEGER"), strings.HasSuffix(dbType, "SERIAL"):
+		return "integer", "int64"
+	case strings.Contains(dbType, "DECIMAL") || strings.Contains(dbType, "NUMERIC") || strings.Contains(dbType, "REAL") ||
+		strings.Contains(dbType, "FLOAT") || strings.Contains(dbType, "DOUBLE"):
+		return "number", "double"
+	case dbType == "DATE":
+		return "string", "date"
+	case strings.Contains(dbType, "TIMESTAMP") || strings.Contains(dbType, "DATETIME"):
+		return "string", "date-time"
+	case strings.Contains(dbType, "BLOB") || strings.Contains(dbType, "BINARY") || dbType == "BYTEA":
+		return "string", "byte"
+	case strings.Contains(dbType, "JSON"):
+		return "", ""
+	}
+	return "string", ""
+}
@@ -33,7 +33,8 @@ func CreateUser(c *gin.Context) {
 // GetUsers 处理获取所有用户请求
 func GetUsers(c *gin.Context) {
 	var users []models.User
-	config.DB.Find(&users)
+	// 【修改】只读查询路由到健康的只读副本，无可用副本时回退到主库
+	config.ReadDB(false).Find(&users)
 	// 【修改】使用统一的 APIResponse 结构
 	c.JSON(http.StatusOK, utils.APIResponse{Code: 0, Message: "查询成功", Data: users})
 }
@@ -49,7 +50,7 @@ func GetUserByID(c *gin.Context) {
 	
 	var user models.User
 
-	if err := config.DB.First(&user, id).Error; err != nil {
+	if err := config.ReadDB(false).First(&user, id).Error; err != nil {
 		// 【修改】使用统一的 APIResponse 结构
 		c.JSON(http.StatusNotFound, utils.APIResponse{Code: 404, Message: "用户未找到"})
 		return
@@ -0,0 +1,157 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/utils"
+)
+
+// CheckFunc 是一项就绪检查，返回 nil 表示健康
+type CheckFunc func(ctx context.Context) error
+
+// Result 是单项检查的结果
+type Result struct {
+	Status    string `json:"status"` // ok | fail
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	Optional  bool   `json:"optional,omitempty"` // 可选检查失败不影响整体就绪状态
+}
+
+var (
+	mu     sync.RWMutex
+	checks = map[string]CheckFunc{}
+	// optional 记录可选检查的名称
+	optional = map[string]bool{}
+
+	// started 标记应用是否已完成启动（数据库连接、迁移与后台组件初始化）
+	started atomic.Bool
+
+	// shuttingDown 标记应用正在优雅关闭，此后就绪检查失败、业务接口不再接收新请求
+	shuttingDown atomic.Bool
+)
+
+// ErrStarting 表示应用仍在启动中（例如正在等待数据库）
+var ErrStarting = errors.New("应用正在启动，数据库尚未就绪")
+
+// ErrShuttingDown 表示应用正在关闭
+var ErrShuttingDown = errors.New("应用正在关闭")
+
+// Register 注册一项就绪检查，同名检查会被覆盖
+func Register(name string, fn CheckFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks[name] = fn
+	delete(optional, name)
+}
+
+// RegisterOptional 注册一项可选检查：结果会出现在 /readyz 中，但失败不会使实例变为未就绪。
+// 适用于只影响部分功能的依赖（例如附加数据源）。
+func RegisterOptional(name string, fn CheckFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks[name] = fn
+	optional[name] = true
+}
+
+// SetStarted 标记应用已完成启动，此后业务接口才会接收请求
+func SetStarted(v bool) {
+	started.Store(v)
+}
+
+// SetShuttingDown 标记应用开始优雅关闭，负载均衡器据此通过 /readyz 摘除实例
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// ShuttingDown 返回应用是否正在关闭
+func ShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// Started 返回应用是否已完成启动
+func Started() bool {
+	return started.Load()
+}
+
+// Run 并发执行全部就绪检查，每项检查的超时时间为 timeout。返回是否全部通过及每项结果。
+func Run(ctx context.Context, timeout time.Duration) (bool, map[string]Result) {
+	mu.RLock()
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fns := make([]CheckFunc, len(names))
+	opts := make([]bool, len(names))
+	for i, name := range names {
+		fns[i] = checks[name]
+		opts[i] = optional[name]
+	}
+	mu.RUnlock()
+
+	results := make(map[string]Result, len(names)+1)
+	switch {
+	case ShuttingDown():
+		results["startup"] = Result{Status: "fail", Error: ErrShuttingDown.Error()}
+	case !Started():
+		results["startup"] = Result{Status: "fail", Error: ErrStarting.Error()}
+	default:
+		results["startup"] = Result{Status: "ok"}
+	}
+
+	var wg sync.WaitGroup
+	var resMu sync.Mutex
+	for i := range names {
+		wg.Add(1)
+		go func(name string, fn CheckFunc, opt bool) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := fn(cctx)
+			r := Result{Status: "ok", LatencyMs: time.Since(start).Milliseconds(), Optional: opt}
+			if err != nil {
+				r.Status = "fail"
+				r.Error = err.Error()
+			}
+			resMu.Lock()
+			results[name] = r
+			resMu.Unlock()
+		}(names[i], fns[i], opts[i])
+	}
+	wg.Wait()
+
+	ok := true
+	for _, r := range results {
+		if r.Status != "ok" && !r.Optional {
+			ok = false
+		}
+	}
+	return ok, results
+}
+
+// RequireStarted 在应用完成启动前对业务接口返回 503，避免在数据库未就绪时访问 config.DB；
+// 优雅关闭期间同样拒绝新请求（复用的 keep-alive 连接上仍可能到达新请求）
+func RequireStarted() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ShuttingDown() {
+			c.Header("Connection", "close")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, utils.APIResponse{Code: 503, Message: ErrShuttingDown.Error()})
+			return
+		}
+		if !Started() {
+			c.Header("Retry-After", "5")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, utils.APIResponse{Code: 503, Message: ErrStarting.Error()})
+			return
+		}
+		c.Next()
+	}
+}
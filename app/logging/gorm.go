@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger 将 GORM 日志输出为结构化的 slog 日志：
+// 普通 SQL 为 Debug 级别，慢查询为 Warn，执行错误为 Error（记录不存在不视为错误）。
+type GormLogger struct {
+	// SlowThreshold 慢查询阈值，0 表示不记录慢查询
+	SlowThreshold time.Duration
+	// LogParams 为 false 时 SQL 中的参数以 ? 占位符输出，避免敏感数据进入日志
+	LogParams bool
+}
+
+// NewGormLogger 创建 GORM 日志适配器
+func NewGormLogger(slowThreshold time.Duration, logParams bool) *GormLogger {
+	return &GormLogger{SlowThreshold: slowThreshold, LogParams: logParams}
+}
+
+// LogMode 实现 logger.Interface。日志级别统一由 slog 控制，此处忽略。
+func (l *GormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	slog.InfoContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	slog.WarnContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	slog.ErrorContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+}
+
+// ParamsFilter 实现 gorm.ParamsFilter：未开启 LogParams 时不将参数值嵌入日志中的 SQL
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if !l.LogParams {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// Trace 实现 logger.Interface，在每条 SQL 执行后调用
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		if !slog.Default().Enabled(ctx, slog.LevelError) {
+			return
+		}
+		sql, rows := fc()
+		slog.ErrorContext(ctx, "SQL 执行失败", "component", "gorm", "sql", sql, "rows", rows,
+			"duration_ms", elapsed.Milliseconds(), "error", err)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold:
+		if !slog.Default().Enabled(ctx, slog.LevelWarn) {
+			return
+		}
+		sql, rows := fc()
+		slog.WarnContext(ctx, "慢查询", "component", "gorm", "sql", sql, "rows", rows,
+			"duration_ms", elapsed.Milliseconds(), "slow_threshold_ms", l.SlowThreshold.Milliseconds())
+	default:
+		if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+			return
+		}
+		sql, rows := fc()
+		slog.DebugContext(ctx, "SQL", "component", "gorm", "sql", sql, "rows", rows,
+			"duration_ms", elapsed.Milliseconds())
+	}
+}
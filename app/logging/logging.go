@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDKey 是请求 ID 在 context.Context 中的键
+type requestIDKey struct{}
+
+// WithRequestID 返回携带请求 ID 的 context，使用该 context 输出的日志会自动带上 request_id 字段
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext 返回 context 中的请求 ID，不存在时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler 在每条日志中追加 context 中的 request_id 以及当前 span 的 trace_id/span_id
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// level 是全局日志级别，可在运行时通过 SetLevel 调整
+var level = new(slog.LevelVar)
+
+// ParseLevel 解析日志级别字符串 (debug | info | warn | error)
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("未知的日志级别: %s", s)
+	}
+	return l, nil
+}
+
+// SetLevel 调整全局日志级别
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// Init 初始化全局 slog 日志器。format 为 json（默认）或 text，level 为 debug | info | warn | error。
+// 标准库 log 包的输出也会被重定向到 slog，保证第三方库的日志同样是结构化的。
+func Init(format, levelName string) error {
+	return InitWithWriter(os.Stdout, format, levelName)
+}
+
+// InitWithWriter 与 Init 相同，但输出到指定的 writer
+func InitWithWriter(w io.Writer, format, levelName string) error {
+	l, err := ParseLevel(levelName)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("未知的日志格式: %s", format)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{h}))
+	// slog.SetDefault 会将标准库 log 的输出转发到 slog (Info 级别)，此处去掉重复的时间前缀
+	log.SetFlags(0)
+	return nil
+}
+
+// lineWriter 将写入的每一行作为一条 slog 日志输出，用于接管 gin 等库的文本输出
+type lineWriter struct {
+	level     slog.Level
+	component string
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			slog.Log(context.Background(), w.level, line, "component", w.component)
+		}
+	}
+	return len(p), nil
+}
+
+// NewWriter 返回一个 io.Writer，写入的每一行都会以指定级别输出为结构化日志
+func NewWriter(level slog.Level, component string) io.Writer {
+	return lineWriter{level: level, component: component}
+}
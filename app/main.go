@@ -1,68 +1,332 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"go-gin-gorm-api/app/audit"
 	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/health"
+	"go-gin-gorm-api/app/logging"
+	"go-gin-gorm-api/app/metrics"
 	"go-gin-gorm-api/app/router"
+	"go-gin-gorm-api/app/servicedir"
+	"go-gin-gorm-api/app/tracing"
+	"gorm.io/gorm"
 )
 
-// 【新增】Config 应用程序的配置结构体，包含数据库和应用端口信息
-type Config struct {
-	DBUser  string
-	DBPass  string
-	DBHost  string
-	DBPort  string
-	DBName  string
-	AppPort int
+// defaultConfigFile 是未设置 CONFIG_FILE 时尝试加载的配置文件
+const defaultConfigFile = "config.yaml"
+
+// 【修改】configPath 返回配置文件路径: 优先使用 CONFIG_FILE，其次为当前目录下存在的 config.yaml，否则仅使用环境变量
+func configPath() string {
+	if p := os.Getenv("CONFIG_FILE"); p != "" {
+		return p
+	}
+	if _, err := os.Stat(defaultConfigFile); err == nil {
+		return defaultConfigFile
+	}
+	return ""
 }
 
-// 【新增】实现 config.DBConfig 接口方法，用于解耦
-func (c *Config) GetDBUser() string { return c.DBUser }
-func (c *Config) GetDBPass() string { return c.DBPass }
-func (c *Config) GetDBHost() string { return c.DBHost }
-func (c *Config) GetDBPort() string { return c.DBPort }
-func (c *Config) GetDBName() string { return c.DBName }
+func main() {
+	// 【修改】1. 加载配置: 默认值 → 配置文件 → 环境变量 (含 .env，用于本地开发)，并校验
+	dotEnvLoaded := godotenv.Load() == nil
+	cfgPath := configPath()
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		slog.Error("加载配置失败", "file", cfgPath, "error", err)
+		os.Exit(1)
+	}
+	config.SetCurrent(cfg)
+
+	// 初始化结构化日志，此后所有日志（包括标准库 log 与 GORM）均以 slog 输出
+	if err := logging.Init(cfg.Logging.Format, cfg.Logging.Level); err != nil {
+		slog.Error("日志初始化失败", "error", err)
+		os.Exit(1)
+	}
+	if !dotEnvLoaded {
+		slog.Info("未找到 .env 文件，使用系统环境变量")
+	}
+	if cfgPath != "" {
+		slog.Info("已加载配置文件", "file", cfgPath)
+	}
+
+	// 初始化链路追踪（需在数据库与路由之前完成）
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		slog.Error("链路追踪初始化失败", "error", err)
+		os.Exit(1)
+	}
+
+	// 审计哈希链: 配置 AUDIT_HMAC_KEY 后每条审计记录都会链接上一条记录的哈希
+	audit.ConfigureChain(cfg.Audit.HMACKey, cfg.Audit.CheckpointFile)
+
+	// 命令行子命令 (migrate、service、audit、apikey、user): 执行后退出，未指定或 serve 时启动 HTTP 服务
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		code := runCommand(cfg, os.Args[1:])
+		shutdownTracing(context.Background())
+		os.Exit(code)
+	}
+	if !audit.ChainEnabled() {
+		slog.Warn("未配置 AUDIT_HMAC_KEY，审计哈希链未启用")
+	}
+
+	// 注册就绪检查: 数据库连通性、迁移状态、审计写入器与附加数据源
+	registerHealthChecks(cfg)
+
+	// 【修改】2. 初始化数据库，将配置结构体传递给 InitDatabase。
+	// 数据库就绪后启动后台审计写入器、保留策略与检查点任务
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+
+	// 配置热加载: SIGHUP 或配置文件变更时更新动态服务限制、日志级别与 CORS
+	config.Watch(bgCtx, cfgPath, func(c *config.Config) {
+		if level, err := logging.ParseLevel(c.Logging.Level); err == nil {
+			logging.SetLevel(level)
+		}
+	})
+	if cfg.Database.WaitOnStartup {
+		go waitForDatabase(bgCtx, cfg)
+	} else {
+		config.InitDatabase(cfg)
+		onDatabaseReady(bgCtx, cfg)
+	}
+
+	// 4. 初始化路由
+	r := router.InitRouter()
+
+	// 【修改】5. 使用 http.Server 运行服务，以便设置超时并支持优雅关闭。
+	// 所有请求的 context 均派生自 requestCtx，关闭超时后取消它即可中断仍在执行的查询。
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	inflight := newInflightRequests()
+	srv := &http.Server{
+		Addr: ":" + strconv.Itoa(cfg.Server.Port),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !inflight.acquire() {
+				w.Header().Set("Connection", "close")
+				http.Error(w, "服务正在关闭", http.StatusServiceUnavailable)
+				return
+			}
+			defer inflight.release()
+			r.ServeHTTP(w, req)
+		}),
+		ReadHeaderTimeout: cfg.Server.ReadTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		BaseContext:       func(net.Listener) context.Context { return requestCtx },
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("服务器正在运行", "port", cfg.Server.Port)
+		serverErr <- srv.ListenAndServe()
+	}()
 
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serverErr:
+		slog.Error("服务器启动失败", "error", err)
+		os.Exit(1)
+	case s := <-sig:
+		slog.Info("收到退出信号，开始优雅关闭", "signal", s.String(), "timeout", cfg.Server.ShutdownTimeout.String())
+	}
+	signal.Stop(sig)
 
-// 【修改】loadConfig 从环境变量加载配置
-func loadConfig() *Config {
-	// 1. 加载 .env 文件 (用于本地开发)
-	if err := godotenv.Load(); err != nil {
-		log.Println("未找到 .env 文件，使用系统环境变量")
+	// 6. 优雅关闭: 停止接收新请求并由 srv.Shutdown 等待进行中的请求完成，
+	// 超时后取消剩余请求的查询，并等待这些处理函数返回后再关闭数据库连接
+	health.SetShuttingDown()
+	stopBackground()
+	inflight.close()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	err = srv.Shutdown(ctx)
+	cancel()
+	if err != nil {
+		slog.Warn("等待进行中的请求超时，取消剩余查询", "error", err)
+		cancelRequests()
+		if !inflight.wait(5 * time.Second) {
+			slog.Error("仍有请求未能在取消后结束")
+		}
 	}
+	cancelRequests()
 
-	appPort := 8080
-	if p, err := strconv.Atoi(os.Getenv("PORT")); err == nil {
-		appPort = p
+	// 将队列中的审计记录写入数据库（启动完成前审计写入器尚未创建，无需刷新）
+	if health.Started() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := audit.DefaultWriter.Close(ctx)
+		cancel()
+		if err != nil {
+			slog.Error("审计记录刷新超时，可能有记录丢失", "error", err)
+		}
 	}
 
-	return &Config{
-		DBUser:  os.Getenv("MYSQL_USER"),
-		DBPass:  os.Getenv("MYSQL_PASSWORD"),
-		DBHost:  os.Getenv("MYSQL_HOST"),
-		DBPort:  os.Getenv("MYSQL_PORT"),
-		DBName:  os.Getenv("MYSQL_DATABASE"),
-		AppPort: appPort,
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("链路追踪导出器关闭失败", "error", err)
 	}
+	cancel()
+
+	config.CloseReplicas()
+	config.CloseDatasources()
+	if err := config.CloseDatabase(); err != nil {
+		slog.Error("关闭数据库连接失败", "error", err)
+	}
+	slog.Info("服务器已退出")
 }
 
-func main() {
-	// 【修改】1. 加载配置
-	cfg := loadConfig()
+// inflightRequests 统计正在执行的请求。srv.Shutdown 超时返回时处理函数可能仍在运行，
+// 关闭数据库连接前需要等待它们结束。close 之后 acquire 拒绝新请求，计数只减不增，
+// 因此 wait 不会与新请求的计数增加并发 (sync.WaitGroup 不允许计数为零时 Add 与 Wait 并发)
+type inflightRequests struct {
+	mu      sync.Mutex
+	n       int
+	closing bool
+	idle    chan struct{} // closing 且计数为零时关闭
+}
 
-	// 【修改】2. 初始化数据库，将配置结构体传递给 InitDatabase
-	config.InitDatabase(cfg)
+func newInflightRequests() *inflightRequests {
+	return &inflightRequests{idle: make(chan struct{})}
+}
 
-	// 3. 初始化路由
-	r := router.InitRouter()
+// acquire 登记一个新请求，开始关闭后返回 false
+func (r *inflightRequests) acquire() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closing {
+		return false
+	}
+	r.n++
+	return true
+}
+
+// release 结束 acquire 登记的请求
+func (r *inflightRequests) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.n--
+	if r.closing && r.n == 0 {
+		close(r.idle)
+	}
+}
+
+// close 停止登记新请求
+func (r *inflightRequests) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closing {
+		return
+	}
+	r.closing = true
+	if r.n == 0 {
+		close(r.idle)
+	}
+}
+
+// wait 在 close 之后等待已登记的请求全部结束，最多等待 d，返回是否在超时前完成
+func (r *inflightRequests) wait(d time.Duration) bool {
+	select {
+	case <-r.idle:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+// registerHealthChecks 注册 /readyz 使用的就绪检查
+func registerHealthChecks(cfg *config.Config) {
+	health.Register("database", config.PingDatabase)
+	health.Register("migrations", func(ctx context.Context) error {
+		if !config.MigrationsCompleted() {
+			return errors.New("数据库迁移尚未完成")
+		}
+		return nil
+	})
+	health.Register("audit_writer", func(ctx context.Context) error {
+		// 先检查启动标记，保证读取 DefaultWriter 时其已完成初始化
+		if !health.Started() {
+			return errors.New("审计写入器尚未启动")
+		}
+		return audit.DefaultWriter.Health()
+	})
+	// 附加数据源只影响引用它的动态服务，作为可选检查不影响实例整体就绪状态
+	for name := range cfg.Datasources {
+		name := name
+		health.RegisterOptional("datasource:"+name, func(ctx context.Context) error {
+			return config.PingDatasource(ctx, name)
+		})
+	}
+	// 只读副本全部不可用时读请求回退到主库，同样作为可选检查
+	if len(cfg.Database.Replicas) > 0 {
+		health.RegisterOptional("replicas", func(ctx context.Context) error {
+			for _, r := range config.Replicas() {
+				if r.Healthy {
+					return nil
+				}
+			}
+			return errors.New("没有可用的只读副本，读请求已回退到主库")
+		})
+	}
+}
+
+// waitForDatabase 在后台按指数退避持续重试连接数据库（不受 DBRetryMaxWait 限制），成功后完成剩余的启动步骤
+func waitForDatabase(ctx context.Context, cfg *config.Config) {
+	if err := config.ConnectWithRetry(ctx, cfg, 0); err != nil {
+		if ctx.Err() == nil {
+			// 配置错误等不可重试的错误
+			slog.Error("数据库初始化失败", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	onDatabaseReady(ctx, cfg)
+}
 
-	// 【修改】4. 运行服务
-	log.Printf("服务器正在端口 %d 上运行...", cfg.AppPort)
-	if err := r.Run(":" + strconv.Itoa(cfg.AppPort)); err != nil {
-		log.Fatalf("服务器启动失败: %v", err)
+// onDatabaseReady 在数据库连接与迁移完成后启动依赖数据库的组件，并标记应用启动完成
+func onDatabaseReady(ctx context.Context, cfg *config.Config) {
+	if err := tracing.RegisterGORM(config.DB); err != nil {
+		slog.Error("注册 GORM 链路追踪回调失败", "error", err)
+		os.Exit(1)
 	}
+
+	// 3. 启动后台审计写入器、保留策略与检查点任务
+	audit.DefaultWriter = audit.NewWriter(config.DB, cfg.Audit.Config)
+	metrics.RegisterAuditWriter(audit.DefaultWriter)
+	metrics.RegisterDBStats(config.DB, "default")
+	audit.StartRetention(ctx, config.DB, cfg.Audit.Config)
+	audit.StartCheckpoints(ctx, config.DB, cfg.Audit.Config)
+
+	// 连接动态服务使用的附加数据源，不可用时在后台重试，不阻塞启动
+	config.OpenDatasources(ctx, cfg, func(name string, db *gorm.DB) {
+		if err := tracing.RegisterGORM(db); err != nil {
+			slog.Error("注册 GORM 链路追踪回调失败", "datasource", name, "error", err)
+		}
+		metrics.RegisterDBStats(db, name)
+	})
+
+	// 连接只读副本并定期检查健康状态，用户查询与动态服务的只读查询按轮询路由到健康副本
+	config.StartReplicas(ctx, cfg, func(name string, db *gorm.DB) {
+		if err := tracing.RegisterGORM(db); err != nil {
+			slog.Error("注册 GORM 链路追踪回调失败", "replica", name, "error", err)
+		}
+		metrics.RegisterDBStats(db, name)
+	})
+
+	// 声明式服务定义目录 (GitOps 模式): 启动时同步一次，之后监听文件变更
+	if cfg.Dynamic.ServicesDir != "" {
+		servicedir.Start(ctx, config.DB, cfg.Dynamic.ServicesDir, cfg.Dynamic.ServicesSyncInterval)
+	}
+
+	health.SetStarted(true)
+	slog.Info("应用启动完成")
 }
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go-gin-gorm-api/app/audit"
+	"gorm.io/gorm"
+)
+
+const namespace = "gin_gorm_api"
+
+// unmatchedRoute 是未匹配任何路由的请求使用的 route 标签，避免任意 URL 导致标签基数膨胀
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP 请求总数，按方法、路由模板和状态码区分。",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP 请求处理耗时（秒），按方法和路由模板区分。",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	dynamicExecutions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dynamic_executions_total",
+		Help:      "动态服务执行次数，按服务名称和执行结果 (outcome) 区分。",
+	}, []string{"service", "outcome"})
+
+	dynamicDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "dynamic_execution_duration_seconds",
+		Help:      "动态服务 SQL 执行耗时（秒），按服务名称和执行结果区分。",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"service", "outcome"})
+
+	dynamicRows = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "dynamic_execution_rows",
+		Help:      "动态服务查询返回的行数（截断前），按服务名称区分。",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+	}, []string{"service"})
+
+	blockedQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dynamic_blocked_queries_total",
+		Help:      "被只读安全策略拦截的动态服务执行次数，按服务名称区分。",
+	}, []string{"service"})
+)
+
+// Middleware 记录每个 HTTP 请求的计数与耗时。route 标签使用 gin 的路由模板 (c.FullPath)。
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler 返回 Prometheus 文本格式的 /metrics 处理函数
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// ObserveDynamicExecution 记录一次动态服务执行。
+// service 为空（例如服务不存在）时使用 "unknown"，避免请求路径进入标签导致基数膨胀。
+func ObserveDynamicExecution(service, outcome string, duration time.Duration, rows int, blocked bool) {
+	if service == "" {
+		service = "unknown"
+	}
+	dynamicExecutions.WithLabelValues(service, outcome).Inc()
+	dynamicDuration.WithLabelValues(service, outcome).Observe(duration.Seconds())
+	if rows > 0 {
+		dynamicRows.WithLabelValues(service).Observe(float64(rows))
+	}
+	if blocked {
+		blockedQueries.WithLabelValues(service).Inc()
+	}
+}
+
+// RegisterDBStats 注册 database/sql 连接池统计 (打开/使用中/空闲连接数、等待次数与时长等)
+func RegisterDBStats(db *gorm.DB, name string) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		slog.Error("注册数据库连接池指标失败", "error", err)
+		return
+	}
+	prometheus.MustRegister(collectors.NewDBStatsCollector(sqlDB, name))
+}
+
+// RegisterAuditWriter 注册后台审计写入器的队列深度、容量与写入/丢弃/失败计数
+func RegisterAuditWriter(w *audit.Writer) {
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "audit_queue_depth",
+			Help:      "审计写入器队列中待写入的记录数。",
+		}, func() float64 { return float64(w.QueueDepth()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "audit_queue_capacity",
+			Help:      "审计写入器队列容量。",
+		}, func() float64 { return float64(w.QueueCapacity()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "audit_written_total",
+			Help:      "已成功写入数据库的审计记录数。",
+		}, func() float64 { return float64(w.Written()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "audit_dropped_total",
+			Help:      "因队列满或写入器关闭而被丢弃的审计记录数。",
+		}, func() float64 { return float64(w.Dropped()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "audit_failed_total",
+			Help:      "写入数据库失败的审计记录数。",
+		}, func() float64 { return float64(w.Failed()) }),
+	)
+}
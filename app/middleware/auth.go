@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/apikey"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/models"
+	"go-gin-gorm-api/app/utils"
+)
+
+// PrincipalKey 是认证中间件写入 gin.Context 的调用方标识键。
+// 处理函数（例如审计记录）通过 c.GetString(PrincipalKey) 读取调用方身份。
+const PrincipalKey = "principal"
+
+// APIKeyKey 是 OptionalAPIKey 写入 gin.Context 的已认证 API Key (*models.APIKey)
+const APIKeyKey = "api_key"
+
+// AdminPrincipal 是通过管理令牌认证的调用方标识
+const AdminPrincipal = "admin"
+
+// bearerToken 返回 Authorization 请求头中的 Bearer 令牌
+func bearerToken(c *gin.Context) string {
+	return strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
+
+// 【修改】AdminAuth 保护管理接口：要求请求头携带 "Authorization: Bearer <ADMIN_API_TOKEN>"，
+// 或拥有 admin 权限范围的 API Key (app apikey create --scopes admin)。
+// 未配置 ADMIN_API_TOKEN 且未携带 API Key 时管理接口整体关闭（失败即拒绝），避免审计数据被匿名读取。
+func AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := config.Current().Auth.AdminToken
+		provided := bearerToken(c)
+
+		if apikey.IsAPIKey(provided) {
+			key, ok := authenticateKey(c, provided, models.ScopeAdmin)
+			if !ok {
+				return
+			}
+			c.Set(PrincipalKey, apikey.Principal(key))
+			c.Next()
+			return
+		}
+
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, utils.APIResponse{Code: 403, Message: "管理接口未启用：未配置 ADMIN_API_TOKEN"})
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, utils.APIResponse{Code: 401, Message: "管理令牌无效或缺失"})
+			return
+		}
+
+		c.Set(PrincipalKey, AdminPrincipal)
+		c.Next()
+	}
+}
+
+// OptionalAPIKey 识别动态服务调用方: 携带 API Key (Bearer ggk_...) 时要求其有效且拥有 run 权限范围，
+// 并将 Key 名称写入 PrincipalKey 供审计记录使用；未携带 API Key 的请求按匿名调用继续处理。
+func OptionalAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := bearerToken(c)
+		if !apikey.IsAPIKey(provided) {
+			c.Next()
+			return
+		}
+		key, ok := authenticateKey(c, provided, models.ScopeRun)
+		if !ok {
+			return
+		}
+		c.Set(PrincipalKey, apikey.Principal(key))
+		c.Set(APIKeyKey, key)
+		c.Next()
+	}
+}
+
+// HasScope 返回调用方携带的 API Key 是否拥有指定权限范围，匿名调用返回 false
+func HasScope(c *gin.Context, scope string) bool {
+	key, ok := c.Get(APIKeyKey)
+	if !ok {
+		return false
+	}
+	k, ok := key.(*models.APIKey)
+	return ok && k.HasScope(scope)
+}
+
+// authenticateKey 校验 API Key 及其权限范围，失败时写入 401/403 响应并返回 false
+func authenticateKey(c *gin.Context, raw, scope string) (*models.APIKey, bool) {
+	key, err := apikey.Authenticate(config.DB.WithContext(c.Request.Context()), raw)
+	if err != nil {
+		if !errors.Is(err, apikey.ErrInvalid) {
+			slog.ErrorContext(c.Request.Context(), "校验 API Key 失败", "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, utils.APIResponse{Code: 500, Message: "校验 API Key 失败"})
+			return nil, false
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, utils.APIResponse{Code: 401, Message: err.Error()})
+		return nil, false
+	}
+	if !key.HasScope(scope) {
+		c.AbortWithStatusJSON(http.StatusForbidden, utils.APIResponse{Code: 403, Message: "API Key 缺少权限范围: " + scope})
+		return nil, false
+	}
+	return key, true
+}
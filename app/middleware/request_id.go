@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/logging"
+)
+
+// RequestIDHeader 是请求 ID 的 HTTP 头
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey 是请求 ID 在 gin.Context 中的键
+const RequestIDKey = "request_id"
+
+// maxRequestIDLength 限制调用方传入的请求 ID 长度，防止日志与审计表被超长值污染
+const maxRequestIDLength = 64
+
+// validRequestID 检查调用方传入的请求 ID 是否只包含安全字符 [A-Za-z0-9._-]
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成 128 位随机请求 ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestID 接受调用方传入的 X-Request-ID（格式合法时），否则生成新的请求 ID。
+// 请求 ID 会写入响应头、gin.Context (RequestIDKey) 与请求 context，后续日志与审计记录都会带上它。
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
@@ -0,0 +1,227 @@
+// Package migrate 实现版本化的数据库迁移。
+//
+// 每个迁移是一段带版本号的 Go 代码 (见 migrations.go)，通过 GORM Migrator 或原生 SQL
+// 修改表结构并可以回填数据。已执行的版本记录在 schema_migrations 表中；执行迁移前会获取
+// 数据库级的咨询锁 (MySQL GET_LOCK / PostgreSQL pg_advisory_lock)，多个实例同时启动时
+// 只有一个实例执行迁移，其余实例等待锁释放后发现没有待执行的迁移直接返回。
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 是一个版本化迁移。Up 与 Down 在同一个事务中执行并更新 schema_migrations
+// (MySQL 的 DDL 会隐式提交，无法整体回滚，编写迁移时应保证可重复执行)。
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// Status 是单个迁移的执行状态
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// schemaMigration 是 schema_migrations 表的记录
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:191;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// lockName 与 pgLockKey 分别是 MySQL 与 PostgreSQL 的迁移咨询锁标识
+const (
+	lockName  = "go-gin-gorm-api.schema_migrations"
+	pgLockKey = int64(0x676f67696e6d6967) // "goginmig"
+)
+
+// LockTimeout 是等待其他实例释放迁移锁的最长时间
+var LockTimeout = 2 * time.Minute
+
+// ErrLockTimeout 表示在 LockTimeout 内未能获取迁移锁
+var ErrLockTimeout = errors.New("等待数据库迁移锁超时，可能有其他实例正在执行迁移")
+
+// All 返回按版本号升序排列的全部迁移
+func All() []Migration {
+	list := make([]Migration, len(migrations))
+	copy(list, migrations)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+// Latest 返回最新的迁移版本号
+func Latest() int64 {
+	all := All()
+	if len(all) == 0 {
+		return 0
+	}
+	return all[len(all)-1].Version
+}
+
+// Up 执行全部未执行的迁移，返回本次执行的迁移
+func Up(ctx context.Context, db *gorm.DB) ([]Migration, error) {
+	var done []Migration
+	err := withLock(ctx, db, func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range All() {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("执行迁移 %d_%s 失败: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本号从新到旧回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func Down(ctx context.Context, db *gorm.DB, steps int) ([]Migration, error) {
+	var done []Migration
+	err := withLock(ctx, db, func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		all := All()
+		for i := len(all) - 1; i >= 0 && len(done) < steps; i-- {
+			m := all[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == nil {
+				return fmt.Errorf("迁移 %d_%s 不支持回滚", m.Version, m.Name)
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("回滚迁移 %d_%s 失败: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// StatusOf 返回全部迁移的执行状态（按版本号升序）
+func StatusOf(ctx context.Context, db *gorm.DB) ([]Status, error) {
+	applied, err := appliedVersions(db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	all := All()
+	list := make([]Status, len(all))
+	for i, m := range all {
+		list[i] = Status{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			at := at
+			list[i].Applied = true
+			list[i].AppliedAt = &at
+		}
+	}
+	return list, nil
+}
+
+// Pending 返回尚未执行的迁移
+func Pending(ctx context.Context, db *gorm.DB) ([]Migration, error) {
+	applied, err := appliedVersions(db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, m := range All() {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// appliedVersions 读取已执行的迁移版本及执行时间，schema_migrations 表不存在时自动创建
+func appliedVersions(db *gorm.DB) (map[int64]time.Time, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		if err := db.Migrator().CreateTable(&schemaMigration{}); err != nil {
+			return nil, fmt.Errorf("创建 schema_migrations 表失败: %w", err)
+		}
+	}
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("读取 schema_migrations 失败: %w", err)
+	}
+	applied := make(map[int64]time.Time, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r.AppliedAt
+	}
+	return applied, nil
+}
+
+// withLock 在单个数据库连接上获取迁移咨询锁后执行 fn，结束后释放锁。
+// 咨询锁属于会话级别，因此 fn 必须使用传入的 conn。SQLite 为单文件数据库，不加锁。
+func withLock(ctx context.Context, db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		// 使用新会话，避免各条语句共享同一个 Statement
+		conn = conn.Session(&gorm.Session{})
+		switch conn.Dialector.Name() {
+		case "mysql":
+			var got *int64
+			secs := int(LockTimeout / time.Second)
+			if err := conn.Raw("SELECT GET_LOCK(?, ?)", lockName, secs).Scan(&got).Error; err != nil {
+				return fmt.Errorf("获取数据库迁移锁失败: %w", err)
+			}
+			if got == nil || *got != 1 {
+				return ErrLockTimeout
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", lockName)
+		case "postgres":
+			deadline := time.Now().Add(LockTimeout)
+			for {
+				var got bool
+				if err := conn.Raw("SELECT pg_try_advisory_lock(?)", pgLockKey).Scan(&got).Error; err != nil {
+					return fmt.Errorf("获取数据库迁移锁失败: %w", err)
+				}
+				if got {
+					break
+				}
+				if time.Now().After(deadline) {
+					return ErrLockTimeout
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(500 * time.Millisecond):
+				}
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", pgLockKey)
+		}
+		return fn(conn)
+	})
+}
@@ -0,0 +1,112 @@
+package migrate
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 在临时目录中创建空的 SQLite 数据库
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrate.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// versions 返回迁移的版本号列表
+func versions(list []Migration) []int64 {
+	v := make([]int64, len(list))
+	for i, m := range list {
+		v[i] = m.Version
+	}
+	return v
+}
+
+// assertColumns 检查 api_services 表中各列是否存在
+func assertColumns(t *testing.T, db *gorm.DB, want map[string]bool) {
+	t.Helper()
+	for column, exists := range want {
+		if got := db.Migrator().HasColumn("api_services", column); got != exists {
+			t.Errorf("api_services.%s 存在 = %v, want %v", column, got, exists)
+		}
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	all := All()
+
+	applied, err := Up(ctx, db)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if len(applied) != len(all) || applied[len(applied)-1].Version != Latest() {
+		t.Fatalf("Up() applied %v, want all %v", versions(applied), versions(all))
+	}
+	for _, table := range []string{"users", "api_services", "audits", "api_keys"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("表 %s 不存在", table)
+		}
+	}
+	assertColumns(t, db, map[string]bool{"status": true, "deprecated_at": true, "disabled": false, "managed_by": true, "kind": true, "steps": true, "isolation_level": true})
+
+	// 重复执行不会再次迁移
+	if applied, err := Up(ctx, db); err != nil || len(applied) != 0 {
+		t.Fatalf("second Up() = %v, %v; want no migrations", versions(applied), err)
+	}
+	status, err := StatusOf(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if !s.Applied || s.AppliedAt == nil {
+			t.Errorf("迁移 %d_%s 未标记为已执行", s.Version, s.Name)
+		}
+	}
+
+	// 回滚最近两个版本
+	reverted, err := Down(ctx, db, 2)
+	if err != nil {
+		t.Fatalf("Down(2) error = %v", err)
+	}
+	if got := versions(reverted); len(got) != 2 || got[0] != Latest() || got[1] != Latest()-1 {
+		t.Fatalf("Down(2) reverted %v, want the latest two versions", got)
+	}
+	assertColumns(t, db, map[string]bool{"status": true, "kind": false, "max_affected_rows": false, "steps": false, "isolation_level": false})
+	pending, err := Pending(ctx, db)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("Pending() = %v, %v; want 2 migrations", versions(pending), err)
+	}
+
+	// 再次执行只会补上被回滚的版本
+	if applied, err := Up(ctx, db); err != nil || len(applied) != 2 {
+		t.Fatalf("Up() after Down(2) = %v, %v; want 2 migrations", versions(applied), err)
+	}
+	assertColumns(t, db, map[string]bool{"kind": true, "steps": true})
+
+	// 全部回滚
+	if reverted, err := Down(ctx, db, len(all)); err != nil || len(reverted) != len(all) {
+		t.Fatalf("Down(all) = %v, %v; want all migrations", versions(reverted), err)
+	}
+	for _, table := range []string{"users", "api_services", "audits", "api_keys"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("表 %s 在全部回滚后仍存在", table)
+		}
+	}
+	if pending, err := Pending(ctx, db); err != nil || len(pending) != len(all) {
+		t.Fatalf("Pending() after Down(all) = %v, %v; want all migrations", versions(pending), err)
+	}
+}
@@ -0,0 +1,233 @@
+package migrate
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations 是全部版本化迁移。已发布的迁移不能修改，表结构变更只能追加新的迁移。
+// 迁移中使用的表结构是当时模型的快照，不能直接引用 models 包，否则模型演进后旧迁移的含义会随之改变。
+var migrations = []Migration{
+	{
+		// 基线: 与此前 AutoMigrate 生成的表结构一致。AutoMigrate 是幂等的，
+		// 已由旧版本创建表的数据库执行此迁移时只会补齐缺失的列与索引。
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AutoMigrate(&userV1{}, &apiServiceV1{}, &auditV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&auditV1{}, &apiServiceV1{}, &userV1{})
+		},
+	},
+	{
+		// 命令行管理: API Key 表
+		Version: 2,
+		Name:    "api_keys",
+		Up: func(tx *gorm.DB) error {
+			// 开发模式 (AutoMigrate) 创建的数据库可能已有此表
+			return tx.Migrator().AutoMigrate(&apiKeyV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&apiKeyV2{})
+		},
+	},
+	{
+		// 声明式服务定义目录: 记录由文件管理的服务
+		Version: 3,
+		Name:    "service_managed_by",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&apiServiceV3{}, "ManagedBy") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&apiServiceV3{}, "ManagedBy")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&apiServiceV3{}, "ManagedBy")
+		},
+	},
+	{
+		// 服务发布状态 (包括停用)、生效时间窗口与弃用时间
+		Version: 4,
+		Name:    "service_status_and_schedule",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"Status", "ActivatesAt", "ExpiresAt", "DeprecatedAt"} {
+				if m.HasColumn(&apiServiceV4{}, field) {
+					continue
+				}
+				if err := m.AddColumn(&apiServiceV4{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"DeprecatedAt", "ExpiresAt", "ActivatesAt", "Status"} {
+				if err := m.DropColumn(&apiServiceV4{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// 写服务 (kind=command) 与最大影响行数
+		Version: 5,
+		Name:    "service_command_kind",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"Kind", "MaxAffectedRows"} {
+				if m.HasColumn(&apiServiceV5{}, field) {
+					continue
+				}
+				if err := m.AddColumn(&apiServiceV5{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"MaxAffectedRows", "Kind"} {
+				if err := m.DropColumn(&apiServiceV5{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// 流水线服务 (kind=pipeline) 的步骤与事务隔离级别
+		Version: 6,
+		Name:    "service_pipeline_steps",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"Steps", "IsolationLevel"} {
+				if m.HasColumn(&apiServiceV6{}, field) {
+					continue
+				}
+				if err := m.AddColumn(&apiServiceV6{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"IsolationLevel", "Steps"} {
+				if err := m.DropColumn(&apiServiceV6{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// userV1 是版本 1 的 users 表结构
+type userV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Username  string         `gorm:"unique;not null"`
+	Email     string         `gorm:"unique;not null"`
+}
+
+func (userV1) TableName() string { return "users" }
+
+// apiServiceV1 是版本 1 的 api_services 表结构
+type apiServiceV1 struct {
+	ID                uint `gorm:"primarykey"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
+	Name              string         `gorm:"unique;not null"`
+	Method            string         `gorm:"not null"`
+	Path              string         `gorm:"unique;not null"`
+	SQL               string         `gorm:"not null"`
+	ParamKeys         string         `gorm:"type:text"`
+	ParamTypes        string         `gorm:"type:text"`
+	AllowedOrigins    string         `gorm:"type:text"`
+	Datasource        string         `gorm:"size:64;not null;default:''"`
+	RequiresFreshData bool           `gorm:"not null;default:false"`
+}
+
+func (apiServiceV1) TableName() string { return "api_services" }
+
+// auditV1 是版本 1 的 audits 表结构
+type auditV1 struct {
+	ID         uint      `gorm:"primarykey"`
+	CreatedAt  time.Time `gorm:"index"`
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	Path       string         `gorm:"index;size:191"`
+	Method     string         `gorm:"size:10"`
+	ClientIP   string         `gorm:"size:45"`
+	Principal  string         `gorm:"index;size:191"`
+	RequestID  string         `gorm:"index;size:64"`
+	SQL        string         `gorm:"type:text"`
+	Args       string         `gorm:"type:text"`
+	DurationMs int64
+	Rows       int
+	Truncated  bool
+	Error      string `gorm:"type:text"`
+	Outcome    string `gorm:"index;size:20"`
+	HTTPStatus int
+	BizCode    int
+	PrevHash   string `gorm:"size:64"`
+	Hash       string `gorm:"index;size:64"`
+}
+
+func (auditV1) TableName() string { return "audits" }
+
+// apiKeyV2 是版本 2 的 api_keys 表结构
+type apiKeyV2 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string `gorm:"size:191;uniqueIndex;not null"`
+	Prefix    string `gorm:"size:16;not null"`
+	KeyHash   string `gorm:"size:64;uniqueIndex;not null"`
+	Scopes    string `gorm:"size:255;not null"`
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+}
+
+func (apiKeyV2) TableName() string { return "api_keys" }
+
+// apiServiceV3 是版本 3 新增的 api_services 列
+type apiServiceV3 struct {
+	ManagedBy string `gorm:"size:255;not null;default:''"`
+}
+
+func (apiServiceV3) TableName() string { return "api_services" }
+
+// apiServiceV4 是版本 4 新增的 api_services 列
+type apiServiceV4 struct {
+	Status       string `gorm:"size:16;not null;default:'active'"`
+	ActivatesAt  *time.Time
+	ExpiresAt    *time.Time
+	DeprecatedAt *time.Time
+}
+
+func (apiServiceV4) TableName() string { return "api_services" }
+
+// apiServiceV5 是版本 5 新增的 api_services 列
+type apiServiceV5 struct {
+	Kind            string `gorm:"size:16;not null;default:'query'"`
+	MaxAffectedRows int    `gorm:"not null;default:0"`
+}
+
+func (apiServiceV5) TableName() string { return "api_services" }
+
+// apiServiceV6 是版本 6 新增的 api_services 列
+type apiServiceV6 struct {
+	Steps          string `gorm:"type:text"`
+	IsolationLevel string `gorm:"size:32;not null;default:''"`
+}
+
+func (apiServiceV6) TableName() string { return "api_services" }
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// API Key 权限范围
+const (
+	ScopeAdmin   = "admin"   // 访问管理接口 (/api/v1/admin/*)，等同于 ADMIN_API_TOKEN
+	ScopeRun     = "run"     // 调用动态服务，审计记录中的调用方为该 Key
+	ScopeCommand = "command" // 调用 kind=command 的写服务 (同时需要 run)
+)
+
+// APIKey 是通过 app apikey create 签发的访问凭据。
+// 数据库中只保存 Key 的 SHA-256 摘要，明文仅在创建时输出一次。
+type APIKey struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Name 是 Key 的唯一名称，同时作为审计记录中的调用方标识 (apikey:<name>)
+	Name string `gorm:"size:191;uniqueIndex;not null" json:"name"`
+	// Prefix 是明文 Key 的前缀，便于在不暴露完整 Key 的情况下辨认
+	Prefix  string `gorm:"size:16;not null" json:"prefix"`
+	KeyHash string `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	// Scopes 是逗号分隔的权限范围，取值见 Scope* 常量
+	Scopes string `gorm:"size:255;not null" json:"scopes"`
+
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// TableName 指定表名为 'api_keys'
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// HasScope 返回 Key 是否拥有指定权限范围
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if strings.TrimSpace(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active 返回 Key 在 now 时刻是否可用（未吊销且未过期）
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -6,6 +6,29 @@ import (
 	"gorm.io/gorm"
 )
 
+// 动态服务的发布状态 (APIService.Status)
+const (
+	ServiceStatusDraft      = "draft"      // 草稿，不对外提供服务
+	ServiceStatusActive     = "active"     // 正常提供服务
+	ServiceStatusDisabled   = "disabled"   // 临时停用
+	ServiceStatusDeprecated = "deprecated" // 已弃用，仍可调用
+)
+
+// 动态服务的类型 (APIService.Kind)
+const (
+	ServiceKindQuery    = "query"    // 只读查询
+	ServiceKindCommand  = "command"  // 写操作 (INSERT/UPDATE/DELETE)，需显式启用
+	ServiceKindPipeline = "pipeline" // 多步骤流水线，在单个事务中按顺序执行 Steps
+)
+
+// 命令与流水线服务的事务隔离级别 (APIService.IsolationLevel)，为空表示数据库默认级别
+const (
+	IsolationReadUncommitted = "read_uncommitted"
+	IsolationReadCommitted   = "read_committed"
+	IsolationRepeatableRead  = "repeatable_read"
+	IsolationSerializable    = "serializable"
+)
+
 // APIService 定义了动态 API 服务的注册模型
 // 它存储了 API 服务的元数据，包括要执行的 SQL 语句。
 type APIService struct {
@@ -25,7 +48,8 @@ type APIService struct {
 	
 	// SQL 是要执行的原始 SQL 语句
 	// 注意: 必须使用 ? 作为参数占位符，并在请求中传递参数值。
-	SQL string `gorm:"not null" json:"sql" binding:"required"`
+	// 【修改】pipeline 服务的语句由 Steps 定义，SQL 为空
+	SQL string `gorm:"not null" json:"sql" binding:"required_unless=Kind pipeline"`
 	
 	// ParamKeys 是一个 JSON 数组字符串，定义了 SQL 占位符对应参数的 key 及其顺序。
 	// 示例: '["user_id", "username"]'。顺序必须与 SQL 中的 ? 占位符顺序一致。
@@ -34,9 +58,66 @@ type APIService struct {
 	// 【新增】ParamTypes 是一个 JSON 数组字符串，定义了 ParamKeys 中每个参数的预期类型。
 	// 示例: '["int", "string", "float", "bool"]'。顺序必须与 ParamKeys 严格一致。
 	ParamTypes string `gorm:"type:text" json:"param_types"`
+
+	// 【新增】AllowedOrigins 是一个 JSON 数组字符串，列出除全局 cors.run 策略外额外允许跨域调用此服务的来源，
+	// 用于嵌入特定前端应用的服务。示例: '["https://report.example.com", "https://*.example.org"]'
+	AllowedOrigins string `gorm:"type:text" json:"allowed_origins"`
+
+	// 【新增】Datasource 是执行 SQL 使用的命名数据源 (见配置 datasources)，为空表示主库 "default"
+	Datasource string `gorm:"size:64;not null;default:''" json:"datasource"`
+
+	// 【新增】RequiresFreshData 为 true 时查询始终在主库执行，不路由到可能存在复制延迟的只读副本
+	RequiresFreshData bool `gorm:"not null;default:false" json:"requires_fresh_data"`
+
+	// 【新增】Status 是服务的发布状态，取值见 ServiceStatus* 常量，注册时为空表示 active。
+	// draft 不对外提供服务 (404)；disabled 返回 503；deprecated 仍可调用，但响应带 Deprecation 头
+	Status string `gorm:"size:16;not null;default:'active'" json:"status" binding:"omitempty,oneof=draft active disabled deprecated"`
+
+	// 【新增】ActivatesAt 与 ExpiresAt 是可选的生效时间窗口: 生效前返回 404，到期后返回 410。
+	// 设置 ExpiresAt 时响应带 Sunset 头
+	ActivatesAt *time.Time `json:"activates_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+
+	// 【新增】DeprecatedAt 是服务进入 deprecated 状态的时间，由系统通过 TrackDeprecation 维护，
+	// 用于 Deprecation 响应头 (RFC 9745)；其他状态为空
+	DeprecatedAt *time.Time `json:"deprecated_at"`
+
+	// 【新增】ManagedBy 是定义此服务的文件（相对于 dynamic.services_dir），为空表示通过接口或命令行注册。
+	// 由文件管理的服务只读，只能通过修改文件变更
+	ManagedBy string `gorm:"size:255;not null;default:''" json:"managed_by"`
+
+	// 【新增】Kind 是服务类型，取值见 ServiceKind* 常量，注册时为空表示 query。
+	// command 服务执行单条 INSERT/UPDATE/DELETE，需要 dynamic.commands_enabled 与 command 权限范围的 API Key
+	Kind string `gorm:"size:16;not null;default:'query'" json:"kind" binding:"omitempty,oneof=query command pipeline"`
+
+	// 【新增】MaxAffectedRows 是 command 服务单次执行允许影响的最大行数，超过时事务回滚；
+	// 0 表示使用 dynamic.command_max_affected_rows
+	MaxAffectedRows int `gorm:"not null;default:0" json:"max_affected_rows" binding:"gte=0"`
+
+	// 【新增】Steps 是 pipeline 服务的步骤 JSON 数组字符串，按顺序在单个事务中执行。
+	// 示例: '[{"name": "stock", "sql": "SELECT qty FROM stock WHERE sku = ?", "params": ["sku"], "require_rows": true},
+	//        {"name": "order", "sql": "INSERT INTO orders (sku, qty) VALUES (?, ?)", "params": ["sku", "qty"]}]'。
+	// params 中的 "step.column" 引用前面步骤结果第一行的列 (写步骤还可引用 rows_affected 与 last_insert_id)，其余为 ParamKeys 中的请求参数
+	Steps string `gorm:"type:text" json:"steps"`
+
+	// 【新增】IsolationLevel 是 command 与 pipeline 服务的事务隔离级别，取值见 Isolation* 常量，为空表示数据库默认级别
+	IsolationLevel string `gorm:"size:32;not null;default:''" json:"isolation_level" binding:"omitempty,oneof=read_uncommitted read_committed repeatable_read serializable"`
 }
 
 // TableName 指定表名为 'api_services'
 func (APIService) TableName() string {
 	return "api_services"
 }
+
+// TrackDeprecation 在写入前维护 DeprecatedAt: previous 是更新前的记录 (新建时为 nil)。
+// 已弃用的服务保留原来的弃用时间，新进入 deprecated 状态时记为 now，其他状态清空
+func (s *APIService) TrackDeprecation(previous *APIService, now time.Time) {
+	switch {
+	case s.Status != ServiceStatusDeprecated:
+		s.DeprecatedAt = nil
+	case previous != nil && previous.Status == ServiceStatusDeprecated && previous.DeprecatedAt != nil:
+		s.DeprecatedAt = previous.DeprecatedAt
+	default:
+		s.DeprecatedAt = &now
+	}
+}
@@ -6,10 +6,22 @@ import (
     "gorm.io/gorm"
 )
 
+// 审计结果 (Outcome) 枚举，标识一次动态服务执行尝试的最终结果
+const (
+    AuditOutcomeSuccess    = "success"     // 查询成功执行
+    AuditOutcomeBlocked    = "blocked"     // 被只读安全策略拦截
+    AuditOutcomeBadRequest = "bad_request" // 请求参数缺失、格式错误或类型转换失败
+    AuditOutcomeSQLError   = "sql_error"   // SQL 执行或结果扫描失败
+    AuditOutcomeTimeout    = "timeout"     // 查询超时被取消
+    AuditOutcomeRejected   = "rejected"    // 服务不存在或服务配置无效，请求在执行前被拒绝
+    AuditOutcomeRolledBack = "rolled_back" // 【新增】写服务影响行数超过上限，事务已回滚
+)
+
 // Audit 记录动态 SQL 执行的审计信息
+// 每一次执行尝试（包括被拦截、参数错误、SQL 错误和超时）都会写入一条记录。
 type Audit struct {
     ID        uint           `gorm:"primarykey" json:"id"`
-    CreatedAt time.Time      `json:"created_at"`
+    CreatedAt time.Time      `gorm:"index" json:"created_at"`
     UpdatedAt time.Time      `json:"updated_at"`
     DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
@@ -17,6 +29,10 @@ type Audit struct {
     Path      string `gorm:"index;size:191" json:"path"`
     Method    string `gorm:"size:10" json:"method"`
     ClientIP  string `gorm:"size:45" json:"client_ip"`
+    // 【新增】调用方标识，由认证中间件写入 (middleware.PrincipalKey)，匿名调用为空
+    Principal string `gorm:"index;size:191" json:"principal"`
+    // 【新增】请求 ID (X-Request-ID)，用于关联访问日志、链路追踪与审计记录
+    RequestID string `gorm:"index;size:64" json:"request_id"`
 
     // 执行相关
     SQL        string `gorm:"type:text" json:"sql"`
@@ -25,6 +41,17 @@ type Audit struct {
     Rows       int    `json:"rows"`
     Truncated  bool   `json:"truncated"`
     Error      string `gorm:"type:text" json:"error"`
+
+    // 【新增】执行结果，取值见 AuditOutcome* 常量
+    Outcome    string `gorm:"index;size:20" json:"outcome"`
+    // 【新增】返回给客户端的 HTTP 状态码与业务代码 (APIResponse.Code)
+    HTTPStatus int    `json:"http_status"`
+    BizCode    int    `json:"biz_code"`
+
+    // 【新增】哈希链：Hash = HMAC-SHA256(记录内容 + PrevHash)，PrevHash 为上一条记录的 Hash。
+    // 未配置 AUDIT_HMAC_KEY 时两者为空。
+    PrevHash string `gorm:"size:64" json:"prev_hash"`
+    Hash     string `gorm:"index;size:64" json:"hash"`
 }
 
 // TableName 指定表名为 'audits'
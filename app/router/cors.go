@@ -0,0 +1,84 @@
+package router
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/handlers"
+)
+
+// managementPrefixes 是使用 cors.management 策略的路径前缀
+var managementPrefixes = []string{"/api/v1/admin", "/api/v1/dynamic/register"}
+
+// corsState 缓存根据某一版本配置构建的各路由分组 CORS 处理函数
+type corsState struct {
+	cfg        *config.Config
+	def        gin.HandlerFunc
+	management gin.HandlerFunc
+	run        gin.HandlerFunc
+}
+
+// corsMiddleware 按请求路径选择跨域策略：管理接口、动态服务执行接口与其他接口分别使用独立策略。
+// 以全局中间件注册，保证未注册 OPTIONS 路由的接口也能正确响应预检请求。
+// 配置热加载后 config.Current() 返回新实例，此时重新构建处理函数。
+func corsMiddleware() gin.HandlerFunc {
+	var state atomic.Pointer[corsState]
+	return func(c *gin.Context) {
+		cur := config.Current()
+		s := state.Load()
+		if s == nil || s.cfg != cur {
+			s = &corsState{
+				cfg:        cur,
+				def:        newCORSHandler(cur.CORS.CORSPolicy, nil),
+				management: newCORSHandler(cur.CORS.ManagementPolicy(), nil),
+				// 动态服务执行接口额外允许服务级 allowed_origins 中的来源
+				run: newCORSHandler(cur.CORS.RunPolicy(), handlers.ServiceAllowsOrigin),
+			}
+			state.Store(s)
+		}
+
+		path := c.Request.URL.Path
+		switch {
+		case path == handlers.DynamicRunPrefix || strings.HasPrefix(path, handlers.DynamicRunPrefix+"/"):
+			s.run(c)
+		case hasAnyPrefix(path, managementPrefixes):
+			s.management(c)
+		default:
+			s.def(c)
+		}
+	}
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// newCORSHandler 将跨域策略转换为 gin-contrib/cors 处理函数。
+// extra 用于策略之外的附加来源判断（例如服务级来源），为 nil 时仅使用策略中的来源规则。
+// 不被允许的跨域请求由 cors 中间件返回 403。
+func newCORSHandler(p config.CORSPolicy, extra func(*gin.Context, string) bool) gin.HandlerFunc {
+	cfg := cors.Config{
+		AllowMethods:     p.AllowMethods,
+		AllowHeaders:     p.AllowHeaders,
+		ExposeHeaders:    p.ExposeHeaders,
+		AllowCredentials: p.AllowCredentials,
+		MaxAge:           p.MaxAge,
+	}
+	if p.AllowsAny() {
+		cfg.AllowAllOrigins = true
+	} else {
+		// 来源匹配（含通配子域名）由 config.CORSPolicy 实现
+		cfg.AllowOriginWithContextFunc = func(c *gin.Context, origin string) bool {
+			return p.Allows(origin) || (extra != nil && extra(c, origin))
+		}
+	}
+	return cors.New(cfg)
+}
@@ -1,38 +1,74 @@
 package router
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/docs"
 	"go-gin-gorm-api/app/handlers"
+	"go-gin-gorm-api/app/health"
+	"go-gin-gorm-api/app/logging"
+	"go-gin-gorm-api/app/metrics"
+	"go-gin-gorm-api/app/middleware"
+	"go-gin-gorm-api/app/sdkgen"
+	"go-gin-gorm-api/app/tracing"
 )
 
 // InitRouter 初始化 Gin 路由配置
 func InitRouter() *gin.Engine {
-	// 设置 Gin 模式 (release 模式可以提高性能)
-	gin.SetMode(gin.DebugMode)
-	
-	r := gin.Default()
-
-	// 1. 配置 CORS 跨域
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"}, // 生产环境中应限制为特定的域名
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
+	// 设置 Gin 模式 (release 模式可以提高性能)，未通过 server.mode / GIN_MODE 指定时使用 debug 模式
+	if mode := config.Current().Server.Mode; mode != "" {
+		gin.SetMode(mode)
+	} else {
+		gin.SetMode(gin.DebugMode)
+	}
+
+	// gin 自身的调试输出与 panic 堆栈也以结构化日志输出
+	gin.DefaultWriter = logging.NewWriter(slog.LevelDebug, "gin")
+	gin.DefaultErrorWriter = logging.NewWriter(slog.LevelError, "gin")
+
+	// 使用结构化访问日志替代 gin.Default() 自带的文本 Logger
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	// 请求 ID 需最先设置，后续中间件与处理函数的日志都会带上 request_id
+	r.Use(middleware.RequestID())
+
+	// 链路追踪 (W3C trace context)、结构化访问日志与请求计数/耗时指标
+	r.Use(tracing.Middleware())
+	r.Use(logging.AccessLog())
+	r.Use(metrics.Middleware())
+
+	// 1. 配置 CORS 跨域 (cors 配置段，支持热加载)
+	r.Use(corsMiddleware())
 
 	// 根路径健康检查
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "Welcome to Go Gin Gorm API"})
 	})
 
-	// 2. API 路由分组
-	v1 := r.Group("/api/v1")
+	// 存活与就绪检查 (供 Kubernetes / Docker HEALTHCHECK 使用)
+	r.GET("/healthz", handlers.Healthz)
+	r.GET("/readyz", handlers.Readyz)
+
+	// Prometheus 指标
+	r.GET("/metrics", metrics.Handler())
+
+	// API 文档页面 (离线资源打包在二进制中)，文档内容来自 /api/v1/openapi.json
+	r.GET("/docs", func(c *gin.Context) { c.Redirect(http.StatusMovedPermanently, "/docs/") })
+	r.GET("/docs/*file", docs.Handler())
+
+	// 2. API 路由分组，应用完成启动（数据库就绪）前统一返回 503
+	v1 := r.Group("/api/v1", health.RequireStarted())
 	{
+		// 运行时生成的 OpenAPI 3 文档: 用户管理接口与当前可调用的动态服务
+		v1.GET("/openapi.json", handlers.OpenAPISpec)
+
+		// 按当前已发布的动态服务生成的类型化客户端 (go | typescript)
+		v1.GET("/sdk/:lang", sdkgen.Handler)
+
 		// 用户管理 (基础示例)
 		userRoutes := v1.Group("/users")
 		{
@@ -52,8 +88,28 @@ func InitRouter() *gin.Engine {
 			// 避免与管理路由冲突，将执行路由放在 /run/*path 下
 			// 管理路由: POST /api/v1/dynamic/register
 			// 执行路由:  /api/v1/dynamic/run/*path
-			log.Println("注册动态服务执行路由 /api/v1/dynamic/run/*path")
-			dynamic.Any("/run/*path", handlers.ExecuteService)
+			slog.Debug("注册动态服务执行路由", "route", "/api/v1/dynamic/run/*path")
+			dynamic.Any("/run/*path", middleware.OptionalAPIKey(), handlers.ExecuteService)
+		}
+
+		// 4. 管理接口 (需要管理令牌 ADMIN_API_TOKEN)
+		admin := v1.Group("/admin", middleware.AdminAuth())
+		{
+			// 审计日志检索、导出与聚合统计
+			admin.GET("/audits", handlers.ListAudits)
+			admin.GET("/audits/export", handlers.ExportAudits)
+			admin.GET("/audits/stats", handlers.GetAuditStats)
+			admin.GET("/audits/verify", handlers.VerifyAudits)
+
+			// 数据源状态 (不含凭据)
+			admin.GET("/datasources", handlers.ListDatasources)
+
+			// 动态服务定义包导入/导出 (YAML 或 JSON)
+			admin.GET("/services/export", handlers.ExportServices)
+			admin.POST("/services/import", handlers.ImportServices)
+
+			// 动态服务试运行: 发布前以样例参数在只读事务中执行，不写入审计表
+			admin.POST("/services/test", handlers.DryRunService)
 		}
 	}
 
@@ -0,0 +1,366 @@
+package sdkgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+
+	"go-gin-gorm-api/app/handlers"
+	"go-gin-gorm-api/app/models"
+)
+
+// goField 是生成的 Go 结构体字段
+type goField struct {
+	Name  string // JSON 名称 (参数名或列名)
+	Ident string
+	Type  string
+	Tag   string
+}
+
+// newGoField 返回字段定义。名称无法写入结构体标签时省略标签，按字段名匹配
+func newGoField(name, ident, typ string) goField {
+	f := goField{Name: name, Ident: ident, Type: typ}
+	if !strings.ContainsAny(name, "`\",\\") {
+		f.Tag = fmt.Sprintf("`json:%q`", name)
+	}
+	return f
+}
+
+// goService 是生成的 Go 客户端方法
+type goService struct {
+	Ident      string
+	HTTPMethod string
+	RunPath    string
+	Doc        []string
+	Deprecated bool
+	Query      bool   // GET 服务的参数为查询参数，其他方法为 JSON 请求体
+	ResultType string // 写服务与流水线服务的结果类型 (CommandResult、PipelineResult)，为空时返回结果行
+	Returns    string // 方法返回的结果类型
+	Call       string // 发送请求的泛型函数
+	Params     []goField
+	Columns    []goField
+}
+
+// goClientReserved 是 Client 的字段名，方法名不能与其重复
+var goClientReserved = []string{"BaseURL", "APIKey", "HTTPClient"}
+
+// goParamType 返回参数的 Go 类型
+func goParamType(t string) string {
+	switch t {
+	case "integer":
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	}
+	return "string"
+}
+
+// goColumnType 返回结果列的 Go 类型，可为 NULL 的列使用指针
+func goColumnType(col handlers.ResultColumn) string {
+	var t string
+	switch {
+	case col.Format == "byte":
+		return "[]byte"
+	case col.Type == "":
+		return "json.RawMessage"
+	case col.Type == "integer":
+		t = "int64"
+	case col.Type == "number":
+		t = "float64"
+	case col.Type == "boolean":
+		t = "bool"
+	default:
+		t = "string"
+	}
+	if col.Nullable {
+		return "*" + t
+	}
+	return t
+}
+
+// resultType 返回写服务与流水线服务在生成代码中的结果类型，查询服务返回空字符串
+func resultType(kind string) string {
+	switch kind {
+	case models.ServiceKindCommand:
+		return "CommandResult"
+	case models.ServiceKindPipeline:
+		return "PipelineResult"
+	}
+	return ""
+}
+
+// renderGo 生成 Go 客户端包源码
+func renderGo(schemas []handlers.ServiceSchema, pkg string) ([]byte, error) {
+	methods := uniqueNames{}
+	for _, name := range goClientReserved {
+		methods[name] = true
+	}
+
+	services := make([]goService, 0, len(schemas))
+	for _, s := range schemas {
+		svc := goService{
+			HTTPMethod: s.Service.Method,
+			RunPath:    s.RunPath,
+			Doc:        serviceDoc(s),
+			Deprecated: deprecated(s),
+			Query:      s.Service.Method == "GET",
+			ResultType: resultType(s.Service.Kind),
+		}
+		svc.Ident = methods.add(goIdent(s.Service.Name, "Service"))
+		svc.Doc[0] = svc.Ident + " " + svc.Doc[0]
+		svc.Returns, svc.Call = "*Result["+svc.Ident+"Row]", "call["+svc.Ident+"Row]"
+		if svc.ResultType != "" {
+			svc.Returns, svc.Call = "*"+svc.ResultType, "result["+svc.ResultType+"]"
+		}
+
+		fields := uniqueNames{}
+		for _, p := range s.Params {
+			svc.Params = append(svc.Params, newGoField(p.Name, fields.add(goIdent(p.Name, "Param")), goParamType(p.Type)))
+		}
+		fields = uniqueNames{}
+		for _, col := range s.Columns {
+			svc.Columns = append(svc.Columns, newGoField(col.Name, fields.add(goIdent(col.Name, "Col")), goColumnType(col)))
+		}
+		services = append(services, svc)
+	}
+
+	var buf bytes.Buffer
+	if err := goTemplate.Execute(&buf, map[string]interface{}{"Package": pkg, "Services": services}); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化生成的 Go 代码失败: %w", err)
+	}
+	return src, nil
+}
+
+var goTemplate = template.Must(template.New("go").Parse(`// Code generated by "app sdk generate"; DO NOT EDIT.
+
+// Package {{.Package}} 是动态服务的 Go 客户端，每个已发布的动态服务对应 Client 的一个方法。
+// 服务变更后通过 app sdk generate 或 GET /api/v1/sdk/go 重新生成。
+package {{.Package}}
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client 调用动态服务。APIKey 不为空时以 Authorization: Bearer 发送
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// NewClient 创建访问 baseURL (例如 http://localhost:8080) 的客户端
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// Result 是动态服务的查询结果。结果超过服务端最大行数时 Truncated 为 true
+type Result[T any] struct {
+	Rows      []T
+	Truncated bool
+}
+
+// CommandResult 是写服务的执行结果。LastInsertID 仅在 MySQL 与 SQLite 的 INSERT 中返回
+type CommandResult struct {
+	RowsAffected int64                    ` + "`json:\"rows_affected\"`" + `
+	LastInsertID *int64                   ` + "`json:\"last_insert_id\"`" + `
+	Returning    []map[string]interface{} ` + "`json:\"returning\"`" + `
+}
+
+// PipelineResult 是流水线服务的执行结果，按顺序包含每个步骤的结果
+type PipelineResult struct {
+	Steps []StepResult ` + "`json:\"steps\"`" + `
+}
+
+// StepResult 是流水线中单个步骤的结果。RowsAffected 与 LastInsertID 仅用于写步骤
+type StepResult struct {
+	Name         string                   ` + "`json:\"name\"`" + `
+	Rows         []map[string]interface{} ` + "`json:\"rows\"`" + `
+	Truncated    bool                     ` + "`json:\"truncated\"`" + `
+	RowsAffected *int64                   ` + "`json:\"rows_affected\"`" + `
+	LastInsertID *int64                   ` + "`json:\"last_insert_id\"`" + `
+	DurationMs   int64                    ` + "`json:\"duration_ms\"`" + `
+}
+
+// Error 是动态服务返回的错误: HTTP 状态码非 2xx，或业务码非 0 (1 表示被安全策略拦截，2 表示查询超时，
+// 3 表示写服务影响行数超过上限已回滚，4 表示流水线步骤条件不满足已回滚)
+type Error struct {
+	StatusCode int
+	Code       int
+	Message    string
+	Detail     json.RawMessage
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("动态服务返回错误 (HTTP %d, code %d): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// response 是服务端统一响应结构
+type response struct {
+	Code    int             ` + "`json:\"code\"`" + `
+	Message string          ` + "`json:\"message\"`" + `
+	Data    json.RawMessage ` + "`json:\"data\"`" + `
+}
+
+// do 发送请求并返回响应中的 data
+func do(ctx context.Context, c *Client, method, path string, query url.Values, body interface{}) (json.RawMessage, error) {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var resp response
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, &Error{StatusCode: res.StatusCode, Code: res.StatusCode, Message: "无法解析响应: " + err.Error()}
+	}
+	if res.StatusCode/100 != 2 || resp.Code != 0 {
+		return nil, &Error{StatusCode: res.StatusCode, Code: resp.Code, Message: resp.Message, Detail: resp.Data}
+	}
+	return resp.Data, nil
+}
+
+// call 发送请求并解析结果行
+func call[T any](ctx context.Context, c *Client, method, path string, query url.Values, body interface{}) (*Result[T], error) {
+	raw, err := do(ctx, c, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result[T]{}
+	data := bytes.TrimSpace(raw)
+	if len(data) > 0 && data[0] == '{' {
+		// 结果被截断时 data 为 {"rows_returned": n, "truncated": true, "data": [...]}
+		var truncated struct {
+			Truncated bool            ` + "`json:\"truncated\"`" + `
+			Data      json.RawMessage ` + "`json:\"data\"`" + `
+		}
+		if err := json.Unmarshal(data, &truncated); err != nil {
+			return nil, err
+		}
+		result.Truncated, data = truncated.Truncated, truncated.Data
+	}
+	if len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, &result.Rows); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// result 发送请求并将 data 解析为 T，用于写服务与流水线服务
+func result[T any](ctx context.Context, c *Client, method, path string, query url.Values, body interface{}) (*T, error) {
+	raw, err := do(ctx, c, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	out := new(T)
+	if err := json.Unmarshal(raw, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// queryValue 将参数格式化为查询字符串中的值
+func queryValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// 确保未使用参数或没有写服务时仍然有效
+var (
+	_ = queryValue
+	_ = result[CommandResult]
+)
+{{range .Services}}
+{{- if .Params}}
+// {{.Ident}}Params 是 {{.Ident}} 的参数
+type {{.Ident}}Params struct {
+{{- range .Params}}
+	{{.Ident}} {{.Type}} {{.Tag}}
+{{- end}}
+}
+{{end}}
+{{- if .ResultType}}
+{{- else if .Columns}}
+// {{.Ident}}Row 是 {{.Ident}} 的结果行
+type {{.Ident}}Row struct {
+{{- range .Columns}}
+	{{.Ident}} {{.Type}} {{.Tag}}
+{{- end}}
+}
+{{else}}
+// {{.Ident}}Row 是 {{.Ident}} 的结果行 (结果列未知)
+type {{.Ident}}Row = map[string]interface{}
+{{end}}
+{{- range .Doc}}
+// {{.}}
+{{- end}}
+{{- if .Deprecated}}
+//
+// Deprecated: 服务已弃用。
+{{- end}}
+func (c *Client) {{.Ident}}(ctx context.Context{{if .Params}}, params {{.Ident}}Params{{end}}) ({{.Returns}}, error) {
+{{- if not .Params}}
+	return {{.Call}}(ctx, c, "{{.HTTPMethod}}", {{printf "%q" .RunPath}}, nil, nil)
+{{- else if .Query}}
+	query := url.Values{}
+{{- range .Params}}
+	query.Set({{printf "%q" .Name}}, queryValue(params.{{.Ident}}))
+{{- end}}
+	return {{.Call}}(ctx, c, "{{.HTTPMethod}}", {{printf "%q" .RunPath}}, query, nil)
+{{- else}}
+	return {{.Call}}(ctx, c, "{{.HTTPMethod}}", {{printf "%q" .RunPath}}, nil, params)
+{{- end}}
+}
+{{end}}`))
@@ -0,0 +1,181 @@
+// Package sdkgen 根据已注册的动态服务生成类型化的客户端: Go 客户端包与 TypeScript 客户端，
+// 每个可调用的 APIService 对应一个函数，参数结构来自 ParamKeys/ParamTypes，结果行类型来自推断出的结果列
+// (见 handlers.DescribeActiveServices)。生成结果只取决于服务定义，服务未变化时内容不变。
+package sdkgen
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/handlers"
+	"go-gin-gorm-api/app/models"
+	"go-gin-gorm-api/app/utils"
+	"gorm.io/gorm"
+)
+
+// 支持的客户端语言
+const (
+	LangGo         = "go"
+	LangTypeScript = "typescript"
+)
+
+// DefaultPackage 是 Go 客户端默认的包名
+const DefaultPackage = "client"
+
+// Options 是生成选项
+type Options struct {
+	// Package 是 Go 客户端的包名，为空时使用 DefaultPackage
+	Package string
+}
+
+// NormalizeLang 将语言名称规范化为 Lang* 常量 (接受 ts 作为 typescript 的简写)，不支持时返回空
+func NormalizeLang(lang string) string {
+	switch strings.ToLower(lang) {
+	case "go", "golang":
+		return LangGo
+	case "ts", "typescript":
+		return LangTypeScript
+	}
+	return ""
+}
+
+// FileName 返回客户端文件的默认名称
+func FileName(lang string) string {
+	if lang == LangTypeScript {
+		return "client.ts"
+	}
+	return "client.go"
+}
+
+// Generate 按当前可调用的动态服务生成指定语言的客户端源码
+func Generate(ctx context.Context, db *gorm.DB, lang string, opts Options) ([]byte, error) {
+	schemas, err := handlers.DescribeActiveServices(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	return Render(schemas, lang, opts)
+}
+
+// Render 由服务描述生成指定语言的客户端源码
+func Render(schemas []handlers.ServiceSchema, lang string, opts Options) ([]byte, error) {
+	switch NormalizeLang(lang) {
+	case LangGo:
+		if opts.Package == "" {
+			opts.Package = DefaultPackage
+		}
+		if !goPackageName.MatchString(opts.Package) {
+			return nil, fmt.Errorf("无效的 Go 包名: %s", opts.Package)
+		}
+		return renderGo(schemas, opts.Package)
+	case LangTypeScript:
+		return renderTypeScript(schemas)
+	}
+	return nil, fmt.Errorf("不支持的客户端语言: %s (可选 go、typescript)", lang)
+}
+
+// Handler 返回当前服务的客户端源码，语言由路径参数 :lang 指定，Go 包名由 ?package 指定。
+// 响应带内容摘要 ETag，服务未变化时对 If-None-Match 返回 304，便于前端与构建脚本按需重新生成。
+// GET /api/v1/sdk/:lang
+func Handler(c *gin.Context) {
+	lang := NormalizeLang(c.Param("lang"))
+	if lang == "" {
+		c.JSON(http.StatusNotFound, utils.APIResponse{Code: 404, Message: "不支持的客户端语言: " + c.Param("lang") + " (可选 go、typescript)"})
+		return
+	}
+	src, err := Generate(c.Request.Context(), config.DB, lang, Options{Package: c.Query("package")})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.APIResponse{Code: 400, Message: "生成客户端失败", Data: gin.H{"detail": err.Error()}})
+		return
+	}
+
+	sum := sha256.Sum256(src)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+FileName(lang)+`"`)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", src)
+}
+
+// goPackageName 匹配合法的 Go 包名
+var goPackageName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// identParts 匹配标识符中的字母数字片段
+var identParts = regexp.MustCompile(`[A-Za-z0-9]+`)
+
+// jsIdent 匹配无需加引号的 JavaScript 属性名
+var jsIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// goInitialisms 是 Go 命名中整体大写的常见缩写
+var goInitialisms = map[string]bool{
+	"api": true, "id": true, "ip": true, "json": true, "http": true, "sql": true, "url": true, "uuid": true,
+}
+
+// goIdent 将名称转换为导出的 Go 标识符，例如 user_id → UserID。以数字开头或为空时加上 prefix
+func goIdent(name, prefix string) string {
+	var b strings.Builder
+	for _, p := range identParts.FindAllString(name, -1) {
+		if goInitialisms[strings.ToLower(p)] {
+			b.WriteString(strings.ToUpper(p))
+		} else {
+			b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+		}
+	}
+	id := b.String()
+	if id == "" || (id[0] >= '0' && id[0] <= '9') {
+		id = prefix + id
+	}
+	return id
+}
+
+// uniqueNames 为名称去重: 与已使用的名称重复时依次追加 2、3 ...
+type uniqueNames map[string]bool
+
+func (u uniqueNames) add(name string) string {
+	unique := name
+	for i := 2; u[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	u[unique] = true
+	return unique
+}
+
+// commentText 将 s 转为可以安全写入单行注释的文本: 控制字符与 Unicode 行分隔符替换为空格，
+// */ 转义为 *\/，避免换行注入代码或提前结束 JSDoc 注释。ValidateService 已拒绝此类名称，
+// 这里防御校验之前注册的服务
+func commentText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+			return ' '
+		}
+		return r
+	}, s)
+	return strings.ReplaceAll(s, "*/", "*\\/")
+}
+
+// serviceDoc 返回服务函数的说明行，服务名称与路径经过 commentText 处理
+func serviceDoc(s handlers.ServiceSchema) []string {
+	doc := []string{fmt.Sprintf("调用动态服务 %s (%s %s)。", commentText(s.Service.Name), s.Service.Method, commentText(s.RunPath))}
+	if s.ColumnsError != "" || len(s.Columns) == 0 {
+		doc = append(doc, "无法推断结果列，结果行为任意对象。")
+	}
+	if s.Service.ExpiresAt != nil {
+		doc = append(doc, "服务将于 "+s.Service.ExpiresAt.UTC().Format("2006-01-02T15:04:05Z")+" 停止提供。")
+	}
+	return doc
+}
+
+// deprecated 报告服务是否已弃用
+func deprecated(s handlers.ServiceSchema) bool {
+	return s.Service.Status == models.ServiceStatusDeprecated
+}
@@ -0,0 +1,156 @@
+package sdkgen
+
+import (
+	"strings"
+	"testing"
+
+	"go-gin-gorm-api/app/handlers"
+	"go-gin-gorm-api/app/models"
+)
+
+func TestGoIdent(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{"user_id", "Param", "UserID"},
+		{"list-users", "Service", "ListUsers"},
+		{"getHTTPStatus", "Service", "GetHTTPStatus"},
+		{"api url", "Service", "APIURL"},
+		{"user.name", "Col", "UserName"},
+		{"2fa_code", "Param", "Param2faCode"},
+		{"中文名", "Col", "Col"},
+		{"", "Param", "Param"},
+		{"a*/b\nc", "Service", "ABC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := goIdent(tt.name, tt.prefix); got != tt.want {
+				t.Errorf("goIdent(%q, %q) = %q, want %q", tt.name, tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTsKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"user_id", "user_id"},
+		{"$ref", "$ref"},
+		{"user-id", `"user-id"`},
+		{"2fa", `"2fa"`},
+		{"a\"b", `"a\"b"`},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tsKey(tt.name); got != tt.want {
+				t.Errorf("tsKey(%q) = %s, want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUniqueNames 中名称 List2 已被自动生成的名称占用，再次添加时得到 List22，结果仍然唯一
+func TestUniqueNames(t *testing.T) {
+	u := uniqueNames{"BaseURL": true}
+	var got []string
+	for _, name := range []string{"List", "List", "BaseURL", "List2", "List"} {
+		got = append(got, u.add(name))
+	}
+	want := []string{"List", "List2", "BaseURL2", "List22", "List3"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("add() = %v, want %v", got, want)
+	}
+}
+
+func TestCommentText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"普通文本", "report /api/v1/report", "report /api/v1/report"},
+		{"换行", "a\nb\r\nc", "a b  c"},
+		{"注释结束符", "a*/b", `a*\/b`},
+		{"Unicode 行分隔符", "a\u2028b\u2029c", "a b c"},
+		{"制表符与 NUL", "a\tb\x00c", "a b c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commentText(tt.in); got != tt.want {
+				t.Errorf("commentText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRenderNames 检查服务名、参数名与客户端成员冲突或彼此冲突时生成的名称
+func TestRenderNames(t *testing.T) {
+	schema := func(name string, params ...string) handlers.ServiceSchema {
+		s := handlers.ServiceSchema{
+			Service: &models.APIService{Name: name, Method: "GET", Status: models.ServiceStatusActive},
+			RunPath: handlers.RunPathPrefix + "/" + name,
+		}
+		for _, p := range params {
+			s.Params = append(s.Params, handlers.ServiceParam{Name: p, Type: "string"})
+		}
+		return s
+	}
+	schemas := []handlers.ServiceSchema{
+		schema("user-list", "user id", "user_id", `a"b`),
+		schema("user_list"),
+		schema("base_url"),
+		schema("constructor"),
+		schema("evil*/\nname"),
+	}
+
+	tests := []struct {
+		lang string
+		want []string
+	}{
+		{LangGo, []string{
+			"func (c *Client) UserList(ctx context.Context, params UserListParams)",
+			"func (c *Client) UserList2(ctx context.Context)",
+			"func (c *Client) BaseURL2(ctx context.Context)",
+			"func (c *Client) Constructor(ctx context.Context)",
+			"func (c *Client) EvilName(ctx context.Context)",
+			"UserID string `json:\"user id\"`",
+			"UserID2 string `json:\"user_id\"`",
+			"AB string }",
+			`evil*\/ name`,
+		}},
+		{LangTypeScript, []string{
+			"userList(params: UserListParams):",
+			"userList2():",
+			"baseUrl():",
+			"constructor2():",
+			"evilName():",
+			`"user id": string;`,
+			"user_id: string;",
+			`"a\"b": string;`,
+			`evil*\/ name`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			src, err := Render(schemas, tt.lang, Options{})
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			// 忽略 gofmt 对齐产生的空白差异
+			code := strings.Join(strings.Fields(string(src)), " ")
+			for _, want := range tt.want {
+				if !strings.Contains(code, want) {
+					t.Errorf("生成的代码缺少 %q:\n%s", want, src)
+				}
+			}
+			if strings.Contains(code, "evil*/ name") {
+				t.Error("注释中包含未转义的服务名称")
+			}
+		})
+	}
+}
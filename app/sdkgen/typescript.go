@@ -0,0 +1,272 @@
+package sdkgen
+
+import (
+	"bytes"
+	"strconv"
+	"text/template"
+
+	"go-gin-gorm-api/app/handlers"
+)
+
+// tsField 是生成的 TypeScript 接口属性
+type tsField struct {
+	Key  string // 属性名，非合法标识符时带引号
+	Type string
+}
+
+// tsService 是生成的 TypeScript 客户端方法
+type tsService struct {
+	Method     string
+	TypeName   string
+	HTTPMethod string
+	RunPath    string
+	Doc        []string
+	Deprecated bool
+	Query      bool
+	ResultType string // 写服务与流水线服务的结果类型，为空时返回结果行
+	Params     []tsField
+	Columns    []tsField
+	HasColumns bool
+}
+
+// tsClientReserved 是 Client 类已使用的成员名，方法名不能与其重复
+var tsClientReserved = []string{"constructor", "baseURL", "options", "send", "request", "result"}
+
+// tsKey 返回属性名，非合法标识符时加引号
+func tsKey(name string) string {
+	if jsIdent.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// tsParamType 返回参数的 TypeScript 类型
+func tsParamType(t string) string {
+	switch t {
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	}
+	return "string"
+}
+
+// tsColumnType 返回结果列的 TypeScript 类型，二进制列为 base64 字符串
+func tsColumnType(col handlers.ResultColumn) string {
+	t := tsParamType(col.Type)
+	if col.Type == "" {
+		return "unknown"
+	}
+	if col.Nullable {
+		t += " | null"
+	}
+	return t
+}
+
+// renderTypeScript 生成 TypeScript 客户端源码 (单个模块，依赖全局 fetch)
+func renderTypeScript(schemas []handlers.ServiceSchema) ([]byte, error) {
+	methods := uniqueNames{}
+	for _, name := range tsClientReserved {
+		methods[name] = true
+	}
+	types := uniqueNames{}
+
+	services := make([]tsService, 0, len(schemas))
+	for _, s := range schemas {
+		svc := tsService{
+			Method:     methods.add(handlers.OperationID(s.Service.Name)),
+			TypeName:   types.add(goIdent(s.Service.Name, "Service")),
+			HTTPMethod: s.Service.Method,
+			RunPath:    s.RunPath,
+			Doc:        serviceDoc(s),
+			Deprecated: deprecated(s),
+			Query:      s.Service.Method == "GET",
+			ResultType: resultType(s.Service.Kind),
+			HasColumns: len(s.Columns) > 0,
+		}
+		for _, p := range s.Params {
+			svc.Params = append(svc.Params, tsField{Key: tsKey(p.Name), Type: tsParamType(p.Type)})
+		}
+		for _, col := range s.Columns {
+			svc.Columns = append(svc.Columns, tsField{Key: tsKey(col.Name), Type: tsColumnType(col)})
+		}
+		services = append(services, svc)
+	}
+
+	var buf bytes.Buffer
+	if err := tsTemplate.Execute(&buf, map[string]interface{}{"Services": services}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var tsTemplate = template.Must(template.New("ts").Parse(`// Code generated by "app sdk generate"; DO NOT EDIT.
+//
+// 动态服务的 TypeScript 客户端，每个已发布的动态服务对应 Client 的一个方法。
+// 服务变更后通过 app sdk generate 或 GET /api/v1/sdk/typescript 重新生成。
+
+/** 动态服务的查询结果。结果超过服务端最大行数时 truncated 为 true */
+export interface Result<T> {
+  rows: T[];
+  truncated: boolean;
+}
+
+/** 写服务的执行结果。last_insert_id 仅在 MySQL 与 SQLite 的 INSERT 中返回 */
+export interface CommandResult {
+  rows_affected: number;
+  last_insert_id?: number;
+  returning?: Record<string, unknown>[];
+}
+
+/** 流水线中单个步骤的结果。rows_affected 与 last_insert_id 仅用于写步骤 */
+export interface StepResult {
+  name: string;
+  rows: Record<string, unknown>[];
+  truncated?: boolean;
+  rows_affected?: number;
+  last_insert_id?: number;
+  duration_ms: number;
+}
+
+/** 流水线服务的执行结果，按顺序包含每个步骤的结果 */
+export interface PipelineResult {
+  steps: StepResult[];
+}
+
+/** 动态服务返回的错误: HTTP 状态码非 2xx，或业务码非 0 (1 表示被安全策略拦截，2 表示查询超时，3 表示写服务影响行数超过上限已回滚，4 表示流水线步骤条件不满足已回滚) */
+export class ApiError extends Error {
+  readonly status: number;
+  readonly code: number;
+  readonly detail?: unknown;
+
+  constructor(status: number, code: number, message: string, detail?: unknown) {
+    super(message);
+    this.name = "ApiError";
+    this.status = status;
+    this.code = code;
+    this.detail = detail;
+  }
+}
+
+export interface ClientOptions {
+  /** API Key (ggk_...)，以 Authorization: Bearer 发送 */
+  apiKey?: string;
+  /** 自定义 fetch 实现，默认使用全局 fetch */
+  fetch?: typeof fetch;
+  /** 附加的请求头 */
+  headers?: Record<string, string>;
+}
+
+interface Envelope {
+  code: number;
+  message: string;
+  data?: unknown;
+}
+{{range .Services}}
+{{- if .Params}}
+/** {{.Method}} 的参数 */
+export interface {{.TypeName}}Params {
+{{- range .Params}}
+  {{.Key}}: {{.Type}};
+{{- end}}
+}
+{{end}}
+{{- if .ResultType}}
+{{- else if .HasColumns}}
+/** {{.Method}} 的结果行 */
+export interface {{.TypeName}}Row {
+{{- range .Columns}}
+  {{.Key}}: {{.Type}};
+{{- end}}
+}
+{{else}}
+/** {{.Method}} 的结果行 (结果列未知) */
+export type {{.TypeName}}Row = Record<string, unknown>;
+{{end}}
+{{- end}}
+export class Client {
+  private readonly baseURL: string;
+  private readonly options: ClientOptions;
+
+  constructor(baseURL: string, options: ClientOptions = {}) {
+    this.baseURL = baseURL.replace(/\/+$/, "");
+    this.options = options;
+  }
+
+  private async send(method: string, path: string, query?: Record<string, string | number | boolean>, body?: unknown): Promise<unknown> {
+    let url = this.baseURL + path;
+    if (query) {
+      const search = new URLSearchParams();
+      for (const [key, value] of Object.entries(query)) {
+        search.set(key, String(value));
+      }
+      url += "?" + search.toString();
+    }
+    const headers: Record<string, string> = { Accept: "application/json", ...this.options.headers };
+    if (body !== undefined) {
+      headers["Content-Type"] = "application/json";
+    }
+    if (this.options.apiKey) {
+      headers["Authorization"] = "Bearer " + this.options.apiKey;
+    }
+    const doFetch = this.options.fetch ?? fetch;
+    const res = await doFetch(url, { method, headers, body: body === undefined ? undefined : JSON.stringify(body) });
+
+    let resp: Envelope;
+    try {
+      resp = (await res.json()) as Envelope;
+    } catch (err) {
+      throw new ApiError(res.status, res.status, "无法解析响应: " + String(err));
+    }
+    if (!res.ok || resp.code !== 0) {
+      throw new ApiError(res.status, resp.code, resp.message, resp.data);
+    }
+    return resp.data;
+  }
+
+  private async request<T>(method: string, path: string, query?: Record<string, string | number | boolean>, body?: unknown): Promise<Result<T>> {
+    // 结果被截断时 data 为 {rows_returned, truncated, data}
+    const data = (await this.send(method, path, query, body)) as T[] | { truncated: boolean; data: T[] } | null | undefined;
+    if (data && !Array.isArray(data)) {
+      return { rows: data.data ?? [], truncated: data.truncated };
+    }
+    return { rows: data ?? [], truncated: false };
+  }
+
+  private async result<T>(method: string, path: string, query?: Record<string, string | number | boolean>, body?: unknown): Promise<T> {
+    return (await this.send(method, path, query, body)) as T;
+  }
+{{range .Services}}
+  /**
+{{- range .Doc}}
+   * {{.}}
+{{- end}}
+{{- if .Deprecated}}
+   * @deprecated 服务已弃用。
+{{- end}}
+   */
+{{- if .ResultType}}
+  {{.Method}}({{if .Params}}params: {{.TypeName}}Params{{end}}): Promise<{{.ResultType}}> {
+{{- if not .Params}}
+    return this.result<{{.ResultType}}>("{{.HTTPMethod}}", {{printf "%q" .RunPath}});
+{{- else if .Query}}
+    return this.result<{{.ResultType}}>("{{.HTTPMethod}}", {{printf "%q" .RunPath}}, { ...params });
+{{- else}}
+    return this.result<{{.ResultType}}>("{{.HTTPMethod}}", {{printf "%q" .RunPath}}, undefined, params);
+{{- end}}
+  }
+{{- else if not .Params}}
+  {{.Method}}(): Promise<Result<{{.TypeName}}Row>> {
+    return this.request<{{.TypeName}}Row>("{{.HTTPMethod}}", {{printf "%q" .RunPath}});
+  }
+{{- else if .Query}}
+  {{.Method}}(params: {{.TypeName}}Params): Promise<Result<{{.TypeName}}Row>> {
+    return this.request<{{.TypeName}}Row>("{{.HTTPMethod}}", {{printf "%q" .RunPath}}, { ...params });
+  }
+{{- else}}
+  {{.Method}}(params: {{.TypeName}}Params): Promise<Result<{{.TypeName}}Row>> {
+    return this.request<{{.TypeName}}Row>("{{.HTTPMethod}}", {{printf "%q" .RunPath}}, undefined, params);
+  }
+{{- end}}
+{{end}}}
+`))
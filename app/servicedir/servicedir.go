@@ -0,0 +1,262 @@
+// Package servicedir 实现声明式动态服务定义目录 (GitOps 模式)。
+//
+// 目录 (dynamic.services_dir) 中的每个 .yaml/.yml/.json 文件都是一个服务定义包，格式与
+// app service export 导出的相同。启动时与文件变更后，目录中的全部定义会与数据库同步:
+// 新服务被创建，定义变化的服务被更新，从目录中删除的服务被停用。同步的服务记录其来源文件
+// (APIService.ManagedBy)，注册接口与命令行不能修改这些服务，仓库始终是唯一的来源。
+package servicedir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go-gin-gorm-api/app/handlers"
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+// Result 是一次同步的结果
+type Result struct {
+	Files    int      `json:"files"`
+	Created  []string `json:"created,omitempty"`
+	Updated  []string `json:"updated,omitempty"`
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+// definition 是目录中的单个服务定义及其来源文件
+type definition struct {
+	file    string
+	service models.APIService
+}
+
+// ErrInvalidDefinitions 表示目录中存在无法解析或无效的服务定义，此时不会修改数据库
+var ErrInvalidDefinitions = errors.New("服务定义目录包含无效的定义")
+
+// ErrConflict 表示目录中的服务与数据库中未由目录定义的服务冲突（路径已被占用），此时不会修改数据库
+var ErrConflict = errors.New("服务定义与已有服务冲突")
+
+// load 读取 dir 下（含子目录）全部服务定义文件并校验，返回按名称索引的定义与文件数。
+// 任一文件无法解析、服务无效或名称/路径在文件之间重复时返回 ErrInvalidDefinitions。
+func load(dir string) (map[string]definition, int, error) {
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defs := make(map[string]definition)
+	paths := make(map[string]string)
+	var errs []string
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		bundle, err := handlers.ParseBundle(data)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		services, err := handlers.ValidateBundle(bundle)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		for _, s := range services {
+			if other, ok := defs[s.Name]; ok {
+				errs = append(errs, fmt.Sprintf("%s: 服务 %s 已在 %s 中定义", file, s.Name, other.file))
+				continue
+			}
+			if other, ok := paths[s.Path]; ok {
+				errs = append(errs, fmt.Sprintf("%s: 服务 %s 的路径 %s 与服务 %s 重复", file, s.Name, s.Path, other))
+				continue
+			}
+			s.ManagedBy = file
+			defs[s.Name] = definition{file: file, service: s}
+			paths[s.Path] = s.Name
+		}
+	}
+	if len(errs) > 0 {
+		return nil, len(files), fmt.Errorf("%w: %s", ErrInvalidDefinitions, strings.Join(errs, "; "))
+	}
+	return defs, len(files), nil
+}
+
+// listFiles 返回 dir 下全部定义文件相对于 dir 的路径（使用 / 分隔），按名称排序。以 . 开头的文件与目录被忽略
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Sync 将 dir 中的服务定义同步到数据库（单个事务）: 创建新服务、更新定义变化的服务（包括接管同名的
+// 非文件管理服务）、停用由文件管理但已从目录中删除的服务。路径被其他未由目录定义的服务占用时同步失败。
+func Sync(ctx context.Context, db *gorm.DB, dir string) (*Result, error) {
+	defs, files, err := load(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Files: files}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []models.APIService
+		if err := tx.Unscoped().Find(&existing).Error; err != nil {
+			return err
+		}
+		byName := make(map[string]*models.APIService, len(existing))
+		for i := range existing {
+			byName[existing[i].Name] = &existing[i]
+		}
+
+		names := make([]string, 0, len(defs))
+		for name := range defs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		// 先停用已从目录中删除的服务
+		for i := range existing {
+			s := &existing[i]
+			if s.ManagedBy == "" || s.DeletedAt.Valid || s.Status == models.ServiceStatusDisabled {
+				continue
+			}
+			if _, ok := defs[s.Name]; ok {
+				continue
+			}
+			if err := tx.Model(s).Updates(map[string]interface{}{"status": models.ServiceStatusDisabled, "deprecated_at": nil}).Error; err != nil {
+				return fmt.Errorf("停用服务 %s 失败: %w", s.Name, err)
+			}
+			result.Disabled = append(result.Disabled, s.Name)
+		}
+
+		for _, name := range names {
+			def := defs[name]
+			desired := def.service
+			for i := range existing {
+				if other := &existing[i]; other.Path == desired.Path && other.Name != name {
+					if _, ok := defs[other.Name]; !ok {
+						return fmt.Errorf("%w: %s: 服务 %s 的路径 %s 已被服务 %s 使用", ErrConflict, def.file, name, desired.Path, other.Name)
+					}
+				}
+			}
+
+			current, ok := byName[name]
+			switch {
+			case !ok:
+				desired.TrackDeprecation(nil, time.Now())
+				if err := tx.Create(&desired).Error; err != nil {
+					return fmt.Errorf("%s: 创建服务 %s 失败: %w", def.file, name, err)
+				}
+				result.Created = append(result.Created, name)
+			case current.DeletedAt.Valid || current.ManagedBy != desired.ManagedBy || len(handlers.DiffServices(current, &desired)) > 0:
+				if current.ManagedBy == "" {
+					slog.Warn("服务定义目录接管已注册的动态服务", "service", name, "file", def.file)
+				}
+				desired.ID, desired.CreatedAt = current.ID, current.CreatedAt
+				desired.TrackDeprecation(current, time.Now())
+				if err := tx.Unscoped().Save(&desired).Error; err != nil {
+					return fmt.Errorf("%s: 更新服务 %s 失败: %w", def.file, name, err)
+				}
+				result.Updated = append(result.Updated, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fingerprint 返回目录中定义文件的名称、大小与修改时间摘要，用于检测变更
+func fingerprint(dir string) (string, error) {
+	files, err := listFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, file := range files {
+		fi, err := os.Stat(filepath.Join(dir, file))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s|%d|%d\n", file, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+// Start 立即同步一次 dir，之后每隔 interval 检查目录，文件新增、修改或删除时重新同步。
+// 定义无效或冲突时保留数据库中的当前状态并输出错误日志，等待文件修正；数据库错误会在下一次检查时重试。
+// ctx 取消后停止。
+func Start(ctx context.Context, db *gorm.DB, dir string, interval time.Duration) {
+	// sync 返回 false 表示需要在下一次检查时重试
+	sync := func(reason string) bool {
+		result, err := Sync(ctx, db, dir)
+		if err != nil {
+			slog.Error("同步服务定义目录失败，保留当前服务", "dir", dir, "reason", reason, "error", err)
+			return errors.Is(err, ErrInvalidDefinitions) || errors.Is(err, ErrConflict)
+		}
+		slog.Info("服务定义目录已同步", "dir", dir, "reason", reason, "files", result.Files,
+			"created", result.Created, "updated", result.Updated, "disabled", result.Disabled)
+		return true
+	}
+
+	last, _ := fingerprint(dir)
+	ok := sync("startup")
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current, err := fingerprint(dir)
+			if err != nil {
+				slog.Error("读取服务定义目录失败", "dir", dir, "error", err)
+				continue
+			}
+			if current == last && ok {
+				continue
+			}
+			reason := "file_changed"
+			if current == last {
+				reason = "retry"
+			}
+			last = current
+			ok = sync(reason)
+		}
+	}()
+}
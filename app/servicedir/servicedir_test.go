@@ -0,0 +1,151 @@
+package servicedir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-gin-gorm-api/app/config"
+	"go-gin-gorm-api/app/migrate"
+	"go-gin-gorm-api/app/models"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 在临时目录中创建 SQLite 数据库并执行全部迁移，设为当前配置
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Database.Driver = config.DriverSQLite
+	cfg.Database.Name = filepath.Join(t.TempDir(), "test.db")
+	config.SetCurrent(cfg)
+	t.Cleanup(func() { config.SetCurrent(config.Default()) })
+
+	db, err := config.OpenDatabase(cfg)
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if _, err := migrate.Up(context.Background(), db); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// bundle 返回包含给定服务的定义包，每个服务为 名称、路径、SQL 三元组
+func bundle(services ...[3]string) string {
+	s := "version: 1\nservices:\n"
+	for _, svc := range services {
+		s += fmt.Sprintf("  - {name: %s, method: GET, path: %s, sql: %q}\n", svc[0], svc[1], svc[2])
+	}
+	return s
+}
+
+// TestSync 在同一目录与数据库上依次执行各步骤，每步写入或删除文件 (内容为空表示删除) 后同步一次
+func TestSync(t *testing.T) {
+	db := setupTestDB(t)
+	for _, s := range []models.APIService{
+		{Name: "legacy", Method: "GET", Path: "/legacy", SQL: "SELECT 1"},
+		{Name: "squatter", Method: "GET", Path: "/taken", SQL: "SELECT 1"},
+	} {
+		if err := db.Create(&s).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    *Result
+		wantErr error
+	}{
+		{"创建新服务并接管同名服务", map[string]string{
+			"a.yaml": bundle([3]string{"report", "/report", "SELECT 1"}, [3]string{"legacy", "/legacy", "SELECT 2"}),
+		}, &Result{Files: 1, Created: []string{"report"}, Updated: []string{"legacy"}}, nil},
+		{"文件未变化", nil, &Result{Files: 1}, nil},
+		{"更新定义并读取子目录", map[string]string{
+			"a.yaml":      bundle([3]string{"report", "/report", "SELECT 3"}, [3]string{"legacy", "/legacy", "SELECT 2"}),
+			"sub/b.yml":   bundle([3]string{"stats", "/stats", "SELECT 1"}),
+			".hidden.yml": "not a bundle",
+		}, &Result{Files: 2, Created: []string{"stats"}, Updated: []string{"report"}}, nil},
+		{"文件删除后停用服务", map[string]string{"sub/b.yml": ""},
+			&Result{Files: 1, Disabled: []string{"stats"}}, nil},
+		{"路径被未管理的服务占用", map[string]string{"c.yaml": bundle([3]string{"other", "/taken", "SELECT 1"})},
+			nil, ErrConflict},
+		{"名称在文件之间重复", map[string]string{"c.yaml": bundle([3]string{"report", "/report2", "SELECT 1"})},
+			nil, ErrInvalidDefinitions},
+		{"恢复已停用的服务", map[string]string{"c.yaml": bundle([3]string{"stats", "/stats", "SELECT 1"})},
+			&Result{Files: 2, Updated: []string{"stats"}}, nil},
+	}
+	for _, tt := range tests {
+		// 各步骤依赖前一步的状态，失败时停止
+		ok := t.Run(tt.name, func(t *testing.T) {
+			for file, content := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(file))
+				if content == "" {
+					if err := os.Remove(path); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var before []models.APIService
+			if err := db.Order("id").Find(&before).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Sync(context.Background(), db, dir)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sync() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sync() = %+v, want %+v", got, tt.want)
+			}
+			if err != nil {
+				// 失败时不修改数据库
+				var after []models.APIService
+				if err := db.Order("id").Find(&after).Error; err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(after, before) {
+					t.Errorf("Sync() 失败后数据库被修改: %+v -> %+v", before, after)
+				}
+			}
+		})
+		if !ok {
+			break
+		}
+	}
+
+	var services []models.APIService
+	if err := db.Order("name").Find(&services).Error; err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, s := range services {
+		got[s.Name] = fmt.Sprintf("%s|%s|%s", s.ManagedBy, s.Status, s.SQL)
+	}
+	want := map[string]string{
+		"legacy":   "a.yaml|active|SELECT 2",
+		"report":   "a.yaml|active|SELECT 3",
+		"squatter": "|active|SELECT 1",
+		"stats":    "c.yaml|active|SELECT 1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("services = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// gormSpanKey 是在 GORM Statement 实例中保存当前 span 的键
+const gormSpanKey = "tracing:span"
+
+// RegisterGORM 注册 GORM 回调，为每条数据库操作创建 client span。
+// span 中记录带 ? 占位符的 SQL 语句，参数值不会被记录（脱敏）。
+// 调用方需要通过 db.WithContext(ctx) 传入请求上下文，span 才能挂到对应的父 span 下。
+func RegisterGORM(db *gorm.DB) error {
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().Before("gorm:create").Register("tracing:before_create", beforeCallback("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", afterCallback),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", beforeCallback("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", afterCallback),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", beforeCallback("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", afterCallback),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", beforeCallback("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", afterCallback),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", beforeCallback("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", afterCallback),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", beforeCallback("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", afterCallback),
+	}
+	return errors.Join(errs...)
+}
+
+func beforeCallback(op string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		_, span := Tracer().Start(ctx, "gorm."+op, trace.WithSpanKind(trace.SpanKindClient))
+		tx.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func afterCallback(tx *gorm.DB) {
+	v, ok := tx.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemKey.String(tx.Dialector.Name()),
+		semconv.DBQueryText(tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	}
+	if tx.Statement.Table != "" {
+		attrs = append(attrs, semconv.DBCollectionName(tx.Statement.Table))
+	}
+	span.SetAttributes(attrs...)
+
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+}
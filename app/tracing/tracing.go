@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 是本应用创建 span 时使用的 instrumentation 名称
+const tracerName = "go-gin-gorm-api"
+
+// 支持的导出器类型
+const (
+	ExporterNone   = "none"   // 不导出（默认），仍然解析并传播 W3C trace context
+	ExporterOTLP   = "otlp"   // OTLP/HTTP，端点等通过标准 OTEL_EXPORTER_OTLP_* 环境变量配置
+	ExporterStdout = "stdout" // 输出到标准输出，便于本地调试
+	ExporterFile   = "file"   // 追加写入 JSON 文件，便于离线分析
+)
+
+// Config 定义链路追踪配置
+type Config struct {
+	Exporter    string  `yaml:"exporter"`     // none | otlp | stdout | file
+	FilePath    string  `yaml:"file_path"`    // file 导出器的输出文件
+	ServiceName string  `yaml:"service_name"` // 上报的 service.name
+	SampleRatio float64 `yaml:"sample_ratio"` // 根 span 采样率 (0, 1]，对已有父 span 的请求沿用父 span 的采样决策
+}
+
+// Tracer 返回应用统一使用的 Tracer
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Init 初始化全局 TracerProvider 与 W3C trace context 传播器，返回用于刷新并关闭导出器的函数。
+func Init(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	// 无论是否导出，都解析并传播 traceparent / baggage 请求头
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		closer   func() error
+		err      error
+	)
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		if cfg.FilePath == "" {
+			return nil, errors.New("file 导出器需要配置 TRACING_FILE")
+		}
+		f, ferr := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+		if ferr != nil {
+			return nil, fmt.Errorf("打开链路追踪文件失败: %w", ferr)
+		}
+		closer = f.Close
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("未知的链路追踪导出器: %s", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("创建链路追踪导出器失败: %w", err)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = tracerName
+	}
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建链路追踪资源失败: %w", err)
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			if cerr := closer(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
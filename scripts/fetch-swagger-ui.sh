@@ -0,0 +1,22 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Download swagger-ui-dist and copy it into app/docs/ui/swagger-ui/ so that it is embedded
+# into the binary (go:embed) and served offline at /docs/. Without it /docs/ falls back to
+# the built-in lightweight viewer. Run once on a machine with access to the npm registry.
+#
+# Usage: ./scripts/fetch-swagger-ui.sh [version]
+ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+VERSION="${1:-5.17.14}"
+DEST="$ROOT_DIR/app/docs/ui/swagger-ui"
+TMP_DIR="$(mktemp -d)"
+trap 'rm -rf "$TMP_DIR"' EXIT
+
+echo "Downloading swagger-ui-dist@$VERSION ..."
+curl -fsSL "https://registry.npmjs.org/swagger-ui-dist/-/swagger-ui-dist-$VERSION.tgz" -o "$TMP_DIR/swagger-ui.tgz"
+tar -xzf "$TMP_DIR/swagger-ui.tgz" -C "$TMP_DIR"
+
+mkdir -p "$DEST"
+cp "$TMP_DIR/package/swagger-ui-bundle.js" "$TMP_DIR/package/swagger-ui.css" "$TMP_DIR/package/LICENSE" "$DEST/"
+echo "$VERSION" > "$DEST/VERSION"
+echo "Copied to $DEST — rebuild the binary (go build -o app ./app) to embed it."